@@ -0,0 +1,75 @@
+package anissia
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Client는 Anissia API 클라이언트입니다.
+type Client interface {
+	// GetSchedule은 요일별 애니메이션 편성표 정보를 받아옵니다.
+	GetSchedule(ctx context.Context, week int) ([]AnimeInfo, error)
+	// GetCaptions은 애니메이션 No로 자막 정보를 받아옵니다.
+	GetCaptions(ctx context.Context, animeNo int) ([]SubtitleInfo, error)
+}
+
+// AnimeInfo 구조체는 각 애니메이션에 대한 정보를 정의합니다.
+type AnimeInfo struct {
+	Week         string `json:"week"`
+	AnimeNo      int    `json:"animeNo"`
+	Status       string `json:"status"`
+	Time         string `json:"time"`
+	Subject      string `json:"subject"`
+	Genres       string `json:"genres"`
+	CaptionCount int    `json:"captionCount"`
+	StartDate    string `json:"startDate"`
+	EndDate      string `json:"endDate"`
+	Website      string `json:"website"`
+}
+
+// AnimeScheduleResponse 구조체는 전체 응답을 정의합니다.
+type AnimeScheduleResponse struct {
+	Code string      `json:"code"`
+	Data []AnimeInfo `json:"data"`
+}
+
+// SubtitleInfo 구조체는 자막 정보를 정의합니다.
+type SubtitleInfo struct {
+	Episode string `json:"episode"` // 자막 회차
+	UpdDt   string `json:"updDt"`   // 자막 업로드 시간
+	Website string `json:"website"` // 자막 웹사이트
+	Name    string `json:"name"`    // 자막 제작자 이름
+}
+
+// updDtLayout은 자막 업로드 시간의 형식입니다.
+const updDtLayout = "2006-01-02T15:04:05"
+
+// kst는 Anissia API가 사용하는 한국 표준시입니다.
+var kst = time.FixedZone("KST", 9*60*60)
+
+// ParseUpdDt는 자막 업로드 시간을 한국 표준시 기준의 time.Time으로 변환합니다.
+func ParseUpdDt(updDt string) (time.Time, error) {
+	return time.ParseInLocation(updDtLayout, updDt, kst)
+}
+
+// SubtitleResponse 구조체는 전체 응답을 정의합니다.
+type SubtitleResponse struct {
+	Code string         `json:"code"`
+	Data []SubtitleInfo `json:"data"`
+}
+
+// StatusError는 Anissia API가 200이 아닌 상태 코드를 반환했을 때의 에러입니다.
+type StatusError struct {
+	URL        string // 요청 URL
+	StatusCode int    // HTTP 상태 코드
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d from %s", e.StatusCode, e.URL)
+}
+
+// Temporary는 재시도할 가치가 있는 에러인지 반환합니다.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == 429
+}
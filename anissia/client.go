@@ -0,0 +1,189 @@
+package anissia
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultBaseURL은 Anissia API의 기본 주소입니다.
+const DefaultBaseURL = "https://api.anissia.net"
+
+// Config는 ClientImpl의 설정입니다.
+type Config struct {
+	BaseURL    string        // API 주소
+	Timeout    time.Duration // 요청 한 번의 타임아웃
+	MaxRetries int           // 최대 재시도 횟수
+	MinBackoff time.Duration // 첫 재시도 대기 시간
+	MaxBackoff time.Duration // 최대 재시도 대기 시간
+	RateLimit  float64       // 초당 최대 요청 수, 0이면 제한하지 않음
+	RateBurst  int           // 한 번에 몰아서 보낼 수 있는 요청 수
+}
+
+// DefaultConfig는 기본 설정을 반환합니다.
+func DefaultConfig() Config {
+	return Config{
+		BaseURL:    DefaultBaseURL,
+		Timeout:    10 * time.Second,
+		MaxRetries: 3,
+		MinBackoff: 500 * time.Millisecond,
+		MaxBackoff: 10 * time.Second,
+		RateLimit:  5,
+		RateBurst:  1,
+	}
+}
+
+// ClientImpl은 anissia.Client interface 의 구현체입니다.
+type ClientImpl struct {
+	config     Config
+	httpClient *http.Client
+	limiter    *rate.Limiter // 모든 요청이 공유하는 요청 속도 제한
+}
+
+// NewClient는 ClientImpl을 생성합니다.
+// 설정되지 않은 값은 DefaultConfig의 값을 사용합니다.
+func NewClient(config Config) *ClientImpl {
+	defaults := DefaultConfig()
+	if config.BaseURL == "" {
+		config.BaseURL = defaults.BaseURL
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	if config.Timeout <= 0 {
+		config.Timeout = defaults.Timeout
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = defaults.MinBackoff
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = config.MinBackoff
+	}
+	if config.RateBurst <= 0 {
+		config.RateBurst = 1
+	}
+
+	limiter := rate.NewLimiter(rate.Inf, 0)
+	if config.RateLimit > 0 {
+		limiter = rate.NewLimiter(rate.Limit(config.RateLimit), config.RateBurst)
+	}
+
+	return &ClientImpl{
+		config:     config,
+		httpClient: &http.Client{},
+		limiter:    limiter,
+	}
+}
+
+// GetSchedule은 요일별 애니메이션 편성표 정보를 받아옵니다.
+func (c *ClientImpl) GetSchedule(ctx context.Context, week int) ([]AnimeInfo, error) {
+	var schedule AnimeScheduleResponse
+	if err := c.getJSON(ctx, fmt.Sprintf("/anime/schedule/%d", week), &schedule); err != nil {
+		return nil, err
+	}
+	return schedule.Data, nil
+}
+
+// GetCaptions은 애니메이션 No로 자막 정보를 받아옵니다.
+func (c *ClientImpl) GetCaptions(ctx context.Context, animeNo int) ([]SubtitleInfo, error) {
+	var subtitleResponse SubtitleResponse
+	if err := c.getJSON(ctx, fmt.Sprintf("/anime/caption/animeNo/%d", animeNo), &subtitleResponse); err != nil {
+		return nil, err
+	}
+	return subtitleResponse.Data, nil
+}
+
+// getJSON은 path로 GET 요청을 보내고 응답을 v에 디코딩합니다.
+// 네트워크 에러와 5xx 응답은 지수 백오프로 재시도합니다.
+func (c *ClientImpl) getJSON(ctx context.Context, path string, v any) error {
+	reqUrl := c.config.BaseURL + path
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = c.doGetJSON(ctx, reqUrl, v)
+		if err == nil || !isRetryable(err) || attempt >= c.config.MaxRetries {
+			return err
+		}
+
+		wait := c.backoff(attempt)
+		log.Printf("[Anissia] - retrying %s in %s: %v", reqUrl, wait, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// doGetJSON은 재시도 없이 한 번의 요청을 수행합니다.
+func (c *ClientImpl) doGetJSON(ctx context.Context, reqUrl string, v any) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return fmt.Errorf("failed to wait for rate limiter: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request %s: %w", reqUrl, err)
+	}
+	defer res.Body.Close()
+
+	// Check response status code
+	if res.StatusCode != http.StatusOK {
+		return &StatusError{URL: reqUrl, StatusCode: res.StatusCode}
+	}
+
+	// JSON을 파싱합니다.
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response body: %w", err)
+	}
+
+	return nil
+}
+
+// backoff는 attempt번째 재시도 전에 기다릴 시간을 계산합니다.
+func (c *ClientImpl) backoff(attempt int) time.Duration {
+	wait := c.config.MaxBackoff
+	// 시프트가 오버플로하지 않도록 MaxBackoff를 넘는지 먼저 확인합니다.
+	if attempt < 63 && c.config.MinBackoff <= c.config.MaxBackoff>>attempt {
+		wait = c.config.MinBackoff << attempt
+	}
+	// 동시에 실패한 요청들이 한꺼번에 재시도하지 않도록 지터를 추가합니다.
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// isRetryable은 재시도할 수 있는 에러인지 판별합니다.
+func isRetryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Temporary()
+	}
+	// 호출자가 취소한 경우에는 재시도하지 않습니다.
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	// 디코딩 에러는 재시도해도 같은 결과입니다.
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,237 @@
+package anissia
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient는 server를 가리키고 바로 재시도하는 ClientImpl을 생성합니다.
+func newTestClient(server *httptest.Server) *ClientImpl {
+	return NewClient(Config{
+		BaseURL:    server.URL,
+		Timeout:    time.Second,
+		MaxRetries: 3,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 2 * time.Millisecond,
+	})
+}
+
+func TestGetJSONRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  []int // 성공하기 전에 차례로 반환할 상태 코드, 0이면 연결을 끊습니다.
+		wantCalls int32
+		wantCode  int // 기대하는 StatusError의 상태 코드, 0이면 성공
+	}{
+		{name: "ok", wantCalls: 1},
+		{name: "5xx then ok", failures: []int{500, 503}, wantCalls: 3},
+		{name: "429 then ok", failures: []int{429}, wantCalls: 2},
+		{name: "network error then ok", failures: []int{0}, wantCalls: 2},
+		{name: "404 is not retried", failures: []int{404}, wantCalls: 1, wantCode: 404},
+		{name: "400 is not retried", failures: []int{400}, wantCalls: 1, wantCode: 400},
+		{name: "retries exhausted", failures: []int{502, 502, 502, 502, 502}, wantCalls: 4, wantCode: 502},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&calls, 1)
+				if int(n) <= len(tt.failures) {
+					code := tt.failures[n-1]
+					if code == 0 {
+						conn, _, err := w.(http.Hijacker).Hijack()
+						if err != nil {
+							t.Errorf("hijack: %v", err)
+							return
+						}
+						conn.Close()
+						return
+					}
+					w.WriteHeader(code)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"code":"ok","data":[{"episode":"1","name":"tester"}]}`))
+			}))
+			defer server.Close()
+
+			captions, err := newTestClient(server).GetCaptions(context.Background(), 1)
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+			if tt.wantCode == 0 {
+				if err != nil {
+					t.Fatalf("GetCaptions() error = %v", err)
+				}
+				if len(captions) != 1 || captions[0].Name != "tester" {
+					t.Errorf("GetCaptions() = %+v", captions)
+				}
+				return
+			}
+
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("GetCaptions() error = %v, want StatusError", err)
+			}
+			if statusErr.StatusCode != tt.wantCode {
+				t.Errorf("StatusCode = %d, want %d", statusErr.StatusCode, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestGetJSONDecodeErrorIsNotRetried(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"data":"not a list"}`))
+	}))
+	defer server.Close()
+
+	if _, err := newTestClient(server).GetSchedule(context.Background(), 0); err == nil {
+		t.Fatal("GetSchedule() error = nil")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestGetJSONCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{
+		BaseURL:    server.URL,
+		MaxRetries: 10,
+		MinBackoff: time.Hour,
+		MaxBackoff: time.Hour,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GetSchedule(ctx, 0)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetSchedule() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("GetSchedule() took %s after cancel", elapsed)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	var mu sync.Mutex
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"code":"ok","data":[]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{BaseURL: server.URL, RateLimit: 20, RateBurst: 2})
+
+	// 여러 고루틴의 요청이 하나의 제한을 함께 사용합니다.
+	const requests = 6
+	var wg sync.WaitGroup
+	wg.Add(requests)
+	for i := 0; i < requests; i++ {
+		go func(animeNo int) {
+			defer wg.Done()
+			if _, err := client.GetCaptions(context.Background(), animeNo); err != nil {
+				t.Errorf("GetCaptions(%d) error = %v", animeNo, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// 처음 2개는 바로 보내고, 나머지 4개는 초당 20개(50ms 간격)로 보냅니다.
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	if len(times) != requests {
+		t.Fatalf("requests = %d, want %d", len(times), requests)
+	}
+	if span, want := times[requests-1].Sub(times[0]), 4*50*time.Millisecond; span < want-20*time.Millisecond {
+		t.Errorf("requests took %s, want at least %s", span, want)
+	}
+}
+
+func TestRateLimitCanceled(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"code":"ok","data":[]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(Config{BaseURL: server.URL, RateLimit: 0.1, RateBurst: 1})
+	if _, err := client.GetCaptions(context.Background(), 1); err != nil {
+		t.Fatalf("first GetCaptions() error = %v", err)
+	}
+
+	// 다음 요청까지 10초를 기다려야 하므로 ctx가 먼저 끝나고 요청을 보내지 않습니다.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.GetCaptions(ctx, 2); err == nil {
+		t.Error("second GetCaptions() error = nil, want rate limiter error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("GetCaptions() waited %s", elapsed)
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
+
+func TestBackoffIsBounded(t *testing.T) {
+	client := NewClient(Config{
+		MinBackoff: 100 * time.Millisecond,
+		MaxBackoff: time.Second,
+	})
+	for attempt := 0; attempt < 80; attempt++ {
+		want := time.Second
+		if f := 100 * float64(time.Millisecond) * math.Pow(2, float64(attempt)); f < float64(want) {
+			want = time.Duration(f)
+		}
+		for i := 0; i < 20; i++ {
+			got := client.backoff(attempt)
+			if got < want/2 || got > want {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", attempt, got, want/2, want)
+			}
+		}
+	}
+}
+
+func TestStatusErrorTemporary(t *testing.T) {
+	tests := []struct {
+		code int
+		want bool
+	}{
+		{400, false},
+		{401, false},
+		{404, false},
+		{429, true},
+		{500, true},
+		{503, true},
+	}
+	for _, tt := range tests {
+		err := &StatusError{URL: "http://example.com", StatusCode: tt.code}
+		if got := err.Temporary(); got != tt.want {
+			t.Errorf("StatusError{%d}.Temporary() = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
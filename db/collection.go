@@ -1,9 +1,13 @@
 package db
 
 import (
+	"strings"
+
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/models"
 	"github.com/pocketbase/pocketbase/models/schema"
+	"github.com/pocketbase/pocketbase/tools/dbutils"
+	"github.com/pocketbase/pocketbase/tools/types"
 )
 
 func InitCollection(app *pocketbase.PocketBase) error {
@@ -24,11 +28,97 @@ func InitCollection(app *pocketbase.PocketBase) error {
 		if err := createAnimeSubtitleCollection(app); err != nil {
 			return err
 		}
+	} else {
+		// 이전 버전에서 만들어진 Collection에 새로 추가된 필드와 인덱스를 정의합니다.
+		if err := ensureFields(app, animeSubtitleCollection, animeSubtitleFields()...); err != nil {
+			return err
+		}
+		if err := ensureAnimeSubtitleIndexes(app, animeSubtitleCollection); err != nil {
+			return err
+		}
+	}
+
+	// Check if "jobs" collection exists
+	jobsCollection, _ := app.Dao().FindCollectionByNameOrId("jobs")
+	if jobsCollection == nil {
+		if err := createJobsCollection(app); err != nil {
+			return err
+		}
+	} else {
+		// 이전 버전에서 만들어진 Collection에 새로 추가된 필드와 인덱스를 정의합니다.
+		if err := ensureFields(app, jobsCollection, jobsDedupKeyField()); err != nil {
+			return err
+		}
+		if err := ensureIndexes(app, jobsCollection, jobsDedupKeyIndex); err != nil {
+			return err
+		}
+	}
+
+	// Check if "files" collection exists
+	filesCollection, _ := app.Dao().FindCollectionByNameOrId("files")
+	if filesCollection == nil {
+		if err := createFilesCollection(app); err != nil {
+			return err
+		}
+	}
+
+	// Check if "blobs" collection exists
+	blobsCollection, _ := app.Dao().FindCollectionByNameOrId("blobs")
+	if blobsCollection == nil {
+		if err := createBlobsCollection(app); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// ensureFields는 collection에 없는 필드를 추가합니다.
+func ensureFields(app *pocketbase.PocketBase, collection *models.Collection, fields ...*schema.SchemaField) error {
+	changed := false
+	for _, field := range fields {
+		if collection.Schema.GetFieldByName(field.Name) != nil {
+			continue
+		}
+		// 기존 레코드가 있을 수 있으므로 추가되는 필드는 필수로 만들지 않습니다.
+		field.Required = false
+		collection.Schema.AddField(field)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	return app.Dao().SaveCollection(collection)
+}
+
+// ensureIndexes는 collection에 이름이 같은 인덱스가 없으면 추가합니다.
+func ensureIndexes(app *pocketbase.PocketBase, collection *models.Collection, indexes ...string) error {
+	changed := false
+	for _, index := range indexes {
+		if hasIndex(collection, dbutils.ParseIndex(index).IndexName) {
+			continue
+		}
+		collection.Indexes = append(collection.Indexes, index)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	return app.Dao().SaveCollection(collection)
+}
+
+// hasIndex는 collection에 name 인덱스가 정의되어 있는지 확인합니다.
+func hasIndex(collection *models.Collection, name string) bool {
+	for _, index := range collection.Indexes {
+		if strings.EqualFold(dbutils.ParseIndex(index).IndexName, name) {
+			return true
+		}
+	}
+	return false
+}
+
 func createAnimeInfoCollection(app *pocketbase.PocketBase) error {
 	collection := &models.Collection{
 		Name:       "anime_info",
@@ -105,31 +195,266 @@ func createAnimeSubtitleCollection(app *pocketbase.PocketBase) error {
 		CreateRule: nil,
 		UpdateRule: nil,
 		DeleteRule: nil,
+		Schema:     schema.NewSchema(animeSubtitleFields()...),
+		Indexes:    animeSubtitleIndexes(),
+	}
+
+	if err := app.Dao().SaveCollection(collection); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// animeSubtitleReleaseIndex는 자막 정보를 anime_no, episode, name(자막 제작자)의 조합으로 구분하는 인덱스 이름입니다.
+const animeSubtitleReleaseIndex = "idx_anime_subtitle_release"
+
+// animeSubtitleIndexes는 "anime_subtitle" collection의 인덱스를 반환합니다.
+func animeSubtitleIndexes() types.JsonArray[string] {
+	return types.JsonArray[string]{
+		"CREATE UNIQUE INDEX " + animeSubtitleReleaseIndex + " ON anime_subtitle (anime_no, episode, name)",
+	}
+}
+
+// ensureAnimeSubtitleIndexes는 이전 버전에서 만들어진 "anime_subtitle" collection에 인덱스를 추가합니다.
+// 이전 버전은 같은 자막 정보를 여러 번 저장했을 수 있으므로, 유니크 인덱스를 추가하기 전에
+// 가장 최근에 수정된 레코드만 남기고 중복된 레코드를 지웁니다.
+func ensureAnimeSubtitleIndexes(app *pocketbase.PocketBase, collection *models.Collection) error {
+	if !hasIndex(collection, animeSubtitleReleaseIndex) {
+		_, err := app.Dao().DB().NewQuery(`
+			DELETE FROM anime_subtitle
+			WHERE EXISTS (
+				SELECT 1 FROM anime_subtitle AS newer
+				WHERE newer.anime_no = anime_subtitle.anime_no
+					AND newer.episode = anime_subtitle.episode
+					AND newer.name = anime_subtitle.name
+					AND (newer.updated > anime_subtitle.updated
+						OR (newer.updated = anime_subtitle.updated AND newer.id > anime_subtitle.id))
+			)
+		`).Execute()
+		if err != nil {
+			return err
+		}
+	}
+
+	return ensureIndexes(app, collection, animeSubtitleIndexes()...)
+}
+
+// animeSubtitleFields는 "anime_subtitle" collection의 필드를 반환합니다.
+// 자막 정보는 anime_no, episode, name(자막 제작자)의 조합으로 구분합니다.
+func animeSubtitleFields() []*schema.SchemaField {
+	return []*schema.SchemaField{
+		{
+			Name:     "anime_no",
+			Type:     schema.FieldTypeNumber,
+			Required: true,
+		},
+		{
+			Name:     "subject",
+			Type:     schema.FieldTypeText,
+			Required: true,
+		},
+		{
+			Name:     "episode",
+			Type:     schema.FieldTypeText,
+			Required: true,
+		},
+		{
+			Name: "name",
+			Type: schema.FieldTypeText,
+		},
+		{
+			Name: "website",
+			Type: schema.FieldTypeText,
+		},
+		{
+			Name: "upd_dt",
+			Type: schema.FieldTypeDate,
+		},
+		{
+			Name: "preferred",
+			Type: schema.FieldTypeBool,
+		},
+		{
+			Name: "status",
+			Type: schema.FieldTypeText,
+		},
+		{
+			Name:    "files",
+			Type:    schema.FieldTypeJson,
+			Options: &schema.JsonOptions{},
+		},
+		{
+			Name: "error",
+			Type: schema.FieldTypeText,
+		},
+		{
+			Name:    "unmatched_links",
+			Type:    schema.FieldTypeJson,
+			Options: &schema.JsonOptions{},
+		},
+	}
+}
+
+// jobsDedupKeyIndex는 같은 dedup_key의 작업을 한 번만 추가하도록 막는 인덱스입니다.
+// dedup_key가 비어 있는 작업은 중복을 확인하지 않습니다.
+const jobsDedupKeyIndex = "CREATE UNIQUE INDEX idx_jobs_dedup_key ON jobs (dedup_key) WHERE dedup_key != ''"
+
+// jobsDedupKeyField는 "jobs" collection의 dedup_key 필드를 반환합니다.
+func jobsDedupKeyField() *schema.SchemaField {
+	return &schema.SchemaField{
+		Name: "dedup_key",
+		Type: schema.FieldTypeText,
+	}
+}
+
+// createJobsCollection은 자막 수집 작업 큐로 사용하는 "jobs" collection을 생성합니다.
+func createJobsCollection(app *pocketbase.PocketBase) error {
+	collection := &models.Collection{
+		Name:       "jobs",
+		Type:       models.CollectionTypeBase,
+		ListRule:   nil,
+		ViewRule:   nil,
+		CreateRule: nil,
+		UpdateRule: nil,
+		DeleteRule: nil,
 		Schema: schema.NewSchema(
 			&schema.SchemaField{
-				Name:     "anime_no",
-				Type:     schema.FieldTypeNumber,
+				Name:     "stage",
+				Type:     schema.FieldTypeText,
 				Required: true,
 			},
 			&schema.SchemaField{
-				Name:     "subject",
+				Name:     "state",
 				Type:     schema.FieldTypeText,
 				Required: true,
 			},
 			&schema.SchemaField{
-				Name:     "episode",
+				Name: "subtitle_id",
+				Type: schema.FieldTypeText,
+			},
+			&schema.SchemaField{
+				Name:    "payload",
+				Type:    schema.FieldTypeJson,
+				Options: &schema.JsonOptions{},
+			},
+			&schema.SchemaField{
+				Name: "attempts",
+				Type: schema.FieldTypeNumber,
+			},
+			&schema.SchemaField{
+				Name: "max_attempts",
+				Type: schema.FieldTypeNumber,
+			},
+			&schema.SchemaField{
+				Name: "next_run_at",
+				Type: schema.FieldTypeDate,
+			},
+			&schema.SchemaField{
+				Name: "last_error",
+				Type: schema.FieldTypeText,
+			},
+			&schema.SchemaField{
+				Name: "lease_owner",
+				Type: schema.FieldTypeText,
+			},
+			&schema.SchemaField{
+				Name: "lease_expires_at",
+				Type: schema.FieldTypeDate,
+			},
+			jobsDedupKeyField(),
+		),
+		Indexes: types.JsonArray[string]{
+			"CREATE INDEX idx_jobs_claim ON jobs (stage, state, next_run_at)",
+			"CREATE INDEX idx_jobs_subtitle ON jobs (subtitle_id)",
+			jobsDedupKeyIndex,
+		},
+	}
+
+	if err := app.Dao().SaveCollection(collection); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// createFilesCollection은 파일의 논리 경로(anime_no/episode/name/파일 이름)를 blob에 연결하는 "files" collection을 생성합니다.
+func createFilesCollection(app *pocketbase.PocketBase) error {
+	collection := &models.Collection{
+		Name:       "files",
+		Type:       models.CollectionTypeBase,
+		ListRule:   nil,
+		ViewRule:   nil,
+		CreateRule: nil,
+		UpdateRule: nil,
+		DeleteRule: nil,
+		Schema: schema.NewSchema(
+			&schema.SchemaField{
+				Name:     "path",
+				Type:     schema.FieldTypeText,
+				Required: true,
+			},
+			&schema.SchemaField{
+				Name:     "sha256",
 				Type:     schema.FieldTypeText,
 				Required: true,
 			},
 			&schema.SchemaField{
-				Name: "name",
+				Name: "size",
+				Type: schema.FieldTypeNumber,
+			},
+			&schema.SchemaField{
+				Name: "mime_type",
 				Type: schema.FieldTypeText,
 			},
 			&schema.SchemaField{
-				Name: "website",
+				Name: "subtitle_id",
 				Type: schema.FieldTypeText,
 			},
 		),
+		Indexes: types.JsonArray[string]{
+			"CREATE UNIQUE INDEX idx_files_path ON files (path)",
+			"CREATE INDEX idx_files_sha256 ON files (sha256)",
+			"CREATE INDEX idx_files_subtitle ON files (subtitle_id)",
+		},
+	}
+
+	if err := app.Dao().SaveCollection(collection); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// createBlobsCollection은 SHA-256 해시로 저장된 blob의 참조 횟수를 저장하는 "blobs" collection을 생성합니다.
+func createBlobsCollection(app *pocketbase.PocketBase) error {
+	collection := &models.Collection{
+		Name:       "blobs",
+		Type:       models.CollectionTypeBase,
+		ListRule:   nil,
+		ViewRule:   nil,
+		CreateRule: nil,
+		UpdateRule: nil,
+		DeleteRule: nil,
+		Schema: schema.NewSchema(
+			&schema.SchemaField{
+				Name:     "sha256",
+				Type:     schema.FieldTypeText,
+				Required: true,
+			},
+			&schema.SchemaField{
+				Name: "size",
+				Type: schema.FieldTypeNumber,
+			},
+			&schema.SchemaField{
+				Name: "ref_count",
+				Type: schema.FieldTypeNumber,
+			},
+		),
+		Indexes: types.JsonArray[string]{
+			"CREATE UNIQUE INDEX idx_blobs_sha256 ON blobs (sha256)",
+			"CREATE INDEX idx_blobs_ref_count ON blobs (ref_count)",
+		},
 	}
 
 	if err := app.Dao().SaveCollection(collection); err != nil {
@@ -0,0 +1,169 @@
+package downloader
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tarMagicOffset은 tar 헤더에서 "ustar" 매직의 위치입니다.
+const tarMagicOffset = 257
+
+// signature는 파일 앞부분의 매직 바이트입니다.
+type signature struct {
+	offset int
+	magic  []byte
+}
+
+var (
+	zipSignatures = []signature{
+		{0, []byte("PK\x03\x04")},
+		{0, []byte("PK\x05\x06")}, // 빈 zip 파일
+		{0, []byte("PK\x07\x08")}, // 분할 zip 파일
+	}
+	rarSignatures = []signature{
+		{0, []byte("Rar!\x1a\x07\x00")},     // RAR4
+		{0, []byte("Rar!\x1a\x07\x01\x00")}, // RAR5
+	}
+	sevenZSignatures = []signature{{0, []byte("7z\xbc\xaf\x27\x1c")}}
+	gzipSignatures   = []signature{{0, []byte("\x1f\x8b\x08")}}
+	xzSignatures     = []signature{{0, []byte("\xfd7zXZ\x00")}}
+	bzip2Signatures  = []signature{{0, []byte("BZh")}}
+	tarSignatures    = []signature{{tarMagicOffset, []byte("ustar")}}
+	fontSignatures   = []signature{
+		{0, []byte("\x00\x01\x00\x00")}, // TrueType
+		{0, []byte("true")},             // TrueType (Mac)
+		{0, []byte("OTTO")},             // OpenType (CFF)
+		{0, []byte("ttcf")},             // TrueType Collection
+		{0, []byte("wOFF")},
+		{0, []byte("wOF2")},
+	}
+	subtitleSignatures = []signature{
+		{0, []byte("[Script Info]")},
+		{0, []byte("\xef\xbb\xbf[Script Info]")},
+		{0, []byte("WEBVTT")},
+		{0, []byte("\xef\xbb\xbfWEBVTT")},
+	}
+)
+
+var (
+	// subtitleExts는 압축을 풀지 않고 그대로 두는 자막 파일의 확장자입니다.
+	subtitleExts = []string{".ass", ".ssa", ".smi", ".sami", ".srt", ".vtt", ".sub", ".sup", ".idx"}
+	// fontExts는 압축을 풀지 않고 그대로 두는 글꼴 파일의 확장자입니다.
+	fontExts = []string{".ttf", ".otf", ".ttc", ".otc", ".woff", ".woff2"}
+)
+
+// match는 header가 signatures 중 하나로 시작하는지 확인합니다.
+func match(header []byte, signatures []signature) bool {
+	for _, sig := range signatures {
+		end := sig.offset + len(sig.magic)
+		if end <= len(header) && bytes.Equal(header[sig.offset:end], sig.magic) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasExt는 name의 확장자가 exts 중 하나인지 확인합니다.
+func hasExt(name string, exts []string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range exts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// getPackType은 파일 앞부분의 매직 바이트로 압축 파일의 타입을 판별합니다.
+// 확장자는 매직 바이트로 구분할 수 없을 때만 사용합니다.
+// 예를 들어 확장자가 없는 구글 드라이브 파일이나, 확장자는 .zip 이지만 실제로는 rar 파일인 경우도 올바르게 판별합니다.
+func getPackType(filePath string) (PackType, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return NotSupported, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return NotSupported, err
+	}
+	header = header[:n]
+
+	name := strings.ToLower(filepath.Base(filePath))
+	switch {
+	case match(header, zipSignatures):
+		return Zip, nil
+	case match(header, rarSignatures):
+		return Rar, nil
+	case match(header, sevenZSignatures):
+		return SevenZ, nil
+	case match(header, gzipSignatures):
+		return compressedPackType(filePath, gunzip, name, TarGz, Gzip, ".tar.gz", ".tgz"), nil
+	case match(header, xzSignatures):
+		return compressedPackType(filePath, unxz, name, TarXz, Xz, ".tar.xz", ".txz"), nil
+	case isBzip2(header):
+		return compressedPackType(filePath, bunzip2, name, TarBz2, Bzip2, ".tar.bz2", ".tbz2", ".tbz"), nil
+	case match(header, tarSignatures):
+		return Tar, nil
+	case match(header, subtitleSignatures):
+		return Subtitle, nil
+	case match(header, fontSignatures) && !hasExt(name, subtitleExts):
+		return Font, nil
+	}
+
+	// 매직 바이트가 없는 형식은 확장자로 판별합니다.
+	switch {
+	case hasExt(name, []string{".tar"}):
+		// 매직이 없는 오래된 V7 tar 파일입니다.
+		return Tar, nil
+	case hasExt(name, subtitleExts):
+		return Subtitle, nil
+	case hasExt(name, fontExts):
+		return Font, nil
+	default:
+		return NotSupported, nil
+	}
+}
+
+// isBzip2는 header가 bzip2 스트림의 시작인지 확인합니다.
+// "BZh" 다음에 블록 크기(1-9)와 블록 매직(0x314159265359)이 와야 합니다.
+func isBzip2(header []byte) bool {
+	return match(header, bzip2Signatures) &&
+		len(header) >= 10 &&
+		header[3] >= '1' && header[3] <= '9' &&
+		bytes.Equal(header[4:10], []byte("\x31\x41\x59\x26\x53\x59"))
+}
+
+// compressedPackType은 gzip, xz, bzip2로 압축된 파일이 tar 파일인지, 파일 하나를 압축한 것인지 판별합니다.
+// 압축을 푼 앞부분에 tar 매직이 있으면 tar로 보고, 매직이 없으면 확장자(tarExts)로 판별합니다.
+func compressedPackType(filePath string, decompress decompressor, name string, tarType PackType, fileType PackType, tarExts ...string) PackType {
+	if isCompressedTar(filePath, decompress) {
+		return tarType
+	}
+	for _, ext := range tarExts {
+		if strings.HasSuffix(name, ext) {
+			return tarType
+		}
+	}
+	return fileType
+}
+
+// isCompressedTar은 압축을 푼 앞부분에 tar 매직이 있는지 확인합니다.
+func isCompressedTar(filePath string, decompress decompressor) bool {
+	r, closer, err := openDecompressed(filePath, decompress)
+	if err != nil {
+		return false
+	}
+	defer closer.Close()
+
+	header := make([]byte, tarMagicOffset+len("ustar"))
+	if _, err := io.ReadFull(r, header); err != nil {
+		return false
+	}
+	return match(header, tarSignatures)
+}
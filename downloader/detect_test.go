@@ -0,0 +1,198 @@
+package downloader
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetPackType(t *testing.T) {
+	subtitle := "[Script Info]\nTitle: 01\n"
+	tests := []struct {
+		name string
+		file string
+		data func(t *testing.T) []byte
+		want PackType
+	}{
+		{
+			name: "zip",
+			file: "01화.zip",
+			data: func(t *testing.T) []byte { return buildZip(t, archiveFile{name: "01화.ass", body: subtitle}) },
+			want: Zip,
+		},
+		{
+			// 확장자는 .zip 이지만 실제로는 rar 파일입니다.
+			name: "rar named zip",
+			file: "01화.zip",
+			data: func(t *testing.T) []byte { return []byte("Rar!\x1a\x07\x00\xcf\x90\x73\x00\x00\x0d\x00") },
+			want: Rar,
+		},
+		{
+			name: "rar5 named zip",
+			file: "01화.zip",
+			data: func(t *testing.T) []byte { return []byte("Rar!\x1a\x07\x01\x00\x33\x92\xb5\xe5\x0a\x01\x05") },
+			want: Rar,
+		},
+		{
+			// 확장자가 없는 구글 드라이브 파일입니다.
+			name: "extensionless zip",
+			file: "7화",
+			data: func(t *testing.T) []byte { return buildZip(t, archiveFile{name: "07화.ass", body: subtitle}) },
+			want: Zip,
+		},
+		{
+			name: "7z named rar",
+			file: "01화.rar",
+			data: func(t *testing.T) []byte { return []byte("7z\xbc\xaf\x27\x1c\x00\x04") },
+			want: SevenZ,
+		},
+		{
+			name: "gzip subtitle",
+			file: "01화.ass.gz",
+			data: func(t *testing.T) []byte { return gzipData(t, []byte(subtitle)) },
+			want: Gzip,
+		},
+		{
+			name: "extensionless gzip subtitle",
+			file: "01화",
+			data: func(t *testing.T) []byte { return gzipData(t, []byte(subtitle)) },
+			want: Gzip,
+		},
+		{
+			name: "gzip tarball named gz",
+			file: "01화.gz",
+			data: func(t *testing.T) []byte {
+				return gzipData(t, buildTar(t, archiveFile{name: "01화.ass", body: subtitle}))
+			},
+			want: TarGz,
+		},
+		{
+			// 압축을 푼 앞부분이 tar가 아니어도 확장자가 .tar.gz이면 tar로 풉니다.
+			name: "gzip named tar.gz",
+			file: "01화.tar.gz",
+			data: func(t *testing.T) []byte { return gzipData(t, []byte(subtitle)) },
+			want: TarGz,
+		},
+		{
+			name: "xz subtitle",
+			file: "01화.ass.xz",
+			data: func(t *testing.T) []byte { return xzData(t, []byte(subtitle)) },
+			want: Xz,
+		},
+		{
+			name: "bzip2 tarball",
+			file: "01화.bz2",
+			data: func(t *testing.T) []byte { return readTestdata(t, "subtitle.tar.bz2") },
+			want: TarBz2,
+		},
+		{
+			// "BZh"로 시작해도 bzip2 블록 매직이 없으면 bzip2가 아닙니다.
+			name: "text starting with BZh",
+			file: "readme.txt",
+			data: func(t *testing.T) []byte { return []byte("BZh9 is not a bzip2 stream") },
+			want: NotSupported,
+		},
+		{
+			name: "ustar only tarball",
+			file: "01화",
+			data: func(t *testing.T) []byte { return buildTar(t, archiveFile{name: "01화.ass", body: subtitle}) },
+			want: Tar,
+		},
+		{
+			// 매직이 없는 V7 tar 파일은 확장자로 판별합니다.
+			name: "v7 tar",
+			file: "01화.tar",
+			data: func(t *testing.T) []byte { return make([]byte, 1024) },
+			want: Tar,
+		},
+		{
+			name: "ass",
+			file: "01화.ass",
+			data: func(t *testing.T) []byte { return []byte(subtitle) },
+			want: Subtitle,
+		},
+		{
+			name: "extensionless ass",
+			file: "01화",
+			data: func(t *testing.T) []byte { return []byte("\xef\xbb\xbf" + subtitle) },
+			want: Subtitle,
+		},
+		{
+			name: "smi",
+			file: "01화.smi",
+			data: func(t *testing.T) []byte { return []byte("<SAMI>\n<BODY>\n") },
+			want: Subtitle,
+		},
+		{
+			name: "srt",
+			file: "01화.SRT",
+			data: func(t *testing.T) []byte { return []byte("1\n00:00:01,000 --> 00:00:02,000\n안녕하세요\n") },
+			want: Subtitle,
+		},
+		{
+			// 글꼴 매직("true")으로 시작하는 자막은 자막으로 봅니다.
+			name: "smi starting with font magic",
+			file: "01화.smi",
+			data: func(t *testing.T) []byte { return []byte("true story") },
+			want: Subtitle,
+		},
+		{
+			name: "ttf",
+			file: "a.ttf",
+			data: func(t *testing.T) []byte { return []byte("\x00\x01\x00\x00\x00\x0e\x00\x80") },
+			want: Font,
+		},
+		{
+			name: "extensionless otf",
+			file: "a",
+			data: func(t *testing.T) []byte { return []byte("OTTO\x00\x0e\x00\x80") },
+			want: Font,
+		},
+		{
+			name: "unknown",
+			file: "01화",
+			data: func(t *testing.T) []byte { return []byte("<html></html>") },
+			want: NotSupported,
+		},
+		{
+			name: "empty",
+			file: "01화.zip",
+			data: func(t *testing.T) []byte { return nil },
+			want: NotSupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := writeArchive(t, t.TempDir(), tt.file, tt.data(t))
+			got, err := getPackType(filePath)
+			if err != nil {
+				t.Fatalf("getPackType(%q) error = %v", tt.file, err)
+			}
+			if got != tt.want {
+				t.Errorf("getPackType(%q) = %s, want %s", tt.file, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnpackNotArchive(t *testing.T) {
+	tests := []struct {
+		file string
+		data string
+	}{
+		{file: "01화.ass", data: "[Script Info]\n"},
+		{file: "01화.smi", data: "<SAMI>\n"},
+		{file: "01화.srt", data: "1\n00:00:01,000 --> 00:00:02,000\n"},
+		{file: "a.ttf", data: "\x00\x01\x00\x00"},
+		{file: "01화", data: "<html></html>"},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		filePath := writeArchive(t, dir, tt.file, []byte(tt.data))
+		if _, err := NewUnpacker(UnpackerConfig{}).Unpack(filePath, filepath.Join(dir, "files")); !errors.Is(err, ErrNotSupportedPackType) {
+			t.Errorf("Unpack(%q) error = %v, want ErrNotSupportedPackType", tt.file, err)
+		}
+	}
+}
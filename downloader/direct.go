@@ -0,0 +1,43 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// directExts는 DirectSource가 바로 받는 자막, 압축 파일 확장자입니다.
+var directExts = map[string]bool{
+	".zip": true, ".7z": true, ".rar": true,
+	".ass": true, ".ssa": true, ".smi": true, ".srt": true, ".vtt": true,
+}
+
+// DirectSource는 자막이나 압축 파일을 가리키는 일반 HTTP 링크를 다운로드합니다.
+type DirectSource struct{}
+
+// Type은 DirectURL을 반환합니다.
+func (s *DirectSource) Type() DownloadURLType { return DirectURL }
+
+// Match는 url이 자막, 압축 파일 확장자로 끝나는 http(s) 링크인지 판별합니다.
+func (s *DirectSource) Match(rawURL string) bool {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return false
+	}
+	return directExts[strings.ToLower(path.Ext(parsedURL.Path))]
+}
+
+// Resolve는 url을 그대로 요청합니다.
+func (s *DirectSource) Resolve(ctx context.Context, client *http.Client, url string) (*http.Request, error) {
+	return newRequest(ctx, url, "")
+}
+
+// Download는 파일을 받습니다.
+func (s *DirectSource) Download(ctx context.Context, d *Downloader, url string, dest Dest) ([]*Result, error) {
+	return d.downloadResolved(ctx, s, url, dest, fileNameFromURL(url))
+}
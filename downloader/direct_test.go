@@ -0,0 +1,84 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDirectDownloadFileName(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		disposition string
+		want        string
+	}{
+		{name: "url path", path: "/files/%ED%85%8C%EC%8A%A4%ED%8A%B8_01.ass", want: "테스트_01.ass"},
+		{
+			// Content-Disposition의 파일 이름이 URL보다 우선합니다.
+			name:        "filename*",
+			path:        "/files/download.zip",
+			disposition: `attachment; filename="fallback.zip"; filename*=UTF-8''01%ED%99%94.zip`,
+			want:        "01화.zip",
+		},
+		{name: "filename", path: "/files/download.zip", disposition: `attachment; filename="01.zip"`, want: "01.zip"},
+		{name: "euc-kr filename*", path: "/files/download.zip", disposition: `attachment; filename*=EUC-KR''01%C8%AD.ass`, want: "01화.ass"},
+		{
+			// 파일 이름의 경로 구분자는 _로 바꿔서 저장 경로를 벗어나지 않습니다.
+			name:        "path in filename",
+			path:        "/files/download.zip",
+			disposition: `attachment; filename="../../01.zip"`,
+			want:        ".._.._01.zip",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.EscapedPath() != tt.path {
+					http.NotFound(w, r)
+					return
+				}
+				if tt.disposition != "" {
+					w.Header().Set("Content-Disposition", tt.disposition)
+				}
+				w.Write([]byte("subtitle content"))
+			}))
+			defer server.Close()
+			d := newTestDownloader(t, server)
+			dest := newTestDest(t)
+
+			results, err := (&DirectSource{}).Download(context.Background(), d, server.URL+tt.path, dest)
+			if err != nil {
+				t.Fatalf("Download() error = %v", err)
+			}
+			if len(results) != 1 || results[0].Name != tt.want {
+				t.Fatalf("Download() = %+v, want %s", results, tt.want)
+			}
+			if data := readStored(t, dest, results[0].Path); string(data) != "subtitle content" {
+				t.Errorf("content = %q, want %q", data, "subtitle content")
+			}
+		})
+	}
+}
+
+func TestDirectMatch(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://example.com/files/01화.ZIP", want: true},
+		{url: "https://example.com/files/01.ass?download=1", want: true},
+		{url: "http://example.com/01.smi", want: true},
+		{url: "https://example.com/files/01.html", want: false},
+		{url: "https://example.com/files/", want: false},
+		{url: "ftp://example.com/01.zip", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := (&DirectSource{}).Match(tt.url); got != tt.want {
+			t.Errorf("Match(%q) = %t, want %t", tt.url, got, tt.want)
+		}
+	}
+}
@@ -2,10 +2,21 @@ package downloader
 
 import (
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
+	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/option"
@@ -13,8 +24,10 @@ import (
 
 // Unpacker는 압축 파일을 풉니다.
 type Unpacker interface {
-	// Unpack는 압축 파일을 풉니다.
-	Unpack(filePath string, unpackPath string) error
+	// Unpack는 filePath 압축 파일을 unpackPath 디렉토리에 풀고 풀린 파일 목록을 반환합니다.
+	// 형식은 확장자가 아닌 파일 앞부분의 매직 바이트로 판별합니다.
+	// 압축 파일이 아니거나(자막, 글꼴 파일 등) 지원하지 않는 형식이면 ErrNotSupportedPackType을 반환합니다.
+	Unpack(filePath string, unpackPath string) (*Manifest, error)
 }
 
 // Parser는 다운로드 URL을 파싱합니다.
@@ -23,82 +36,355 @@ type Parser interface {
 	GetDownloadURLType(url string) DownloadURLType
 	// ParseGoogleDriveURL은 구글 드라이브 URL을 파싱하여 파일 ID를 반환합니다.
 	ParseGoogleDriveURL(url string) (string, error)
+	// ParseGoogleDriveFolderURL은 구글 드라이브 폴더 URL을 파싱하여 폴더 ID를 반환합니다.
+	ParseGoogleDriveFolderURL(url string) (string, error)
+}
+
+// userAgent는 블로그 첨부 파일을 다운로드할 때 사용하는 User-Agent입니다.
+// 네이버 블로그는 브라우저가 아닌 User-Agent의 요청을 거부합니다.
+const userAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36"
+
+// DefaultGDriveWebBaseURL은 구글 드라이브 웹 다운로드의 기본 주소입니다.
+const DefaultGDriveWebBaseURL = "https://drive.google.com"
+
+// Config는 Downloader 설정입니다.
+type Config struct {
+	GDriveAPIKey          string        // Google Drive API 키, 비어 있으면 웹 다운로드만 사용합니다.
+	GDriveWebBaseURL      string        // 구글 드라이브 웹 다운로드(uc?export=download) 주소
+	TempDir               string        // 받는 중인 파일(.part)과 임시 파일을 두는 로컬 디렉토리
+	ConnectTimeout        time.Duration // 연결과 TLS 핸드셰이크의 타임아웃
+	ResponseHeaderTimeout time.Duration // 요청을 보낸 뒤 응답 헤더를 기다리는 타임아웃
+	ReadTimeout           time.Duration // 응답 본문의 데이터를 기다리는 타임아웃, 넘으면 ErrReadTimeout으로 중단합니다.
+}
+
+// DefaultConfig는 기본 Downloader 설정을 반환합니다.
+func DefaultConfig() Config {
+	return Config{
+		GDriveWebBaseURL:      DefaultGDriveWebBaseURL,
+		TempDir:               "./downloads/.tmp",
+		ConnectTimeout:        30 * time.Second,
+		ResponseHeaderTimeout: time.Minute,
+		ReadTimeout:           time.Minute,
+	}
 }
 
 // Downloader는 다운로드를 수행합니다.
 type Downloader struct {
-	GDriveClient *drive.Service // Google Drive API Client
-	Parser       Parser         // 다운로드 URL 파서
-	DownloadDir  string         // 다운로드 디렉토리
+	GDriveClient     *drive.Service  // Google Drive API Client, nil이면 웹 다운로드를 사용합니다.
+	GDriveAPIKey     string          // Google Drive API 키, Drive API로 파일 내용을 받을 때 사용합니다.
+	GDriveWebBaseURL string          // 구글 드라이브 웹 다운로드 주소
+	HTTPClient       *http.Client    // 파일 다운로드에 사용하는 HTTP Client
+	Parser           Parser          // 다운로드 URL 파서
+	Sources          *SourceRegistry // 다운로드 호스트별 Source
+	TempDir          string          // 받는 중인 파일과 임시 파일을 두는 로컬 디렉토리
+}
+
+// Sink는 다운로드한 파일을 저장하는 곳입니다. storage.Storage도 Sink입니다.
+type Sink interface {
+	// Put은 다 받고 검증한 로컬 파일 srcPath를 logicalPath("/"로 구분된 상대 경로)로 저장합니다.
+	// 성공하면 srcPath는 옮겨지거나 지워집니다.
+	Put(logicalPath string, srcPath string) error
+}
+
+// Dest는 다운로드한 파일을 저장할 곳입니다.
+type Dest struct {
+	Dir  string // 파일을 저장할 논리 경로의 디렉토리 (예: anime_no/episode/name)
+	Sink Sink   // 다 받은 파일을 저장하는 곳
+}
+
+// sub는 Dir 아래의 name 디렉토리에 저장하는 Dest를 반환합니다.
+func (dest Dest) sub(name string) Dest {
+	return Dest{Dir: path.Join(dest.Dir, name), Sink: dest.Sink}
+}
+
+// put은 검증한 로컬 파일 srcPath를 Dir/result.Name 으로 Sink에 저장하고 result.Path를 채웁니다.
+func (dest Dest) put(result *Result, srcPath string) error {
+	logicalPath := path.Join(dest.Dir, result.Name)
+	if err := dest.Sink.Put(logicalPath, srcPath); err != nil {
+		return fmt.Errorf("failed to store %s: %w", logicalPath, err)
+	}
+	result.Path = logicalPath
+	return nil
+}
+
+// StatusError는 다운로드 요청이 200이 아닌 상태 코드를 반환했을 때의 에러입니다.
+type StatusError struct {
+	URL        string // 요청 URL
+	StatusCode int    // HTTP 상태 코드
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d from %s", e.StatusCode, e.URL)
 }
 
 // NewDownloader는 Downloader를 생성합니다.
-func NewDownloader(ctx context.Context, apiKey string, downloadDir string) *Downloader {
-	gdriveClient, err := drive.NewService(ctx, option.WithAPIKey(apiKey))
+// API 키가 없으면 Drive API Client를 만들지 않고 구글 드라이브 파일을 웹 다운로드로 받습니다.
+// 설정되지 않은 값은 DefaultConfig의 값을 사용합니다.
+func NewDownloader(ctx context.Context, config Config) (*Downloader, error) {
+	defaults := DefaultConfig()
+	if config.GDriveWebBaseURL == "" {
+		config.GDriveWebBaseURL = defaults.GDriveWebBaseURL
+	}
+	config.GDriveWebBaseURL = strings.TrimRight(config.GDriveWebBaseURL, "/")
+	if config.TempDir == "" {
+		config.TempDir = defaults.TempDir
+	}
+	if config.ConnectTimeout <= 0 {
+		config.ConnectTimeout = defaults.ConnectTimeout
+	}
+	if config.ResponseHeaderTimeout <= 0 {
+		config.ResponseHeaderTimeout = defaults.ResponseHeaderTimeout
+	}
+	if config.ReadTimeout <= 0 {
+		config.ReadTimeout = defaults.ReadTimeout
+	}
+	transport := newTransport(config)
+
+	var gdriveClient *drive.Service
+	if config.GDriveAPIKey != "" {
+		// Drive API 요청에도 같은 타임아웃을 적용하기 위해 HTTP Client를 직접 넘깁니다.
+		var err error
+		gdriveClient, err = drive.NewService(ctx, option.WithHTTPClient(&http.Client{
+			Transport: &apiKeyTransport{key: config.GDriveAPIKey, base: transport},
+		}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create drive service: %w", err)
+		}
+	}
+
+	// 구글 드라이브의 바이러스 검사 확인 쿠키를 다음 요청에 보내기 위해 쿠키를 저장합니다.
+	jar, err := cookiejar.New(nil)
 	if err != nil {
-		log.Fatalf("failed to create drive service: %v", err)
+		return nil, fmt.Errorf("failed to create cookie jar: %w", err)
 	}
 
+	sources := DefaultSourceRegistry()
 	return &Downloader{
-		GDriveClient: gdriveClient,
-		Parser:       &ParserImpl{},
-		DownloadDir:  downloadDir,
-	}
+		GDriveClient:     gdriveClient,
+		GDriveAPIKey:     config.GDriveAPIKey,
+		GDriveWebBaseURL: config.GDriveWebBaseURL,
+		HTTPClient:       &http.Client{Transport: transport, Jar: jar},
+		Parser:           &ParserImpl{Sources: sources},
+		Sources:          sources,
+		TempDir:          config.TempDir,
+	}, nil
+}
+
+// Result는 다운로드 결과입니다.
+type Result struct {
+	Path     string // Sink에 저장된 논리 경로 (Dest.Dir/Name)
+	Name     string // 파일 이름
+	Size     int64  // 파일 크기 (byte)
+	MD5      string // MD5 해시 (hex)
+	SHA256   string // SHA-256 해시 (hex)
+	MIMEType string // MIME 타입
+}
+
+// expectation은 다운로드한 파일을 검증할 값입니다. 값이 비어 있으면 검증하지 않습니다.
+type expectation struct {
+	size     int64  // 파일 크기, 0 이하이면 검증하지 않습니다.
+	md5      string // MD5 해시 (hex)
+	mimeType string // 원본이 알려준 MIME 타입
 }
 
 // Download는 다운로드를 수행합니다.
-func (d *Downloader) Download(fileUrl string) error {
-	// 다운로드 URL 타입을 판별합니다.
-	urlType := d.Parser.GetDownloadURLType(fileUrl)
-	log.Printf("URL Type: %s\n", urlType)
-	// 다운로드 URL 타입에 따라 다운로드를 수행합니다.
-	switch urlType {
-	case GoogleDriveURL:
-		fileID, err := d.Parser.ParseGoogleDriveURL(fileUrl)
-		if err != nil {
-			return err
-		}
-		log.Printf("File ID: %s\n", fileID)
+// 파일은 TempDir에 받아 검증한 뒤 dest.Sink의 dest.Dir 아래에 저장되며, 로컬에는 남지 않습니다.
+// 폴더 URL은 폴더 안의 모든 파일을 다운로드하므로 여러 개의 결과를 반환합니다.
+// ctx가 취소되면 진행 중인 요청을 중단합니다.
+func (d *Downloader) Download(ctx context.Context, fileUrl string, dest Dest) ([]*Result, error) {
+	// 다운로드 URL에 맞는 Source를 찾습니다.
+	source := d.Sources.Source(fileUrl)
+	if source == nil {
+		return nil, fmt.Errorf("not supported download url: %s", fileUrl)
+	}
+	log.Printf("URL Type: %s\n", source.Type())
 
-		// 파일 메타데이터를 가져옵니다.
-		file, err := d.GDriveClient.Files.Get(fileID).Do()
-		if err != nil {
-			return err
-		}
-		log.Printf("File Name: %s\n", file.Name)
+	return source.Download(ctx, d, fileUrl, dest)
+}
 
-		// 파일을 다운로드합니다.
-		res, err := d.GDriveClient.Files.Get(fileID).Download()
-		if err != nil {
-			return err
-		}
-		defer res.Body.Close()
+// downloadGoogleDrive는 구글 드라이브 파일을 다운로드합니다.
+// Drive API Client가 없거나 API 할당량을 초과하면 웹 다운로드로 받습니다.
+func (d *Downloader) downloadGoogleDrive(ctx context.Context, fileID string, dest Dest) (*Result, error) {
+	log.Printf("File ID: %s\n", fileID)
+	if d.GDriveClient == nil {
+		return d.downloadGoogleDriveWeb(ctx, fileID, dest)
+	}
 
-		// TODO: 파일을 저장합니다.
-		// fileName := strings.TrimSpace(strings.TrimSuffix(file.Name, ".zip"))
-		// filePath := fmt.Sprintf("%s/%s/%s", downloadDir, fileName, file.Name)
+	result, err := d.downloadGoogleDriveAPI(ctx, fileID, dest)
+	if isQuotaError(err) {
+		log.Printf("Drive API quota exceeded, falling back to web download: %v\n", err)
+		return d.downloadGoogleDriveWeb(ctx, fileID, dest)
+	}
+	return result, err
+}
 
-		// saveFile(fileName, res.Body)
-	case NaverBlogURL:
-		// http 요청을 보냅니다.
-		resp, err := http.Get(fileUrl)
+// downloadGoogleDriveAPI는 Drive API로 파일을 다운로드하고 드라이브 메타데이터의 크기와 MD5로 검증합니다.
+// 파일 내용은 alt=media 요청을 downloadRequest로 받으므로 중단되면 .part 파일부터 이어서 받습니다.
+func (d *Downloader) downloadGoogleDriveAPI(ctx context.Context, fileID string, dest Dest) (*Result, error) {
+	// 파일 메타데이터를 가져옵니다.
+	file, err := d.GDriveClient.Files.Get(fileID).Fields("id", "name", "size", "md5Checksum", "mimeType").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("File Name: %s\n", file.Name)
+
+	// 파일을 다운로드합니다. API 키는 URL에 남지 않도록 헤더로 보냅니다.
+	mediaURL := d.GDriveClient.BasePath + "files/" + url.PathEscape(fileID) + "?alt=media"
+	req, err := newRequest(ctx, mediaURL, "")
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Goog-Api-Key", d.GDriveAPIKey)
+
+	return d.downloadRequest(mediaURL, req, dest, file.Name, expectation{
+		size:     file.Size,
+		md5:      file.Md5Checksum,
+		mimeType: file.MimeType,
+	})
+}
+
+// googleDriveFolderMimeType은 구글 드라이브 폴더의 MIME 타입입니다.
+const googleDriveFolderMimeType = "application/vnd.google-apps.folder"
+
+// downloadGoogleDriveFolder는 구글 드라이브 폴더 안의 모든 파일을 재귀적으로 다운로드합니다.
+// 파일은 dest.Dir/폴더 이름/하위 폴더 이름/... 에 폴더 구조를 유지하여 저장됩니다.
+func (d *Downloader) downloadGoogleDriveFolder(ctx context.Context, folderID string, dest Dest) ([]*Result, error) {
+	folder, err := d.GDriveClient.Files.Get(folderID).Fields("id", "name", "mimeType").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	if folder.MimeType != googleDriveFolderMimeType {
+		return nil, fmt.Errorf("%s is not a folder", folderID)
+	}
+	log.Printf("Folder Name: %s\n", folder.Name)
+
+	return d.downloadGoogleDriveFolderFiles(ctx, folderID, dest.sub(safeFileName(folder.Name)))
+}
+
+// downloadGoogleDriveFolderFiles는 폴더의 파일을 다운로드하고 하위 폴더를 재귀적으로 처리합니다.
+func (d *Downloader) downloadGoogleDriveFolderFiles(ctx context.Context, folderID string, dest Dest) ([]*Result, error) {
+	var results []*Result
+	pageToken := ""
+	for {
+		call := d.GDriveClient.Files.List().
+			Q(fmt.Sprintf("'%s' in parents and trashed = false", folderID)).
+			Fields("nextPageToken", "files(id, name, mimeType)").
+			PageSize(100).
+			Context(ctx)
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		list, err := call.Do()
 		if err != nil {
-			return err
+			return results, fmt.Errorf("failed to list folder %s: %w", folderID, err)
 		}
-		defer resp.Body.Close()
 
-		// response code를 확인합니다.
-		if resp.StatusCode != http.StatusOK {
-			return err
+		for _, file := range list.Files {
+			switch {
+			case file.MimeType == googleDriveFolderMimeType:
+				subResults, err := d.downloadGoogleDriveFolderFiles(ctx, file.Id, dest.sub(safeFileName(file.Name)))
+				results = append(results, subResults...)
+				if err != nil {
+					return results, err
+				}
+			case strings.HasPrefix(file.MimeType, "application/vnd.google-apps."):
+				// 구글 문서처럼 원본 파일이 없는 항목은 다운로드할 수 없습니다.
+				log.Printf("Skip Google Docs file: %s (%s)\n", file.Name, file.MimeType)
+			default:
+				result, err := d.downloadGoogleDrive(ctx, file.Id, dest)
+				if err != nil {
+					return results, err
+				}
+				results = append(results, result)
+			}
 		}
-		// url을 decode합니다.
-		decodedURL, err := url.QueryUnescape(fileUrl)
-		if err != nil {
-			return err
+
+		if list.NextPageToken == "" {
+			return results, nil
 		}
-		fileName := filepath.Base(decodedURL)
-		log.Printf("File Name: %s\n", fileName)
+		pageToken = list.NextPageToken
 	}
+}
 
-	return nil
+// saveFile은 r의 내용을 TempDir의 임시 파일에 받고 검증한 뒤 dest에 저장합니다.
+// 이어 받을 수 없는 다운로드(Mega)에 사용합니다.
+// 검증에 실패하거나 저장 도중 에러가 발생하면 임시 파일을 지우므로 불완전한 파일이 남지 않습니다.
+func (d *Downloader) saveFile(dest Dest, fileName string, r io.Reader, expected expectation) (*Result, error) {
+	if err := os.MkdirAll(d.TempDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmpFile, err := os.CreateTemp(d.TempDir, "download-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath) // Sink가 옮긴 뒤에는 아무 일도 하지 않습니다.
+
+	_, err = io.Copy(tmpFile, r)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	result, err := verifyFile(tmpPath, fileName, expected)
+	if err != nil {
+		return nil, err
+	}
+	return result, dest.put(result, tmpPath)
+}
+
+// verifyFile은 다 받은 임시 파일 tmpPath의 해시를 계산하고 expected로 검증합니다.
+// 반환하는 Result의 Path는 비어 있으며 dest.put이 채웁니다.
+func verifyFile(tmpPath string, fileName string, expected expectation) (*Result, error) {
+	size, md5Sum, sha256Sum, err := hashFile(tmpPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Name:     safeFileName(fileName),
+		Size:     size,
+		MD5:      md5Sum,
+		SHA256:   sha256Sum,
+		MIMEType: expected.mimeType,
+	}
+	if result.MIMEType == "" {
+		result.MIMEType = mime.TypeByExtension(filepath.Ext(result.Name))
+	}
+
+	// 다운로드한 파일을 검증합니다.
+	if expected.size > 0 && expected.size != size {
+		return nil, fmt.Errorf("size mismatch for %s: expected %d, got %d", fileName, expected.size, size)
+	}
+	if expected.md5 != "" && !strings.EqualFold(expected.md5, result.MD5) {
+		return nil, fmt.Errorf("md5 mismatch for %s: expected %s, got %s", fileName, expected.md5, result.MD5)
+	}
+	return result, nil
+}
+
+// hashFile은 파일의 크기와 MD5, SHA-256 해시(hex)를 계산합니다.
+func hashFile(path string) (int64, string, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", "", err
+	}
+	defer f.Close()
+
+	md5Hash, sha256Hash := md5.New(), sha256.New()
+	size, err := io.Copy(io.MultiWriter(md5Hash, sha256Hash), f)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return size, hex.EncodeToString(md5Hash.Sum(nil)), hex.EncodeToString(sha256Hash.Sum(nil)), nil
+}
+
+// safeFileName은 파일 이름을 경로 구분자가 없는 이름으로 바꿉니다.
+func safeFileName(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(strings.TrimSpace(name))
+	if name == "" || name == "." || name == ".." {
+		return "download"
+	}
+	return name
 }
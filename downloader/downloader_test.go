@@ -0,0 +1,272 @@
+package downloader
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+// newGDriveAPIServer는 FILE_ID 파일의 메타데이터와 내용을 보내는 Drive API 서버를 생성합니다.
+// media는 alt=media 요청을 처리하며, /uc 웹 다운로드는 항상 content를 보냅니다.
+func newGDriveAPIServer(t *testing.T, content string, media http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	sum := md5.Sum([]byte(content))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/files/") && r.Header.Get("X-Goog-Api-Key") != "KEY" {
+			http.Error(w, "missing api key", http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/files/FILE_ID" && r.URL.Query().Get("alt") == "media":
+			media(w, r)
+		case r.URL.Path == "/files/FILE_ID":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"id":"FILE_ID","name":"subtitle.zip","size":"%d","md5Checksum":"%s","mimeType":"application/zip"}`,
+				len(content), hex.EncodeToString(sum[:]))
+		case r.URL.Path == "/uc" && r.URL.Query().Get("id") == "FILE_ID":
+			// 웹 다운로드
+			w.Header().Set("Content-Type", "application/zip")
+			w.Header().Set("Content-Disposition", `attachment; filename="subtitle.zip"`)
+			w.Write([]byte(content))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestDriveDownloader는 server를 Drive API와 웹 다운로드 주소로 사용하는 Downloader를 생성합니다.
+func newTestDriveDownloader(t *testing.T, server *httptest.Server) *Downloader {
+	t.Helper()
+	d := newTestDownloader(t, server)
+	gdriveClient, err := drive.NewService(context.Background(),
+		option.WithHTTPClient(&http.Client{Transport: &apiKeyTransport{key: "KEY", base: http.DefaultTransport}}),
+		option.WithEndpoint(server.URL+"/"))
+	if err != nil {
+		t.Fatalf("drive.NewService() error = %v", err)
+	}
+	d.GDriveClient = gdriveClient
+	d.GDriveAPIKey = "KEY"
+	return d
+}
+
+func TestDownloadGoogleDriveAPIResume(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+
+	var requests int32
+	var ranges []string
+	server := newGDriveAPIServer(t, content, func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if atomic.AddInt32(&requests, 1) == 1 {
+			// 처음 요청은 절반만 보내고 연결을 끊습니다.
+			w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+			w.Write([]byte(content[:500]))
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		// Drive API는 ETag 없이 Range 요청에 206으로 답합니다.
+		if r.Header.Get("Range") != "bytes=500-" || r.Header.Get("If-Range") != "" {
+			http.Error(w, "unexpected range", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes 500-%d/%d", len(content)-1, len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(content[500:]))
+	})
+	d := newTestDriveDownloader(t, server)
+	dest := newTestDest(t)
+
+	if _, err := d.downloadGoogleDrive(context.Background(), "FILE_ID", dest); err == nil {
+		t.Fatal("first downloadGoogleDrive() error = nil, want interrupted download")
+	}
+	result, err := d.downloadGoogleDrive(context.Background(), "FILE_ID", dest)
+	if err != nil {
+		t.Fatalf("second downloadGoogleDrive() error = %v", err)
+	}
+
+	if want := []string{"", "bytes=500-"}; len(ranges) != 2 || ranges[0] != want[0] || ranges[1] != want[1] {
+		t.Errorf("Range headers = %q, want %q", ranges, want)
+	}
+	if result.Name != "subtitle.zip" || result.MIMEType != "application/zip" {
+		t.Errorf("downloadGoogleDrive() = %+v", result)
+	}
+	data := readStored(t, dest, result.Path)
+	if string(data) != content {
+		t.Errorf("content length = %d, want %d", len(data), len(content))
+	}
+}
+
+func TestDownloadGoogleDriveAPIChecksumMismatch(t *testing.T) {
+	server := newGDriveAPIServer(t, "subtitle content", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("corrupted content"))
+	})
+	d := newTestDriveDownloader(t, server)
+	dest := newTestDest(t)
+
+	_, err := d.downloadGoogleDrive(context.Background(), "FILE_ID", dest)
+	if err == nil || !strings.Contains(err.Error(), "mismatch") {
+		t.Errorf("downloadGoogleDrive() error = %v, want mismatch", err)
+	}
+}
+
+func TestDownloadGoogleDriveAPIQuotaFallsBackToWeb(t *testing.T) {
+	server := newGDriveAPIServer(t, "subtitle content", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "download quota exceeded", http.StatusForbidden)
+	})
+	d := newTestDriveDownloader(t, server)
+	dest := newTestDest(t)
+
+	result, err := d.downloadGoogleDrive(context.Background(), "FILE_ID", dest)
+	if err != nil {
+		t.Fatalf("downloadGoogleDrive() error = %v", err)
+	}
+	if result.Size != int64(len("subtitle content")) {
+		t.Errorf("Size = %d, want %d", result.Size, len("subtitle content"))
+	}
+}
+
+// gdriveTestFile은 테스트 Drive API 서버의 파일이나 폴더입니다.
+type gdriveTestFile struct {
+	name     string
+	mimeType string
+	parent   string
+	content  string
+}
+
+// newGDriveFolderServer는 files의 메타데이터, 내용과 폴더 목록을 보내는 Drive API 서버를 생성합니다.
+// 폴더 목록은 한 페이지에 pageSize개씩 나누어 nextPageToken으로 이어서 보냅니다.
+// listed에는 목록을 요청한 "폴더 ID:페이지 토큰"을 순서대로 기록합니다.
+func newGDriveFolderServer(t *testing.T, files map[string]gdriveTestFile, order []string, pageSize int, listed *[]string) *httptest.Server {
+	t.Helper()
+	queryPattern := regexp.MustCompile(`^'([^']+)' in parents and trashed = false$`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Goog-Api-Key") != "KEY" {
+			http.Error(w, "missing api key", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/files" {
+			match := queryPattern.FindStringSubmatch(r.URL.Query().Get("q"))
+			if match == nil {
+				http.Error(w, "unexpected query", http.StatusBadRequest)
+				return
+			}
+			pageToken := r.URL.Query().Get("pageToken")
+			*listed = append(*listed, match[1]+":"+pageToken)
+
+			var children []map[string]string
+			for _, id := range order {
+				if files[id].parent == match[1] {
+					children = append(children, map[string]string{"id": id, "name": files[id].name, "mimeType": files[id].mimeType})
+				}
+			}
+			start := 0
+			if pageToken != "" {
+				start, _ = strconv.Atoi(strings.TrimPrefix(pageToken, "page"))
+			}
+			list := map[string]interface{}{}
+			if end := start + pageSize; end < len(children) {
+				list["nextPageToken"] = fmt.Sprintf("page%d", end)
+				children = children[start:end]
+			} else {
+				children = children[start:]
+			}
+			list["files"] = children
+			json.NewEncoder(w).Encode(list)
+			return
+		}
+
+		file, ok := files[strings.TrimPrefix(r.URL.Path, "/files/")]
+		switch {
+		case !ok:
+			http.NotFound(w, r)
+		case r.URL.Query().Get("alt") == "media":
+			w.Header().Set("Content-Type", file.mimeType)
+			w.Write([]byte(file.content))
+		default:
+			sum := md5.Sum([]byte(file.content))
+			json.NewEncoder(w).Encode(map[string]string{
+				"id":          strings.TrimPrefix(r.URL.Path, "/files/"),
+				"name":        file.name,
+				"mimeType":    file.mimeType,
+				"size":        fmt.Sprint(len(file.content)),
+				"md5Checksum": hex.EncodeToString(sum[:]),
+			})
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDownloadGoogleDriveFolder(t *testing.T) {
+	files := map[string]gdriveTestFile{
+		"FOLDER_ID": {name: "테스트 애니", mimeType: googleDriveFolderMimeType},
+		"EP01":      {name: "01화.ass", mimeType: "text/plain", parent: "FOLDER_ID", content: "episode 1"},
+		"FONTS":     {name: "fonts", mimeType: googleDriveFolderMimeType, parent: "FOLDER_ID"},
+		"FONT_A":    {name: "a.ttf", mimeType: "font/ttf", parent: "FONTS", content: "font a"},
+		"FONT_B":    {name: "b.ttf", mimeType: "font/ttf", parent: "FONTS", content: "font b"},
+		"DOC":       {name: "읽어 주세요", mimeType: "application/vnd.google-apps.document", parent: "FOLDER_ID"},
+		"EP02":      {name: "02화.ass", mimeType: "text/plain", parent: "FOLDER_ID", content: "episode 2"},
+	}
+	order := []string{"EP01", "FONTS", "FONT_A", "FONT_B", "DOC", "EP02"}
+
+	var listed []string
+	server := newGDriveFolderServer(t, files, order, 2, &listed)
+	d := newTestDriveDownloader(t, server)
+	dest := newTestDest(t)
+
+	results, err := d.downloadGoogleDriveFolder(context.Background(), "FOLDER_ID", dest)
+	if err != nil {
+		t.Fatalf("downloadGoogleDriveFolder() error = %v", err)
+	}
+
+	// 하위 폴더는 폴더 구조를 유지하여 저장하고, 구글 문서는 건너뜁니다.
+	want := map[string]string{
+		"1/2/tester/테스트 애니/01화.ass":     "episode 1",
+		"1/2/tester/테스트 애니/fonts/a.ttf": "font a",
+		"1/2/tester/테스트 애니/fonts/b.ttf": "font b",
+		"1/2/tester/테스트 애니/02화.ass":     "episode 2",
+	}
+	got := make(map[string]string)
+	for _, result := range results {
+		got[result.Path] = string(readStored(t, dest, result.Path))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("downloadGoogleDriveFolder() = %q, want %q", got, want)
+	}
+
+	// 폴더마다 nextPageToken이 없을 때까지 목록을 이어서 요청합니다.
+	if wantListed := []string{"FOLDER_ID:", "FONTS:", "FOLDER_ID:page2"}; !reflect.DeepEqual(listed, wantListed) {
+		t.Errorf("listed = %q, want %q", listed, wantListed)
+	}
+}
+
+func TestDownloadGoogleDriveFolderNotFolder(t *testing.T) {
+	files := map[string]gdriveTestFile{
+		"FILE_ID": {name: "01화.ass", mimeType: "text/plain", content: "episode 1"},
+	}
+	var listed []string
+	server := newGDriveFolderServer(t, files, nil, 100, &listed)
+	d := newTestDriveDownloader(t, server)
+
+	if _, err := d.downloadGoogleDriveFolder(context.Background(), "FILE_ID", newTestDest(t)); err == nil {
+		t.Error("downloadGoogleDriveFolder() error = nil, want not a folder error")
+	}
+	if len(listed) != 0 {
+		t.Errorf("listed = %q, want no list request", listed)
+	}
+}
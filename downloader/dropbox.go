@@ -0,0 +1,46 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DropboxSource는 Dropbox 공유 링크를 다운로드합니다.
+type DropboxSource struct{}
+
+// Type은 DropboxURL을 반환합니다.
+func (s *DropboxSource) Type() DownloadURLType { return DropboxURL }
+
+// Match는 url이 Dropbox 공유 링크인지 판별합니다.
+// 링크 형태: https://www.dropbox.com/s/KEY/NAME?dl=0
+// 링크 형태: https://www.dropbox.com/scl/fi/KEY/NAME?rlkey=RLKEY&dl=0
+// 링크 형태: https://dl.dropboxusercontent.com/s/KEY/NAME
+func (s *DropboxSource) Match(url string) bool {
+	switch hostOf(url) {
+	case "dropbox.com", "www.dropbox.com", "dl.dropbox.com", "dl.dropboxusercontent.com":
+		return true
+	}
+	return false
+}
+
+// Resolve는 공유 링크의 dl 파라미터를 1로 바꿔 미리보기 페이지 대신 파일을 받는 요청을 반환합니다.
+func (s *DropboxSource) Resolve(ctx context.Context, client *http.Client, fileUrl string) (*http.Request, error) {
+	parsedURL, err := url.Parse(fileUrl)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(strings.ToLower(parsedURL.Hostname()), "dropboxusercontent.com") {
+		query := parsedURL.Query()
+		query.Del("raw")
+		query.Set("dl", "1")
+		parsedURL.RawQuery = query.Encode()
+	}
+	return newRequest(ctx, parsedURL.String(), "")
+}
+
+// Download는 공유 파일을 받습니다. 폴더 링크는 Dropbox가 zip 파일로 묶어서 보내 줍니다.
+func (s *DropboxSource) Download(ctx context.Context, d *Downloader, url string, dest Dest) ([]*Result, error) {
+	return d.downloadResolved(ctx, s, url, dest, fileNameFromURL(url))
+}
@@ -0,0 +1,68 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDropboxResolve(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://www.dropbox.com/s/KEY/01화.zip?dl=0", want: "https://www.dropbox.com/s/KEY/01%ED%99%94.zip?dl=1"},
+		{url: "https://www.dropbox.com/s/KEY/01.zip", want: "https://www.dropbox.com/s/KEY/01.zip?dl=1"},
+		{url: "https://www.dropbox.com/s/KEY/01.zip?raw=1", want: "https://www.dropbox.com/s/KEY/01.zip?dl=1"},
+		{
+			url:  "https://www.dropbox.com/scl/fi/KEY/01.zip?rlkey=RLKEY&dl=0",
+			want: "https://www.dropbox.com/scl/fi/KEY/01.zip?dl=1&rlkey=RLKEY",
+		},
+		// 다운로드 주소는 바꾸지 않습니다.
+		{url: "https://dl.dropboxusercontent.com/s/KEY/01.zip", want: "https://dl.dropboxusercontent.com/s/KEY/01.zip"},
+	}
+
+	for _, tt := range tests {
+		req, err := (&DropboxSource{}).Resolve(context.Background(), http.DefaultClient, tt.url)
+		if err != nil {
+			t.Errorf("Resolve(%q) error = %v", tt.url, err)
+			continue
+		}
+		if got := req.URL.String(); got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestDropboxDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/scl/fi/KEY/01화.ass" || query.Get("rlkey") != "RLKEY" {
+			http.NotFound(w, r)
+			return
+		}
+		if query.Get("dl") != "1" {
+			// dl=1이 없으면 미리보기 페이지를 보냅니다.
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html><body>preview</body></html>"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte("[Script Info]\n"))
+	}))
+	defer server.Close()
+	d := newTestDownloader(t, server)
+	dest := newTestDest(t)
+
+	results, err := (&DropboxSource{}).Download(context.Background(), d, server.URL+"/scl/fi/KEY/01%ED%99%94.ass?rlkey=RLKEY&dl=0", dest)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Name != "01화.ass" {
+		t.Fatalf("Download() = %+v, want 01화.ass", results)
+	}
+	if data := readStored(t, dest, results[0].Path); string(data) != "[Script Info]\n" {
+		t.Errorf("content = %q, want the file instead of the preview page", data)
+	}
+}
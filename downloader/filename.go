@@ -0,0 +1,107 @@
+package downloader
+
+import (
+	"io"
+	"mime"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/transform"
+)
+
+// extFilenamePattern은 RFC 5987 형식의 filename* 파라미터입니다. (예: filename*=UTF-8'ko'%EC%9E%90%EB%A7%89.zip)
+var extFilenamePattern = regexp.MustCompile(`(?i)filename\*\s*=\s*([^']*)'[^']*'([^;]+)`)
+
+// filenamePattern은 filename 파라미터입니다.
+var filenamePattern = regexp.MustCompile(`(?i)filename\s*=\s*(?:"([^"]*)"|([^;]+))`)
+
+// fileNameFromContentDisposition은 Content-Disposition 헤더에서 파일 이름을 추출합니다.
+// RFC 5987의 filename*을 filename보다 우선하며, 퍼센트 인코딩과 CP949로 된 이름도 처리합니다.
+func fileNameFromContentDisposition(header string) string {
+	if header == "" {
+		return ""
+	}
+
+	// 표준을 따르는 헤더는 mime 패키지로 처리합니다. filename*이 있으면 filename으로 디코딩됩니다.
+	if _, params, err := mime.ParseMediaType(header); err == nil {
+		if name := params["filename"]; name != "" {
+			return cleanFileName(name)
+		}
+	}
+
+	// mime 패키지가 처리하지 못하는 charset(EUC-KR 등)의 filename*을 처리합니다.
+	if match := extFilenamePattern.FindStringSubmatch(header); match != nil {
+		if raw, err := url.PathUnescape(strings.TrimSpace(match[2])); err == nil {
+			charset := strings.ToLower(strings.TrimSpace(match[1]))
+			if charset == "euc-kr" || charset == "cp949" || charset == "ks_c_5601-1987" {
+				if decoded, err := decodeCP949(raw); err == nil {
+					return cleanFileName(decoded)
+				}
+			}
+			return cleanFileName(raw)
+		}
+	}
+
+	// 따옴표 안에 CP949 바이트가 그대로 들어 있는 헤더를 처리합니다.
+	if match := filenamePattern.FindStringSubmatch(header); match != nil {
+		name := match[1]
+		if name == "" {
+			name = strings.TrimSpace(match[2])
+		}
+		return cleanFileName(name)
+	}
+
+	return ""
+}
+
+// fileNameFromURL은 URL 경로의 마지막 부분을 파일 이름으로 반환합니다.
+func fileNameFromURL(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return cleanFileName(path.Base(parsedURL.Path))
+}
+
+// cleanFileName은 퍼센트 인코딩을 풀고, UTF-8이 아니면 CP949로 디코딩합니다.
+func cleanFileName(name string) string {
+	name = strings.TrimSpace(name)
+	if strings.Contains(name, "%") {
+		if unescaped, err := url.PathUnescape(name); err == nil {
+			name = unescaped
+		}
+	}
+	if decoded, err := decodeFileName(name); err == nil {
+		name = decoded
+	}
+	if name == "/" || name == "." {
+		return ""
+	}
+	return name
+}
+
+// decodeCP949는 CP949(EUC-KR) 문자열을 UTF-8로 디코딩합니다.
+func decodeCP949(s string) (string, error) {
+	reader := transform.NewReader(strings.NewReader(s), korean.EUCKR.NewDecoder())
+	buf := new(strings.Builder)
+	_, err := io.Copy(buf, reader)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// decodeFileName은 UTF-8이 아닌 파일 이름을 CP949로 디코딩합니다.
+func decodeFileName(encodedName string) (string, error) {
+	// 먼저 UTF-8로 시도
+	if utf8.ValidString(encodedName) {
+		return encodedName, nil
+	}
+
+	// UTF-8이 아니면 CP949로 시도
+	return decodeCP949(encodedName)
+}
@@ -0,0 +1,90 @@
+package downloader
+
+import (
+	"net/url"
+	"testing"
+
+	"golang.org/x/text/encoding/korean"
+)
+
+// encodeCP949는 s를 CP949 바이트 문자열로 인코딩합니다.
+func encodeCP949(t *testing.T, s string) string {
+	t.Helper()
+	encoded, err := korean.EUCKR.NewEncoder().String(s)
+	if err != nil {
+		t.Fatalf("encode %q: %v", s, err)
+	}
+	return encoded
+}
+
+func TestFileNameFromContentDisposition(t *testing.T) {
+	cp949 := encodeCP949(t, "자막.zip")
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{name: "empty", header: "", want: ""},
+		{name: "no filename", header: "inline", want: ""},
+		{name: "quoted", header: `attachment; filename="subtitle 01.zip"`, want: "subtitle 01.zip"},
+		{name: "token", header: `attachment; filename=subtitle.zip`, want: "subtitle.zip"},
+		{name: "rfc 5987", header: `attachment; filename*=UTF-8''%EC%9E%90%EB%A7%89.zip`, want: "자막.zip"},
+		{name: "rfc 5987 wins", header: `attachment; filename="fallback.zip"; filename*=UTF-8''%EC%9E%90%EB%A7%89.zip`, want: "자막.zip"},
+		{name: "percent-encoded filename", header: `attachment; filename="%EC%9E%90%EB%A7%89.zip"`, want: "자막.zip"},
+		{name: "euc-kr filename*", header: `attachment; filename*=EUC-KR''` + url.PathEscape(cp949), want: "자막.zip"},
+		{name: "ks_c_5601-1987 filename*", header: `attachment; filename*=ks_c_5601-1987'ko'` + url.PathEscape(cp949), want: "자막.zip"},
+		{name: "raw cp949 bytes", header: `attachment; filename="` + cp949 + `"`, want: "자막.zip"},
+		{name: "percent-encoded cp949", header: `attachment; filename="` + url.PathEscape(cp949) + `"`, want: "자막.zip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fileNameFromContentDisposition(tt.header); got != tt.want {
+				t.Errorf("fileNameFromContentDisposition(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileNameFromURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://example.com/files/subtitle.zip", want: "subtitle.zip"},
+		{url: "https://example.com/files/subtitle.zip?dl=1", want: "subtitle.zip"},
+		{url: "https://example.com/files/%EC%9E%90%EB%A7%89.ass", want: "자막.ass"},
+		{url: "https://example.com/", want: ""},
+		{url: "https://example.com", want: ""},
+		{url: "://bad", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := fileNameFromURL(tt.url); got != tt.want {
+			t.Errorf("fileNameFromURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeFileName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "subtitle.zip", want: "subtitle.zip"},
+		{in: "자막.zip", want: "자막.zip"},
+		{in: encodeCP949(t, "한글 자막.ass"), want: "한글 자막.ass"},
+	}
+
+	for _, tt := range tests {
+		got, err := decodeFileName(tt.in)
+		if err != nil {
+			t.Errorf("decodeFileName(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("decodeFileName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
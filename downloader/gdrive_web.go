@@ -0,0 +1,159 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"google.golang.org/api/googleapi"
+)
+
+// maxGDriveConfirmSteps는 웹 다운로드에서 따라가는 확인 페이지의 최대 개수입니다.
+const maxGDriveConfirmSteps = 3
+
+// ErrGDriveWebQuotaExceeded는 구글 드라이브가 파일의 다운로드 횟수 제한을 알려줄 때의 에러입니다.
+var ErrGDriveWebQuotaExceeded = errors.New("google drive download quota exceeded")
+
+// quotaReasons는 Drive API가 할당량 초과를 알릴 때 사용하는 reason 값입니다.
+var quotaReasons = map[string]bool{
+	"dailyLimitExceeded":      true,
+	"quotaExceeded":           true,
+	"rateLimitExceeded":       true,
+	"userRateLimitExceeded":   true,
+	"downloadQuotaExceeded":   true,
+	"dailyLimitExceededUnreg": true,
+}
+
+// isQuotaError는 err가 Drive API의 할당량 초과 에러인지 확인합니다.
+// 파일 내용은 downloadRequest로 받으므로 에러의 reason 없이 403, 429 상태 코드로 판단합니다.
+func isQuotaError(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusForbidden || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.Code == http.StatusTooManyRequests {
+		return true
+	}
+	if apiErr.Code != http.StatusForbidden {
+		return false
+	}
+	for _, item := range apiErr.Errors {
+		if quotaReasons[item.Reason] {
+			return true
+		}
+	}
+	return false
+}
+
+// downloadGoogleDriveWeb는 API 키 없이 공개된 구글 드라이브 파일을 uc?export=download 로 다운로드합니다.
+// 용량이 큰 파일은 "바이러스 검사를 할 수 없습니다" 확인 페이지가 먼저 오므로
+// 페이지의 다운로드 폼, confirm 링크 또는 download_warning 쿠키로 다시 요청합니다.
+// 파일은 .part 파일에 받으므로 중단되면 다음 시도에서 Range 요청으로 이어서 받습니다.
+func (d *Downloader) downloadGoogleDriveWeb(ctx context.Context, fileID string, dest Dest) (*Result, error) {
+	query := url.Values{"export": {"download"}, "id": {fileID}}
+	sourceURL := d.GDriveWebBaseURL + "/uc?" + query.Encode()
+
+	p, err := d.openPart(sourceURL, dest, expectation{})
+	if err != nil {
+		return nil, err
+	}
+	if p.complete() {
+		return d.finishPart(p, dest, expectation{})
+	}
+
+	reqURL := sourceURL
+	for step := 0; step <= maxGDriveConfirmSteps; step++ {
+		result, nextURL, err := d.requestGoogleDriveWeb(ctx, p, sourceURL, reqURL, dest)
+		if err != nil || result != nil {
+			return result, err
+		}
+		log.Printf("Google Drive confirm: %s\n", nextURL)
+		reqURL = nextURL
+	}
+
+	return nil, fmt.Errorf("too many google drive confirmation pages for %s", fileID)
+}
+
+// requestGoogleDriveWeb는 reqURL을 요청하여 파일이면 p에 받은 결과를, 확인 페이지이면 다음에 요청할 URL을 반환합니다.
+func (d *Downloader) requestGoogleDriveWeb(ctx context.Context, p *part, sourceURL string, reqURL string, dest Dest) (*Result, string, error) {
+	req, err := newRequest(ctx, reqURL, "")
+	if err != nil {
+		return nil, "", err
+	}
+	p.setRange(req)
+
+	resp, err := d.HTTPClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode != http.StatusOK || mediaType != "text/html" {
+		result, err := d.savePart(p, sourceURL, resp, dest, "", expectation{})
+		return result, "", err
+	}
+
+	nextURL, err := googleDriveConfirmURL(resp.Request.URL, resp.Body, resp.Cookies())
+	return nil, nextURL, err
+}
+
+// googleDriveConfirmURL은 구글 드라이브 확인 페이지에서 파일을 받을 다음 URL을 찾습니다.
+func googleDriveConfirmURL(pageURL *url.URL, body io.Reader, cookies []*http.Cookie) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse google drive page: %w", err)
+	}
+
+	// 현재 확인 페이지: <form id="download-form" action="https://drive.usercontent.google.com/download">
+	if form := doc.Find("form#download-form").First(); form.Length() > 0 {
+		action, err := pageURL.Parse(form.AttrOr("action", ""))
+		if err != nil {
+			return "", fmt.Errorf("failed to parse google drive form action: %w", err)
+		}
+		query := action.Query()
+		form.Find("input[type=hidden]").Each(func(_ int, input *goquery.Selection) {
+			if name := input.AttrOr("name", ""); name != "" {
+				query.Set(name, input.AttrOr("value", ""))
+			}
+		})
+		action.RawQuery = query.Encode()
+		return action.String(), nil
+	}
+
+	// 이전 확인 페이지: <a id="uc-download-link" href="/uc?export=download&confirm=XXXX&id=ID">
+	if href, ok := doc.Find("a#uc-download-link, a[href*='confirm=']").First().Attr("href"); ok {
+		next, err := pageURL.Parse(href)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse google drive confirm link: %w", err)
+		}
+		return next.String(), nil
+	}
+
+	// 링크가 없으면 download_warning 쿠키의 값을 confirm 토큰으로 사용합니다.
+	for _, cookie := range cookies {
+		if strings.HasPrefix(cookie.Name, "download_warning") {
+			next := *pageURL
+			query := next.Query()
+			query.Set("confirm", cookie.Value)
+			next.RawQuery = query.Encode()
+			return next.String(), nil
+		}
+	}
+
+	if strings.Contains(doc.Text(), "Too many users have viewed or downloaded this file recently") {
+		return "", ErrGDriveWebQuotaExceeded
+	}
+	return "", fmt.Errorf("google drive returned an html page without a download link: %s", pageURL)
+}
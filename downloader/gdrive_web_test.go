@@ -0,0 +1,174 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/huketo/anisub-scraper/storage"
+)
+
+// gdriveFileContent는 테스트 서버가 confirm 토큰을 확인한 뒤 보내는 파일 내용입니다.
+const gdriveFileContent = "subtitle content"
+
+// newGDriveWebServer는 confirm 토큰이 없는 요청에 page를 보내는 구글 드라이브 웹 다운로드 서버를 생성합니다.
+// cookie가 비어 있지 않으면 확인 페이지와 함께 download_warning 쿠키를 보냅니다.
+func newGDriveWebServer(t *testing.T, page string, cookie string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("id") != "FILE_ID" {
+			http.NotFound(w, r)
+			return
+		}
+		if query.Get("confirm") == "t0ken" {
+			w.Header().Set("Content-Type", "application/zip")
+			w.Header().Set("Content-Disposition", `attachment; filename="subtitle.zip"`)
+			w.Write([]byte(gdriveFileContent))
+			return
+		}
+		if cookie != "" {
+			http.SetCookie(w, &http.Cookie{Name: "download_warning_13058876669334088843_FILE_ID", Value: cookie})
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestDownloader는 server를 구글 드라이브 웹 다운로드 주소로 사용하는 Downloader를 생성합니다.
+func newTestDownloader(t *testing.T, server *httptest.Server) *Downloader {
+	t.Helper()
+	d, err := NewDownloader(context.Background(), Config{
+		GDriveWebBaseURL: server.URL,
+		TempDir:          t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("NewDownloader() error = %v", err)
+	}
+	return d
+}
+
+// newTestDest는 임시 디렉토리의 로컬 저장소에 1/2/tester 경로로 저장하는 Dest를 생성합니다.
+func newTestDest(t *testing.T) Dest {
+	t.Helper()
+	local, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal() error = %v", err)
+	}
+	return Dest{Dir: "1/2/tester", Sink: local}
+}
+
+// readStored는 dest의 저장소에 logicalPath로 저장된 파일 내용을 읽습니다.
+func readStored(t *testing.T, dest Dest, logicalPath string) []byte {
+	t.Helper()
+	r, err := dest.Sink.(storage.Storage).Open(logicalPath)
+	if err != nil {
+		t.Fatalf("open %s: %v", logicalPath, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read %s: %v", logicalPath, err)
+	}
+	return data
+}
+
+func TestDownloadGoogleDriveWebConfirm(t *testing.T) {
+	tests := []struct {
+		name   string
+		page   string
+		cookie string
+	}{
+		{
+			name: "download form",
+			page: `<html><body>
+				<p>Google Drive can't scan this file for viruses.</p>
+				<form id="download-form" action="/download" method="get">
+					<input type="submit" value="Download anyway">
+					<input type="hidden" name="id" value="FILE_ID">
+					<input type="hidden" name="export" value="download">
+					<input type="hidden" name="confirm" value="t0ken">
+				</form></body></html>`,
+		},
+		{
+			name: "uc-download-link",
+			page: `<html><body><a id="uc-download-link" href="/uc?export=download&amp;confirm=t0ken&amp;id=FILE_ID">Download anyway</a></body></html>`,
+		},
+		{
+			name: "confirm link",
+			page: `<html><body><a href="/uc?export=download&amp;confirm=t0ken&amp;id=FILE_ID">Download anyway</a></body></html>`,
+		},
+		{
+			name:   "download_warning cookie",
+			page:   `<html><body><p>Google Drive can't scan this file for viruses.</p></body></html>`,
+			cookie: "t0ken",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newGDriveWebServer(t, tt.page, tt.cookie)
+			d := newTestDownloader(t, server)
+			dest := newTestDest(t)
+
+			result, err := d.downloadGoogleDriveWeb(context.Background(), "FILE_ID", dest)
+			if err != nil {
+				t.Fatalf("downloadGoogleDriveWeb() error = %v", err)
+			}
+			if result.Name != "subtitle.zip" {
+				t.Errorf("Name = %q, want %q", result.Name, "subtitle.zip")
+			}
+			if result.Size != int64(len(gdriveFileContent)) {
+				t.Errorf("Size = %d, want %d", result.Size, len(gdriveFileContent))
+			}
+			data := readStored(t, dest, result.Path)
+			if string(data) != gdriveFileContent {
+				t.Errorf("content = %q, want %q", data, gdriveFileContent)
+			}
+		})
+	}
+}
+
+func TestDownloadGoogleDriveWebQuotaExceeded(t *testing.T) {
+	server := newGDriveWebServer(t, `<html><body><p>Sorry, you can't view or download this file at this time.</p>
+		<p>Too many users have viewed or downloaded this file recently. Please try accessing the file again later.</p></body></html>`, "")
+	d := newTestDownloader(t, server)
+	dest := newTestDest(t)
+
+	_, err := d.downloadGoogleDriveWeb(context.Background(), "FILE_ID", dest)
+	if !errors.Is(err, ErrGDriveWebQuotaExceeded) {
+		t.Errorf("downloadGoogleDriveWeb() error = %v, want ErrGDriveWebQuotaExceeded", err)
+	}
+}
+
+func TestDownloadGoogleDriveWebWithoutLink(t *testing.T) {
+	server := newGDriveWebServer(t, `<html><body><p>Sign in</p></body></html>`, "")
+	d := newTestDownloader(t, server)
+	dest := newTestDest(t)
+
+	_, err := d.downloadGoogleDriveWeb(context.Background(), "FILE_ID", dest)
+	if err == nil || errors.Is(err, ErrGDriveWebQuotaExceeded) {
+		t.Errorf("downloadGoogleDriveWeb() error = %v, want missing link error", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "without a download link") {
+		t.Errorf("downloadGoogleDriveWeb() error = %v", err)
+	}
+}
+
+func TestDownloadGoogleDriveWebTooManyConfirmPages(t *testing.T) {
+	// confirm 토큰이 맞지 않으면 서버가 같은 확인 페이지를 계속 보냅니다.
+	server := newGDriveWebServer(t, `<html><body><a id="uc-download-link" href="/uc?export=download&amp;confirm=wrong&amp;id=FILE_ID">Download anyway</a></body></html>`, "")
+	d := newTestDownloader(t, server)
+	dest := newTestDest(t)
+
+	_, err := d.downloadGoogleDriveWeb(context.Background(), "FILE_ID", dest)
+	if err == nil || !strings.Contains(err.Error(), "too many google drive confirmation pages") {
+		t.Errorf("downloadGoogleDriveWeb() error = %v, want too many confirmation pages", err)
+	}
+}
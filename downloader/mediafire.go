@@ -0,0 +1,73 @@
+package downloader
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// MediaFireSource는 MediaFire 파일 페이지를 다운로드합니다.
+type MediaFireSource struct{}
+
+// Type은 MediaFireURL을 반환합니다.
+func (s *MediaFireSource) Type() DownloadURLType { return MediaFireURL }
+
+// Match는 url이 MediaFire 링크인지 판별합니다.
+// 링크 형태: https://www.mediafire.com/file/KEY/NAME/file
+// 링크 형태: https://download1234.mediafire.com/TOKEN/KEY/NAME
+func (s *MediaFireSource) Match(url string) bool {
+	host := hostOf(url)
+	return host == "mediafire.com" || strings.HasSuffix(host, ".mediafire.com")
+}
+
+// Resolve는 파일 페이지의 다운로드 버튼에서 실제 다운로드 URL을 찾습니다.
+func (s *MediaFireSource) Resolve(ctx context.Context, client *http.Client, url string) (*http.Request, error) {
+	if strings.HasPrefix(hostOf(url), "download") {
+		return newRequest(ctx, url, "")
+	}
+
+	req, err := newRequest(ctx, url, "")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mediafire page: %w", err)
+	}
+	button := doc.Find("a#downloadButton").First()
+	if button.Length() == 0 {
+		return nil, errors.New("mediafire download button not found")
+	}
+
+	// 다운로드 URL을 base64로 숨긴 페이지는 data-scrambled-url 속성을 사용합니다.
+	href := button.AttrOr("href", "")
+	if scrambled := button.AttrOr("data-scrambled-url", ""); scrambled != "" {
+		if decoded, err := base64.StdEncoding.DecodeString(scrambled); err == nil {
+			href = string(decoded)
+		}
+	}
+	downloadURL, err := resp.Request.URL.Parse(href)
+	if err != nil || !strings.HasPrefix(downloadURL.Scheme, "http") {
+		return nil, fmt.Errorf("invalid mediafire download url: %q", href)
+	}
+	return newRequest(ctx, downloadURL.String(), url)
+}
+
+// Download는 파일을 받습니다.
+func (s *MediaFireSource) Download(ctx context.Context, d *Downloader, url string, dest Dest) ([]*Result, error) {
+	return d.downloadResolved(ctx, s, url, dest, "")
+}
@@ -0,0 +1,63 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMediaFireResumeWithNewDownloadURL(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	modTime := time.Date(2023, 10, 22, 4, 24, 0, 0, time.UTC)
+
+	var pages, downloads int32
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/file/") {
+			// 파일 페이지는 요청할 때마다 다른 다운로드 URL을 알려줍니다.
+			n := atomic.AddInt32(&pages, 1)
+			fmt.Fprintf(w, `<html><body><a id="downloadButton" href="/dl/token%d/KEY/subtitle.zip">Download</a></body></html>`, n)
+			return
+		}
+
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", `"v1"`)
+		if atomic.AddInt32(&downloads, 1) == 1 {
+			// 처음 요청은 절반만 보내고 연결을 끊습니다.
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(content[:len(content)/2]))
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "subtitle.zip", modTime, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	d := newTestDownloader(t, server)
+	dest := newTestDest(t)
+	source := &MediaFireSource{}
+	pageURL := server.URL + "/file/KEY/subtitle.zip/file"
+
+	if _, err := d.downloadResolved(context.Background(), source, pageURL, dest, ""); err == nil {
+		t.Fatal("first downloadResolved() error = nil, want interrupted download")
+	}
+	results, err := d.downloadResolved(context.Background(), source, pageURL, dest, "")
+	if err != nil {
+		t.Fatalf("second downloadResolved() error = %v", err)
+	}
+
+	if want := []string{"", fmt.Sprintf("bytes=%d-", len(content)/2)}; len(ranges) != 2 || ranges[0] != want[0] || ranges[1] != want[1] {
+		t.Errorf("Range headers = %q, want %q", ranges, want)
+	}
+	data := readStored(t, dest, results[0].Path)
+	if string(data) != content {
+		t.Errorf("content length = %d, want %d", len(data), len(content))
+	}
+}
@@ -0,0 +1,343 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultMegaAPIURL은 Mega API 주소입니다.
+const DefaultMegaAPIURL = "https://g.api.mega.co.nz"
+
+// MegaSource는 Mega 파일 링크를 다운로드합니다.
+// Mega의 파일은 링크의 키로 암호화되어 있으므로 받으면서 AES-CTR로 복호화하고,
+// 복호화한 내용의 청크 MAC을 링크의 키에 있는 MAC과 비교하여 검증합니다.
+// 받으면서 복호화한 내용을 임시 파일에 바로 쓰므로 .part 파일로 이어 받지 않으며,
+// 중단되면 다음 시도에서 처음부터 다시 받습니다.
+type MegaSource struct {
+	APIURL string // Mega API 주소, 비어 있으면 DefaultMegaAPIURL을 사용합니다.
+}
+
+// megaFile은 Mega API가 알려준 파일 정보입니다.
+type megaFile struct {
+	url  string // 암호화된 파일의 다운로드 URL
+	name string // 복호화한 파일 이름
+	size int64  // 파일 크기
+	key  []byte // AES 키
+	iv   []byte // AES-CTR 초기 카운터
+	mac  []byte // 파일 내용의 MAC(8바이트)
+}
+
+// Type은 MegaURL을 반환합니다.
+func (s *MegaSource) Type() DownloadURLType { return MegaURL }
+
+// Match는 url이 Mega 파일 링크인지 판별합니다.
+func (s *MegaSource) Match(url string) bool {
+	switch hostOf(url) {
+	case "mega.nz", "mega.co.nz", "www.mega.nz", "www.mega.co.nz":
+		_, _, err := parseMegaURL(url)
+		return err == nil
+	}
+	return false
+}
+
+// Resolve는 Mega API로 암호화된 파일의 다운로드 요청을 만듭니다.
+// 응답은 암호화되어 있으므로 파일을 받으려면 Download를 사용해야 합니다.
+func (s *MegaSource) Resolve(ctx context.Context, client *http.Client, url string) (*http.Request, error) {
+	file, err := s.resolve(ctx, client, url)
+	if err != nil {
+		return nil, err
+	}
+	return newRequest(ctx, file.url, "")
+}
+
+// Download는 암호화된 파일을 받아 복호화하여 저장합니다. .part 파일을 사용하지 않으므로 이어 받지 않습니다.
+func (s *MegaSource) Download(ctx context.Context, d *Downloader, url string, dest Dest) ([]*Result, error) {
+	file, err := s.resolve(ctx, d.HTTPClient, url)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("File Name: %s\n", file.name)
+
+	req, err := newRequest(ctx, file.url, "")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: file.url, StatusCode: resp.StatusCode}
+	}
+
+	block, err := aes.NewCipher(file.key)
+	if err != nil {
+		return nil, err
+	}
+	plain := &megaMACReader{
+		r:    &cipher.StreamReader{S: cipher.NewCTR(block, file.iv), R: resp.Body},
+		mac:  newMegaMAC(block, file.iv[:8]),
+		want: file.mac,
+	}
+
+	result, err := d.saveFile(dest, file.name, plain, expectation{size: file.size})
+	if err != nil {
+		return nil, err
+	}
+	return []*Result{result}, nil
+}
+
+// parseMegaURL은 Mega 파일 링크에서 파일 핸들과 키를 찾습니다.
+// 링크 형태: https://mega.nz/file/HANDLE#KEY
+// 링크 형태: https://mega.nz/#!HANDLE!KEY
+func parseMegaURL(rawURL string) (string, string, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	switch {
+	case strings.HasPrefix(parsedURL.Path, "/file/"):
+		handle := strings.TrimPrefix(parsedURL.Path, "/file/")
+		if handle != "" && !strings.Contains(handle, "/") && parsedURL.Fragment != "" {
+			return handle, parsedURL.Fragment, nil
+		}
+	case strings.HasPrefix(parsedURL.Fragment, "!"):
+		parts := strings.Split(parsedURL.Fragment, "!")
+		if len(parts) == 3 && parts[1] != "" && parts[2] != "" {
+			return parts[1], parts[2], nil
+		}
+	}
+	return "", "", errors.New("invalid Mega file URL format")
+}
+
+// resolve는 Mega API로 파일의 다운로드 URL과 이름을 가져옵니다.
+func (s *MegaSource) resolve(ctx context.Context, client *http.Client, rawURL string) (*megaFile, error) {
+	handle, encodedKey, err := parseMegaURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	rawKey, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encodedKey, "="))
+	if err != nil || len(rawKey) != 32 {
+		return nil, errors.New("invalid Mega file key")
+	}
+
+	// 파일 키는 앞뒤 16바이트를 XOR한 AES 키, IV(8바이트), 파일 내용의 MAC(8바이트)으로 이루어집니다.
+	// AES-CTR의 초기 카운터는 IV 뒤에 0인 카운터(8바이트)를 붙인 값입니다.
+	file := &megaFile{key: make([]byte, 16), iv: make([]byte, 16), mac: rawKey[24:32]}
+	for i := 0; i < 16; i++ {
+		file.key[i] = rawKey[i] ^ rawKey[i+16]
+	}
+	copy(file.iv, rawKey[16:24])
+
+	apiURL := s.APIURL
+	if apiURL == "" {
+		apiURL = DefaultMegaAPIURL
+	}
+	body, err := json.Marshal([]map[string]interface{}{{"a": "g", "g": 1, "ssl": 2, "p": handle}})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(apiURL, "/")+"/cs", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{URL: req.URL.String(), StatusCode: resp.StatusCode}
+	}
+
+	// 성공하면 [{"g": URL, "s": 크기, "at": 암호화된 속성}], 실패하면 음수 에러 코드를 반환합니다.
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode mega response: %w", err)
+	}
+	var results []json.RawMessage
+	if err := json.Unmarshal(raw, &results); err != nil || len(results) == 0 {
+		return nil, fmt.Errorf("mega api error: %s", raw)
+	}
+	var info struct {
+		URL  string `json:"g"`
+		Size int64  `json:"s"`
+		Attr string `json:"at"`
+	}
+	if err := json.Unmarshal(results[0], &info); err != nil || info.URL == "" {
+		return nil, fmt.Errorf("mega api error: %s", results[0])
+	}
+	file.url = info.URL
+	file.size = info.Size
+
+	file.name, err = decryptMegaAttr(info.Attr, file.key)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// decryptMegaAttr는 AES-CBC로 암호화된 파일 속성에서 파일 이름을 꺼냅니다.
+// 복호화한 속성은 MEGA{"n":"파일 이름"} 형태이며 뒤에 0 바이트가 붙어 있습니다.
+func decryptMegaAttr(encoded string, key []byte) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil || len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return "", errors.New("invalid Mega file attributes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	cipher.NewCBCDecrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(data, data)
+
+	data = bytes.TrimRight(data, "\x00")
+	if !bytes.HasPrefix(data, []byte("MEGA")) {
+		return "", errors.New("failed to decrypt Mega file attributes")
+	}
+	var attr struct {
+		Name string `json:"n"`
+	}
+	if err := json.Unmarshal(data[len("MEGA"):], &attr); err != nil {
+		return "", fmt.Errorf("failed to decode Mega file attributes: %w", err)
+	}
+	return attr.Name, nil
+}
+
+// errMegaMACMismatch는 복호화한 파일 내용의 MAC이 링크의 키에 있는 MAC과 다를 때의 에러입니다.
+var errMegaMACMismatch = errors.New("mega file MAC mismatch")
+
+// megaMACReader는 r에서 읽은 내용의 MAC을 계산하고, 끝까지 읽었을 때 MAC이 want와 다르면 에러를 반환합니다.
+type megaMACReader struct {
+	r       io.Reader
+	mac     *megaMAC
+	want    []byte
+	checked bool
+}
+
+func (m *megaMACReader) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	m.mac.Write(p[:n])
+	if err == io.EOF && !m.checked {
+		m.checked = true
+		if !bytes.Equal(m.mac.Sum(), m.want) {
+			return n, errMegaMACMismatch
+		}
+	}
+	return n, err
+}
+
+const (
+	megaChunkSize    = 128 << 10 // 첫 청크의 크기, 다음 청크는 이 크기만큼 커집니다.
+	megaMaxChunkSize = 1 << 20   // 청크의 최대 크기
+)
+
+// megaMAC은 Mega 파일 내용의 MAC을 계산합니다.
+// 파일을 128KiB, 256KiB, ..., 1MiB 크기의 청크와 그 뒤의 1MiB 청크로 나누어
+// 청크마다 IV를 두 번 이은 값으로 시작하는 AES CBC-MAC을 계산하고, 청크 MAC들을 다시 CBC-MAC으로 합칩니다.
+type megaMAC struct {
+	block      cipher.Block
+	iv         []byte // 청크 MAC의 초기값
+	chunkMAC   []byte
+	fileMAC    []byte
+	buf        []byte // 16바이트가 되지 않아 아직 처리하지 않은 내용
+	chunkIndex int    // 현재 청크의 번호
+	chunkLeft  int    // 현재 청크에 남은 바이트 수
+	chunkUsed  bool   // 현재 청크에 내용이 있는지 여부
+}
+
+// newMegaMAC은 AES 키 block과 파일 IV(8바이트)로 megaMAC을 생성합니다.
+func newMegaMAC(block cipher.Block, iv []byte) *megaMAC {
+	m := &megaMAC{
+		block:   block,
+		iv:      append(append([]byte{}, iv...), iv...),
+		fileMAC: make([]byte, aes.BlockSize),
+		buf:     make([]byte, 0, aes.BlockSize),
+	}
+	m.startChunk()
+	return m
+}
+
+// startChunk는 다음 청크의 MAC 계산을 시작합니다.
+func (m *megaMAC) startChunk() {
+	m.chunkIndex++
+	m.chunkLeft = m.chunkIndex * megaChunkSize
+	if m.chunkLeft > megaMaxChunkSize {
+		m.chunkLeft = megaMaxChunkSize
+	}
+	m.chunkMAC = append(m.chunkMAC[:0], m.iv...)
+	m.chunkUsed = false
+}
+
+// Write는 파일 내용 p를 MAC에 더합니다.
+func (m *megaMAC) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		size := len(p)
+		if size > m.chunkLeft {
+			size = m.chunkLeft
+		}
+		for _, b := range p[:size] {
+			m.buf = append(m.buf, b)
+			if len(m.buf) == aes.BlockSize {
+				m.addBlock()
+			}
+		}
+		m.chunkUsed = true
+		m.chunkLeft -= size
+		p = p[size:]
+		if m.chunkLeft == 0 {
+			m.finishChunk()
+			m.startChunk()
+		}
+	}
+	return n, nil
+}
+
+// addBlock은 buf의 16바이트를 청크 MAC에 더합니다.
+func (m *megaMAC) addBlock() {
+	for i := range m.buf {
+		m.chunkMAC[i] ^= m.buf[i]
+	}
+	m.block.Encrypt(m.chunkMAC, m.chunkMAC)
+	m.buf = m.buf[:0]
+}
+
+// finishChunk는 마지막 블록을 0으로 채워 청크 MAC을 끝내고 파일 MAC에 더합니다.
+func (m *megaMAC) finishChunk() {
+	if len(m.buf) > 0 {
+		m.buf = append(m.buf, make([]byte, aes.BlockSize-len(m.buf))...)
+		m.addBlock()
+	}
+	for i := range m.fileMAC {
+		m.fileMAC[i] ^= m.chunkMAC[i]
+	}
+	m.block.Encrypt(m.fileMAC, m.fileMAC)
+}
+
+// Sum은 마지막 청크를 끝내고 파일 MAC의 4바이트 단어들을 XOR로 접은 8바이트 MAC을 반환합니다.
+// Sum을 호출한 뒤에는 Write를 호출하면 안 됩니다.
+func (m *megaMAC) Sum() []byte {
+	if m.chunkUsed {
+		m.finishChunk()
+		m.chunkUsed = false
+	}
+	sum := make([]byte, 8)
+	for i := 0; i < 4; i++ {
+		sum[i] = m.fileMAC[i] ^ m.fileMAC[i+4]
+		sum[i+4] = m.fileMAC[i+8] ^ m.fileMAC[i+12]
+	}
+	return sum
+}
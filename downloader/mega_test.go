@@ -0,0 +1,224 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// megaTestKey는 테스트 파일의 AES 키이고, megaTestIV는 파일 IV입니다.
+var (
+	megaTestKey = []byte("0123456789abcdef")
+	megaTestIV  = []byte("ivivivIV")
+)
+
+// megaTestMAC은 content를 청크로 나누어 Mega 파일 MAC을 계산합니다.
+func megaTestMAC(t *testing.T, content []byte) []byte {
+	t.Helper()
+	block, err := aes.NewCipher(megaTestKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileMAC := make([]byte, aes.BlockSize)
+	for start, i := 0, 1; start < len(content); i++ {
+		end := start + i*128<<10
+		if i > 8 {
+			end = start + 1<<20
+		}
+		if end > len(content) {
+			end = len(content)
+		}
+		chunkMAC := append(append([]byte{}, megaTestIV...), megaTestIV...)
+		for off := start; off < end; off += aes.BlockSize {
+			data := make([]byte, aes.BlockSize)
+			copy(data, content[off:end])
+			for j := range chunkMAC {
+				chunkMAC[j] ^= data[j]
+			}
+			block.Encrypt(chunkMAC, chunkMAC)
+		}
+		for j := range fileMAC {
+			fileMAC[j] ^= chunkMAC[j]
+		}
+		block.Encrypt(fileMAC, fileMAC)
+		start = end
+	}
+	return []byte{
+		fileMAC[0] ^ fileMAC[4], fileMAC[1] ^ fileMAC[5], fileMAC[2] ^ fileMAC[6], fileMAC[3] ^ fileMAC[7],
+		fileMAC[8] ^ fileMAC[12], fileMAC[9] ^ fileMAC[13], fileMAC[10] ^ fileMAC[14], fileMAC[11] ^ fileMAC[15],
+	}
+}
+
+// megaTestLink는 HANDLE 파일의 Mega 링크를 만듭니다. 링크의 키는 AES 키와 IV, mac으로 이루어집니다.
+func megaTestLink(mac []byte) string {
+	rawKey := make([]byte, 32)
+	copy(rawKey[16:], megaTestIV)
+	copy(rawKey[24:], mac)
+	for i := 0; i < 16; i++ {
+		rawKey[i] = megaTestKey[i] ^ rawKey[i+16]
+	}
+	return "https://mega.nz/file/HANDLE#" + base64.RawURLEncoding.EncodeToString(rawKey)
+}
+
+// megaEncrypt는 content를 AES-CTR로 암호화합니다.
+func megaEncrypt(t *testing.T, content []byte) []byte {
+	t.Helper()
+	block, err := aes.NewCipher(megaTestKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv := make([]byte, aes.BlockSize)
+	copy(iv, megaTestIV)
+	encrypted := make([]byte, len(content))
+	cipher.NewCTR(block, iv).XORKeyStream(encrypted, content)
+	return encrypted
+}
+
+// megaAttr는 파일 이름 속성을 AES-CBC로 암호화합니다.
+func megaAttr(t *testing.T, name string) string {
+	t.Helper()
+	block, err := aes.NewCipher(megaTestKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attr := []byte(fmt.Sprintf(`MEGA{"n":%q}`, name))
+	attr = append(attr, make([]byte, aes.BlockSize-len(attr)%aes.BlockSize)...)
+	cipher.NewCBCEncrypter(block, make([]byte, aes.BlockSize)).CryptBlocks(attr, attr)
+	return base64.RawURLEncoding.EncodeToString(attr)
+}
+
+// newMegaServer는 HANDLE 파일의 정보를 알려주는 Mega API와 암호화된 파일 encrypted를 보내는 서버를 생성합니다.
+func newMegaServer(t *testing.T, encrypted []byte, attr string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cs":
+			var commands []struct {
+				A string `json:"a"`
+				P string `json:"p"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&commands); err != nil || len(commands) != 1 || commands[0].A != "g" {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			if commands[0].P != "HANDLE" {
+				// 파일이 없으면 ENOENT(-9)를 반환합니다.
+				fmt.Fprint(w, `-9`)
+				return
+			}
+			fmt.Fprintf(w, `[{"g":%q,"s":%d,"at":%q}]`, server.URL+"/dl/HANDLE", len(encrypted), attr)
+		case "/dl/HANDLE":
+			w.Write(encrypted)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestMegaDownload(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+	}{
+		{name: "small", size: 21},
+		{name: "first chunk", size: 128 << 10},
+		// 128KiB, 256KiB, 384KiB 청크를 지나고 마지막 블록이 16바이트가 되지 않습니다.
+		{name: "multiple chunks", size: (128+256+384)<<10 + 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := []byte(strings.Repeat("[Script Info]\n", tt.size/14+1)[:tt.size])
+			server := newMegaServer(t, megaEncrypt(t, content), megaAttr(t, "01화.zip"))
+			d := newTestDownloader(t, server)
+			dest := newTestDest(t)
+
+			results, err := (&MegaSource{APIURL: server.URL}).Download(context.Background(), d, megaTestLink(megaTestMAC(t, content)), dest)
+			if err != nil {
+				t.Fatalf("Download() error = %v", err)
+			}
+			if len(results) != 1 || results[0].Name != "01화.zip" || results[0].Size != int64(len(content)) {
+				t.Fatalf("Download() = %+v, want 01화.zip of %d bytes", results, len(content))
+			}
+			if data := readStored(t, dest, results[0].Path); !bytes.Equal(data, content) {
+				t.Errorf("content length = %d, want the decrypted content of %d bytes", len(data), len(content))
+			}
+		})
+	}
+}
+
+func TestMegaDownloadMACMismatch(t *testing.T) {
+	content := []byte(strings.Repeat("[Script Info]\n", 20000))
+	encrypted := megaEncrypt(t, content)
+	// 두 번째 청크의 한 바이트가 바뀐 파일입니다.
+	encrypted[200<<10] ^= 1
+	server := newMegaServer(t, encrypted, megaAttr(t, "01화.zip"))
+	d := newTestDownloader(t, server)
+
+	_, err := (&MegaSource{APIURL: server.URL}).Download(context.Background(), d, megaTestLink(megaTestMAC(t, content)), newTestDest(t))
+	if !errors.Is(err, errMegaMACMismatch) {
+		t.Errorf("Download() error = %v, want errMegaMACMismatch", err)
+	}
+}
+
+func TestMegaResolveErrors(t *testing.T) {
+	server := newMegaServer(t, nil, megaAttr(t, "01화.zip"))
+	validKey := strings.SplitN(megaTestLink(make([]byte, 8)), "#", 2)[1]
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{name: "short key", url: "https://mega.nz/file/HANDLE#" + validKey[:20]},
+		{name: "invalid key", url: "https://mega.nz/file/HANDLE#" + strings.Repeat("*", 43)},
+		{name: "not found", url: "https://mega.nz/file/MISSING#" + validKey},
+	}
+
+	for _, tt := range tests {
+		if _, err := (&MegaSource{APIURL: server.URL}).Resolve(context.Background(), server.Client(), tt.url); err == nil {
+			t.Errorf("%s: Resolve() error = nil, want error", tt.name)
+		}
+	}
+}
+
+func TestParseMegaURL(t *testing.T) {
+	tests := []struct {
+		url        string
+		wantHandle string
+		wantKey    string
+		wantErr    bool
+	}{
+		{url: "https://mega.nz/file/HANDLE#KEY", wantHandle: "HANDLE", wantKey: "KEY"},
+		{url: "https://mega.nz/#!HANDLE!KEY", wantHandle: "HANDLE", wantKey: "KEY"},
+		{url: "https://mega.co.nz/#!HANDLE!KEY", wantHandle: "HANDLE", wantKey: "KEY"},
+		{url: "https://mega.nz/file/HANDLE", wantErr: true},
+		{url: "https://mega.nz/folder/HANDLE#KEY", wantErr: true},
+		{url: "https://mega.nz/file/HANDLE/extra#KEY", wantErr: true},
+		{url: "https://mega.nz/#!HANDLE", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		handle, key, err := parseMegaURL(tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseMegaURL(%q) error = %v, wantErr %t", tt.url, err, tt.wantErr)
+			continue
+		}
+		if handle != tt.wantHandle || key != tt.wantKey {
+			t.Errorf("parseMegaURL(%q) = %q, %q, want %q, %q", tt.url, handle, key, tt.wantHandle, tt.wantKey)
+		}
+		if matched := (&MegaSource{}).Match(tt.url); matched == tt.wantErr {
+			t.Errorf("Match(%q) = %t, want %t", tt.url, matched, !tt.wantErr)
+		}
+	}
+}
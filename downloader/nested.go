@@ -0,0 +1,138 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// unpackNested는 entries 중 압축 파일을 풀어서 그 안의 파일로 바꿉니다.
+// 안쪽 압축 파일은 unpackPath 안에서 압축 파일 이름에서 확장자를 뺀 디렉토리에 풀고, 압축 파일은 지웁니다.
+// depth는 entries 중 압축 파일을 풀 때의 깊이이며, MaxDepth를 넘으면 압축 파일을 그대로 둡니다.
+// 안쪽 압축 파일이 암호가 걸렸거나 손상되어 풀 수 없으면 압축 파일을 그대로 두고, 안전하지 않으면 에러를 반환합니다.
+func (u *UnpackerImpl) unpackNested(unpackPath string, entries []ManifestEntry, lineage []string, depth int, q *quota) ([]ManifestEntry, error) {
+	for i := range entries {
+		entries[i].Lineage = lineage
+	}
+	if depth > u.config.MaxDepth {
+		return entries, nil
+	}
+
+	var result []ManifestEntry
+	consumed := make(map[string]bool) // 첫 볼륨과 함께 풀린 분할 압축 파일의 볼륨
+	for _, entry := range entries {
+		if consumed[entry.Path] {
+			continue
+		}
+		archivePath := filepath.Join(unpackPath, filepath.FromSlash(entry.Path))
+		packType, err := getPackType(archivePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect pack type of %s: %w", entry.Path, err)
+		}
+		if !packType.IsArchive() {
+			result = append(result, entry)
+			continue
+		}
+
+		nested, volumes, err := u.unpackNestedArchive(unpackPath, entry.Path, packType, q)
+		var unsafeErr *UnsafeArchiveError
+		if errors.As(err, &unsafeErr) {
+			return nil, err
+		}
+		if errors.Is(err, ErrNotFirstVolume) {
+			// 첫 볼륨을 풀 때 함께 지워집니다. 첫 볼륨이 없으면 그대로 둡니다.
+			result = append(result, entry)
+			continue
+		}
+		if err != nil {
+			log.Printf("Skip nested archive %s: %v\n", entry.Path, err)
+			result = append(result, entry)
+			continue
+		}
+		for _, volume := range volumes {
+			consumed[volume] = true
+		}
+
+		nestedLineage := make([]string, len(lineage), len(lineage)+1)
+		copy(nestedLineage, lineage)
+		nestedLineage = append(nestedLineage, entry.Path)
+		nested, err = u.unpackNested(unpackPath, nested, nestedLineage, depth+1, q)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, nested...)
+	}
+
+	// 첫 볼륨보다 먼저 나온 볼륨을 지웁니다.
+	filtered := result[:0]
+	for _, entry := range result {
+		if !consumed[entry.Path] {
+			filtered = append(filtered, entry)
+		}
+	}
+	return uniqueEntries(filtered), nil
+}
+
+// unpackNestedArchive는 unpackPath 안의 압축 파일 rel을 rel에서 확장자를 뺀 디렉토리에 풉니다.
+// 압축 파일(분할 압축 파일이면 모든 볼륨)은 지우고, 풀린 파일과 지운 볼륨의 상대 경로를 반환합니다.
+// 풀 수 없으면 unpackPath를 바꾸지 않습니다.
+func (u *UnpackerImpl) unpackNestedArchive(unpackPath string, rel string, packType PackType, q *quota) ([]ManifestEntry, []string, error) {
+	archivePath := filepath.Join(unpackPath, filepath.FromSlash(rel))
+
+	// 압축 파일과 이름이 같은 디렉토리에 풀 수 있도록 임시 디렉토리에 먼저 풉니다.
+	tmpDir, err := os.MkdirTemp(filepath.Dir(archivePath), ".unpack-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	m := q.mark()
+	// 에러 메시지에 안쪽 압축 파일의 경로를 남깁니다. (예: 01화.zip/fonts.7z)
+	outer := q.archive
+	q.archive = outer + "/" + rel
+	entries, err := u.unpack(archivePath, packType, tmpDir, q)
+	q.archive = outer
+	if err != nil {
+		var unsafeErr *UnsafeArchiveError
+		if !errors.As(err, &unsafeErr) {
+			// 그대로 두는 압축 파일의 내용은 제한에 포함하지 않습니다.
+			q.restore(m)
+		}
+		return nil, nil, err
+	}
+
+	volumes := []string{rel}
+	if packType == Rar {
+		for volume := NextRarVolume(rel); ; volume = NextRarVolume(volume) {
+			if _, err := os.Stat(filepath.Join(unpackPath, filepath.FromSlash(volume))); err != nil {
+				break
+			}
+			volumes = append(volumes, volume)
+		}
+	}
+	for _, volume := range volumes {
+		if err := os.Remove(filepath.Join(unpackPath, filepath.FromSlash(volume))); err != nil {
+			return nil, nil, fmt.Errorf("failed to remove nested archive: %w", err)
+		}
+	}
+
+	// 확장자가 없는 압축 파일은 지운 압축 파일과 같은 이름의 디렉토리에 풉니다.
+	dir := strings.TrimSuffix(rel, path.Ext(rel))
+	entries = uniqueEntries(entries)
+	for i := range entries {
+		dst := path.Join(dir, entries[i].Path)
+		dstPath := filepath.Join(unpackPath, filepath.FromSlash(dst))
+		if err := os.MkdirAll(filepath.Dir(dstPath), os.ModePerm); err != nil {
+			return nil, nil, fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.Rename(filepath.Join(tmpDir, filepath.FromSlash(entries[i].Path)), dstPath); err != nil {
+			return nil, nil, fmt.Errorf("failed to move %s: %w", dst, err)
+		}
+		entries[i].Path = dst
+	}
+	return entries, volumes, nil
+}
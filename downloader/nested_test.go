@@ -0,0 +1,194 @@
+package downloader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// buildNestedZip은 01화.ass와 fonts.zip이 들어 있고, fonts.zip 안에 다시 deep.zip이 들어 있는 zip 파일을 만듭니다.
+func buildNestedZip(t *testing.T) []byte {
+	t.Helper()
+	deep := buildZip(t, archiveFile{name: "b.ass", body: "deep subtitle"})
+	fonts := buildZip(t,
+		archiveFile{name: "a.ttf", body: "font"},
+		archiveFile{name: "deep.zip", body: string(deep)},
+	)
+	return buildZip(t,
+		archiveFile{name: "01화.ass", body: "subtitle"},
+		archiveFile{name: "fonts.zip", body: string(fonts)},
+	)
+}
+
+func TestUnpackNestedLineage(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxDepth int
+		want     map[string][]string // 풀린 파일의 경로별 Lineage
+	}{
+		{
+			name:     "all depths",
+			maxDepth: 3,
+			want: map[string][]string{
+				"01화.ass":          {"outer.zip"},
+				"fonts/a.ttf":      {"outer.zip", "fonts.zip"},
+				"fonts/deep/b.ass": {"outer.zip", "fonts.zip", "fonts/deep.zip"},
+			},
+		},
+		{
+			// 깊이 제한을 넘는 안쪽 압축 파일은 풀지 않고 그대로 둡니다.
+			name:     "depth limit",
+			maxDepth: 2,
+			want: map[string][]string{
+				"01화.ass":        {"outer.zip"},
+				"fonts/a.ttf":    {"outer.zip", "fonts.zip"},
+				"fonts/deep.zip": {"outer.zip", "fonts.zip"},
+			},
+		},
+		{
+			name:     "outer only",
+			maxDepth: 1,
+			want: map[string][]string{
+				"01화.ass":   {"outer.zip"},
+				"fonts.zip": {"outer.zip"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := writeArchive(t, dir, "outer.zip", buildNestedZip(t))
+			unpackPath := filepath.Join(dir, "files")
+
+			manifest, err := NewUnpacker(UnpackerConfig{MaxDepth: tt.maxDepth}).Unpack(archivePath, unpackPath)
+			if err != nil {
+				t.Fatalf("Unpack() error = %v", err)
+			}
+			got := make(map[string][]string)
+			for _, entry := range manifest.Entries {
+				got[entry.Path] = entry.Lineage
+				if _, err := os.Stat(filepath.Join(unpackPath, filepath.FromSlash(entry.Path))); err != nil {
+					t.Errorf("unpacked file %s: %v", entry.Path, err)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Entries = %v, want %v", got, tt.want)
+			}
+
+			// 푼 안쪽 압축 파일은 지웁니다.
+			for _, rel := range []string{"fonts.zip", "fonts/deep.zip"} {
+				if _, ok := tt.want[rel]; ok {
+					continue
+				}
+				if _, err := os.Stat(filepath.Join(unpackPath, filepath.FromSlash(rel))); !os.IsNotExist(err) {
+					t.Errorf("nested archive %s was not removed: %v", rel, err)
+				}
+			}
+		})
+	}
+}
+
+func TestUnpackNestedUnsafe(t *testing.T) {
+	subtitle := strings.Repeat("Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,안녕하세요\n", 10)
+	inner := buildZip(t, archiveFile{name: "02화.ass", body: subtitle})
+
+	tests := []struct {
+		name       string
+		data       []byte
+		config     UnpackerConfig
+		wantReason string
+	}{
+		{
+			// 바깥 압축 파일만으로는 제한을 넘지 않지만 안쪽 압축 파일까지 합치면 넘습니다.
+			name: "cumulative total size",
+			data: buildZip(t,
+				archiveFile{name: "01화.ass", body: subtitle},
+				archiveFile{name: "inner.zip", body: string(inner)},
+			),
+			config:     UnpackerConfig{MaxTotalSize: int64(len(subtitle)+len(inner)) + 100},
+			wantReason: "uncompressed size exceeds",
+		},
+		{
+			name: "cumulative entries",
+			data: buildZip(t,
+				archiveFile{name: "01화.ass", body: subtitle},
+				archiveFile{name: "inner.zip", body: string(inner)},
+			),
+			config:     UnpackerConfig{MaxEntries: 2},
+			wantReason: "more than 2 entries",
+		},
+		{
+			name: "nested zip slip",
+			data: buildZip(t,
+				archiveFile{name: "01화.ass", body: subtitle},
+				archiveFile{name: "inner.zip", body: string(buildZip(t, archiveFile{name: "../../evil.ass", body: "evil"}))},
+			),
+			wantReason: "path traversal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := writeArchive(t, dir, "outer.zip", tt.data)
+			unpackPath := filepath.Join(dir, "files")
+
+			_, err := NewUnpacker(tt.config).Unpack(archivePath, unpackPath)
+			var unsafeErr *UnsafeArchiveError
+			if !errors.As(err, &unsafeErr) || !strings.Contains(unsafeErr.Reason, tt.wantReason) {
+				t.Fatalf("Unpack() error = %v, want %q", err, tt.wantReason)
+			}
+			// 에러에 안쪽 압축 파일의 경로를 남깁니다.
+			if unsafeErr.Archive != "outer.zip/inner.zip" {
+				t.Errorf("Archive = %q, want %q", unsafeErr.Archive, "outer.zip/inner.zip")
+			}
+			for _, p := range []string{filepath.Join(dir, "evil.ass"), filepath.Join(unpackPath, "evil.ass")} {
+				if _, err := os.Stat(p); !os.IsNotExist(err) {
+					t.Errorf("file was written outside the nested archive: %s", p)
+				}
+			}
+		})
+	}
+}
+
+func TestUnpackNestedCorruptArchiveKept(t *testing.T) {
+	dir := t.TempDir()
+	broken := "PK\x03\x04 this is not a zip file"
+	archivePath := writeArchive(t, dir, "outer.zip", buildZip(t,
+		archiveFile{name: "01화.ass", body: "subtitle"},
+		archiveFile{name: "broken.zip", body: broken},
+	))
+	unpackPath := filepath.Join(dir, "files")
+
+	manifest, err := NewUnpacker(UnpackerConfig{}).Unpack(archivePath, unpackPath)
+	if err != nil {
+		t.Fatalf("Unpack() error = %v", err)
+	}
+
+	// 풀 수 없는 안쪽 압축 파일은 그대로 둡니다.
+	var paths []string
+	for _, entry := range manifest.Entries {
+		paths = append(paths, entry.Path)
+	}
+	if want := []string{"01화.ass", "broken.zip"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Entries = %q, want %q", paths, want)
+	}
+	data, err := os.ReadFile(filepath.Join(unpackPath, "broken.zip"))
+	if err != nil || string(data) != broken {
+		t.Errorf("broken.zip = %q, %v, want the original content", data, err)
+	}
+	// 안쪽 압축 파일을 풀던 임시 디렉토리를 남기지 않습니다.
+	files, err := os.ReadDir(unpackPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if strings.HasPrefix(f.Name(), ".unpack-") {
+			t.Errorf("temp directory %s was not removed", f.Name())
+		}
+	}
+}
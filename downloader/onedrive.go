@@ -0,0 +1,46 @@
+package downloader
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// DefaultOneDriveAPIURL은 OneDrive 공유 링크를 받을 때 사용하는 API 주소입니다.
+const DefaultOneDriveAPIURL = "https://api.onedrive.com/v1.0"
+
+// OneDriveSource는 OneDrive 공유 링크를 다운로드합니다.
+type OneDriveSource struct {
+	APIURL string // OneDrive API 주소, 비어 있으면 DefaultOneDriveAPIURL을 사용합니다.
+}
+
+// Type은 OneDriveURL을 반환합니다.
+func (s *OneDriveSource) Type() DownloadURLType { return OneDriveURL }
+
+// Match는 url이 OneDrive 공유 링크인지 판별합니다.
+// 링크 형태: https://1drv.ms/u/s!KEY
+// 링크 형태: https://onedrive.live.com/?cid=CID&resid=RESID&authkey=KEY
+func (s *OneDriveSource) Match(url string) bool {
+	switch hostOf(url) {
+	case "1drv.ms", "onedrive.live.com":
+		return true
+	}
+	return false
+}
+
+// Resolve는 공유 링크를 shares API의 content 요청으로 바꿉니다.
+// 공유 토큰은 "u!" 뒤에 패딩 없는 base64url로 인코딩한 공유 링크입니다.
+func (s *OneDriveSource) Resolve(ctx context.Context, client *http.Client, url string) (*http.Request, error) {
+	apiURL := s.APIURL
+	if apiURL == "" {
+		apiURL = DefaultOneDriveAPIURL
+	}
+	token := "u!" + base64.RawURLEncoding.EncodeToString([]byte(url))
+	return newRequest(ctx, strings.TrimRight(apiURL, "/")+"/shares/"+token+"/root/content", "")
+}
+
+// Download는 공유 파일을 받습니다. 파일 이름은 리다이렉트된 다운로드 URL에서 찾습니다.
+func (s *OneDriveSource) Download(ctx context.Context, d *Downloader, url string, dest Dest) ([]*Result, error) {
+	return d.downloadResolved(ctx, s, url, dest, "")
+}
@@ -0,0 +1,40 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOneDriveDownload(t *testing.T) {
+	const shareURL = "https://1drv.ms/u/s!AkSubtitle_07?e=ab12"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download/07화.zip":
+			w.Write([]byte("subtitle content"))
+		default:
+			// shares API는 공유 파일의 다운로드 주소로 리다이렉트합니다.
+			if r.URL.Path != "/v1.0/shares/u!aHR0cHM6Ly8xZHJ2Lm1zL3UvcyFBa1N1YnRpdGxlXzA3P2U9YWIxMg/root/content" {
+				http.NotFound(w, r)
+				return
+			}
+			http.Redirect(w, r, "/download/07%ED%99%94.zip", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+	d := newTestDownloader(t, server)
+	dest := newTestDest(t)
+
+	results, err := (&OneDriveSource{APIURL: server.URL + "/v1.0/"}).Download(context.Background(), d, shareURL, dest)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	// 파일 이름은 리다이렉트된 다운로드 주소에서 찾습니다.
+	if len(results) != 1 || results[0].Name != "07화.zip" {
+		t.Fatalf("Download() = %+v, want 07화.zip", results)
+	}
+	if data := readStored(t, dest, results[0].Path); string(data) != "subtitle content" {
+		t.Errorf("content = %q, want %q", data, "subtitle content")
+	}
+}
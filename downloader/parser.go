@@ -3,11 +3,13 @@ package downloader
 import (
 	"errors"
 	"net/url"
+	"path"
 	"strings"
 )
 
 // ParserImpl은 downloader.Parser interface 의 구현체입니다.
 type ParserImpl struct {
+	Sources *SourceRegistry // 다운로드 URL 타입을 판별할 Source, nil이면 기본 Source를 사용합니다.
 }
 
 // 다운로드 URL 타입을 정의
@@ -16,22 +18,103 @@ type DownloadURLType string
 const (
 	// GoogleDriveURL은 구글 드라이브 URL을 나타냅니다.
 	GoogleDriveURL DownloadURLType = "GoogleDrive"
+	// GoogleDriveFolderURL은 구글 드라이브 폴더 URL을 나타냅니다.
+	GoogleDriveFolderURL DownloadURLType = "GoogleDriveFolder"
 	// NaverBlogURL은 네이버 블로그 URL을 나타냅니다.
 	NaverBlogURL DownloadURLType = "NaverBlog"
+	// TistoryURL은 티스토리 첨부 파일 URL을 나타냅니다.
+	TistoryURL DownloadURLType = "Tistory"
+	// DropboxURL은 Dropbox 공유 링크를 나타냅니다.
+	DropboxURL DownloadURLType = "Dropbox"
+	// OneDriveURL은 OneDrive 공유 링크를 나타냅니다.
+	OneDriveURL DownloadURLType = "OneDrive"
+	// MediaFireURL은 MediaFire 파일 링크를 나타냅니다.
+	MediaFireURL DownloadURLType = "MediaFire"
+	// MegaURL은 Mega 파일 링크를 나타냅니다.
+	MegaURL DownloadURLType = "Mega"
+	// DirectURL은 자막, 압축 파일을 가리키는 일반 HTTP 링크를 나타냅니다.
+	DirectURL DownloadURLType = "Direct"
 	// NotSupportedURL은 지원하지 않는 URL을 나타냅니다.
 	NotSupportedURL DownloadURLType = "NotSupported"
 )
 
 // GetDownloadURLType은 다운로드 URL의 타입을 판별합니다.
+// url에 맞는 Source의 타입을 반환합니다.
 func (p *ParserImpl) GetDownloadURLType(url string) DownloadURLType {
-	if strings.Contains(url, "https://drive.google.com") {
-		return GoogleDriveURL
-	} else if strings.Contains(url, "https://download.blog.naver.com/") {
-		return NaverBlogURL
+	sources := p.Sources
+	if sources == nil {
+		sources = defaultSources
+	}
+	if source := sources.Source(url); source != nil {
+		return source.Type()
 	}
 	return NotSupportedURL
 }
 
+// ParseGoogleDriveFolderURL은 구글 드라이브 폴더 URL을 파싱하여 폴더 ID를 반환합니다.
+func (p *ParserImpl) ParseGoogleDriveFolderURL(urlStr string) (string, error) {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return "", errors.New("failed to parse url")
+	}
+
+	switch {
+	case strings.Contains(parsedURL.Path, "/folders/"):
+		// 링크 형태: https://drive.google.com/drive/folders/FOLDER_ID?usp=sharing
+		// 링크 형태: https://drive.google.com/drive/u/0/folders/FOLDER_ID
+		id := strings.SplitN(parsedURL.Path[strings.Index(parsedURL.Path, "/folders/")+len("/folders/"):], "/", 2)[0]
+		if id != "" {
+			return id, nil
+		}
+	case strings.HasPrefix(parsedURL.Path, "/folderview"):
+		// 링크 형태: https://drive.google.com/folderview?id=FOLDER_ID
+		if id := parsedURL.Query().Get("id"); id != "" {
+			return id, nil
+		}
+	}
+
+	return "", errors.New("invalid Google Drive folder URL format")
+}
+
+// imageExts는 티스토리 CDN에서 첨부 파일이 아닌 이미지로 취급하는 확장자입니다.
+var imageExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".bmp": true,
+}
+
+// IsTistoryAttachmentURL은 티스토리 첨부 파일 URL인지 판별합니다.
+// 첨부 파일은 https://blog.kakaocdn.net/dn/.../파일명?attach=1 또는
+// https://BLOG.tistory.com/attachment/... 형태입니다.
+func IsTistoryAttachmentURL(urlStr string) bool {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(parsedURL.Hostname())
+	switch {
+	case host == "blog.kakaocdn.net" && strings.HasPrefix(parsedURL.Path, "/dn/"):
+		if parsedURL.Query().Get("attach") == "1" {
+			return true
+		}
+		return !imageExts[strings.ToLower(path.Ext(parsedURL.Path))]
+	case strings.HasSuffix(host, ".tistory.com") && strings.HasPrefix(parsedURL.Path, "/attachment/"):
+		return true
+	}
+	return false
+}
+
+// TistoryFileName은 티스토리 첨부 파일 URL에서 원본 파일 이름을 추출합니다.
+// kakaocdn URL의 knm 파라미터에 원본 파일 이름이 있으면 그 값을, 없으면 경로의 마지막 부분을 반환합니다.
+func TistoryFileName(urlStr string) string {
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return ""
+	}
+	if knm := parsedURL.Query().Get("knm"); knm != "" {
+		return knm
+	}
+	return path.Base(parsedURL.Path)
+}
+
 // ParseGoogleDriveURL은 구글 드라이브 URL을 파싱하여 파일 ID를 반환합니다.
 func (p *ParserImpl) ParseGoogleDriveURL(urlStr string) (string, error) {
 	parsedURL, err := url.Parse(urlStr)
@@ -0,0 +1,136 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nwaples/rardecode/v2"
+)
+
+var (
+	// rarPartPattern은 새 방식의 분할 압축 볼륨 이름(예: 01화.part1.rar, 01화.part01.rar)입니다.
+	rarPartPattern = regexp.MustCompile(`(?i)^(.*\.part)(\d+)(\.rar)$`)
+	// rarOldVolumePattern은 예전 방식의 분할 압축 볼륨 이름(예: 01화.r00)입니다. 첫 볼륨은 .rar 입니다.
+	rarOldVolumePattern = regexp.MustCompile(`(?i)^(.*\.r)(\d\d)$`)
+)
+
+// RarVolume은 name이 rar 파일의 볼륨이면 볼륨 번호를 반환합니다. 첫 볼륨은 1입니다.
+// 분할되지 않은 .rar 파일도 첫 볼륨으로 봅니다.
+func RarVolume(name string) (int, bool) {
+	if m := rarPartPattern.FindStringSubmatch(name); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return n, true
+	}
+	if m := rarOldVolumePattern.FindStringSubmatch(name); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return n + 2, true
+	}
+	if strings.EqualFold(filepath.Ext(name), ".rar") {
+		return 1, true
+	}
+	return 0, false
+}
+
+// NextRarVolume은 rar 볼륨 name의 다음 볼륨 이름을 반환합니다.
+// 볼륨 번호의 자릿수는 유지합니다. (part09 -> part10, part1 -> part2)
+func NextRarVolume(name string) string {
+	if m := rarPartPattern.FindStringSubmatch(name); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return fmt.Sprintf("%s%0*d%s", m[1], len(m[2]), n+1, m[3])
+	}
+	if m := rarOldVolumePattern.FindStringSubmatch(name); m != nil {
+		n, _ := strconv.Atoi(m[2])
+		return fmt.Sprintf("%s%02d", m[1], n+1)
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ".r00"
+}
+
+// unpackRar은 rar 파일(RAR4, RAR5)을 풉니다.
+// 분할 압축 파일은 첫 볼륨을 넘기면 같은 디렉토리의 다음 볼륨을 이어서 읽고, 첫 볼륨이 아니면 ErrNotFirstVolume을 반환합니다.
+// 암호가 걸린 파일은 passwords를 차례로 시도합니다.
+func unpackRar(filePath string, unpackPath string, q *quota, passwords []string) ([]ManifestEntry, error) {
+	if n, ok := RarVolume(filePath); ok && n > 1 {
+		return nil, ErrNotFirstVolume
+	}
+	return tryPasswords(q, passwords, func(password string) ([]ManifestEntry, error) {
+		return extractRar(filePath, unpackPath, q, password)
+	})
+}
+
+// extractRar은 password로 rar 파일을 풉니다.
+// 비밀번호가 없거나 틀려서 풀 수 없으면 ErrPasswordRequired를 감싼 에러를 반환합니다.
+func extractRar(filePath string, unpackPath string, q *quota, password string) ([]ManifestEntry, error) {
+	var opts []rardecode.Option
+	if password != "" {
+		opts = append(opts, rardecode.Password(password))
+	}
+	r, err := rardecode.OpenReader(filePath, opts...)
+	if err != nil {
+		return nil, rarError(err, false)
+	}
+	defer r.Close()
+
+	var entries []ManifestEntry
+	for {
+		hdr, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, rarError(err, false)
+		}
+
+		dst, rel, err := q.entryPath(unpackPath, hdr.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		mode := hdr.Mode()
+		switch {
+		case hdr.IsDir:
+			if err := os.MkdirAll(dst, os.ModePerm); err != nil {
+				return nil, fmt.Errorf("failed to create directory: %w", err)
+			}
+		case mode.IsRegular():
+			size, err := q.write(hdr.Name, dst, r)
+			if err != nil {
+				return nil, fmt.Errorf("failed to extract %s: %w", hdr.Name, rarError(err, hdr.Encrypted))
+			}
+			entries = append(entries, ManifestEntry{
+				Path:     rel,
+				Size:     size,
+				Modified: hdr.ModificationTime,
+			})
+		default:
+			// 심볼릭 링크 등은 풀지 않습니다.
+			log.Printf("Skip rar entry %s (mode %s)\n", hdr.Name, mode)
+		}
+	}
+	return entries, nil
+}
+
+// rarError는 비밀번호 때문에 발생한 rardecode 에러를 ErrPasswordRequired로 감쌉니다.
+// 암호가 걸린 파일을 틀린 비밀번호로 풀면 체크섬이 맞지 않거나 디코딩 에러가 발생하므로,
+// encrypted이면 파일을 쓰다가 발생한 에러가 아닌 모든 에러를 비밀번호 에러로 봅니다.
+func rarError(err error, encrypted bool) error {
+	var pathErr *fs.PathError
+	var unsafeErr *UnsafeArchiveError
+	if errors.As(err, &unsafeErr) {
+		return err
+	}
+	if errors.Is(err, rardecode.ErrArchiveEncrypted) ||
+		errors.Is(err, rardecode.ErrArchivedFileEncrypted) ||
+		errors.Is(err, rardecode.ErrBadPassword) ||
+		(encrypted && !errors.As(err, &pathErr)) {
+		return fmt.Errorf("%w: %v", ErrPasswordRequired, err)
+	}
+	return err
+}
@@ -0,0 +1,156 @@
+package downloader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testdataPassword는 testdata의 암호가 걸린 압축 파일의 비밀번호입니다.
+// testdata의 rar, 7z 파일은 testdata/gen_archives.go로 만듭니다.
+const testdataPassword = "anisub"
+
+// unpackTestdata는 testdata의 names 파일을 한 디렉토리에 복사한 뒤 names[0]을 passwords로 풀고,
+// 풀린 파일의 경로별 내용을 반환합니다. 분할 압축 파일은 names에 모든 볼륨을 넘깁니다.
+func unpackTestdata(t *testing.T, passwords []string, names ...string) (*Manifest, map[string]string, error) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		writeArchive(t, dir, name, readTestdata(t, name))
+	}
+	unpackPath := filepath.Join(dir, "files")
+
+	manifest, err := NewUnpacker(UnpackerConfig{Passwords: passwords}).Unpack(filepath.Join(dir, names[0]), unpackPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := make(map[string]string)
+	for _, entry := range manifest.Entries {
+		body, err := os.ReadFile(filepath.Join(unpackPath, filepath.FromSlash(entry.Path)))
+		if err != nil {
+			t.Fatalf("unpacked file %s: %v", entry.Path, err)
+		}
+		files[entry.Path] = string(body)
+	}
+	return manifest, files, nil
+}
+
+// testdataFiles는 testdata의 rar, 7z 파일에 담긴 파일입니다.
+var testdataFiles = map[string]string{
+	"01화.ass":     "[Script Info]\nTitle: 01\n",
+	"fonts/a.ttf": "font",
+}
+
+func TestUnpackRar(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     []string
+		passwords []string
+		wantErr   error
+	}{
+		{name: "rar4", files: []string{"subtitle.rar"}},
+		{name: "rar5", files: []string{"subtitle5.rar"}},
+		{
+			// 첫 파일이 두 볼륨에 나뉘어 있습니다.
+			name:  "rar4 multi-volume",
+			files: []string{"subtitle.part1.rar", "subtitle.part2.rar"},
+		},
+		{
+			name:  "rar5 multi-volume",
+			files: []string{"subtitle5.part1.rar", "subtitle5.part2.rar"},
+		},
+		{
+			name:      "rar4 password",
+			files:     []string{"password.rar"},
+			passwords: []string{"wrong", testdataPassword},
+		},
+		{
+			name:      "rar5 password",
+			files:     []string{"password5.rar"},
+			passwords: []string{"wrong", testdataPassword},
+		},
+		{
+			// RAR4는 비밀번호 확인 값이 없어 CRC-32가 맞지 않는 것으로 틀린 비밀번호를 알 수 있습니다.
+			name:      "rar4 wrong password",
+			files:     []string{"password.rar"},
+			passwords: []string{"wrong", "password"},
+			wantErr:   ErrPasswordRequired,
+		},
+		{
+			name:      "rar5 wrong password",
+			files:     []string{"password5.rar"},
+			passwords: []string{"wrong", "password"},
+			wantErr:   ErrPasswordRequired,
+		},
+		{name: "rar4 missing password", files: []string{"password.rar"}, wantErr: ErrPasswordRequired},
+		{name: "rar5 missing password", files: []string{"password5.rar"}, wantErr: ErrPasswordRequired},
+		{
+			name:    "not first volume",
+			files:   []string{"subtitle5.part2.rar", "subtitle5.part1.rar"},
+			wantErr: ErrNotFirstVolume,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, files, err := unpackTestdata(t, tt.passwords, tt.files...)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Unpack() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unpack() error = %v", err)
+			}
+			if manifest.PackType != Rar {
+				t.Errorf("PackType = %s, want %s", manifest.PackType, Rar)
+			}
+			if !reflect.DeepEqual(files, testdataFiles) {
+				t.Errorf("files = %q, want %q", files, testdataFiles)
+			}
+		})
+	}
+}
+
+func TestUnpackRarMissingVolume(t *testing.T) {
+	_, _, err := unpackTestdata(t, nil, "subtitle5.part1.rar")
+	if err == nil {
+		t.Fatal("Unpack() error = nil, want missing volume error")
+	}
+	if errors.Is(err, ErrPasswordRequired) {
+		t.Errorf("Unpack() error = %v, want not a password error", err)
+	}
+}
+
+func TestRarVolume(t *testing.T) {
+	tests := []struct {
+		name     string
+		want     int
+		wantOK   bool
+		wantNext string
+	}{
+		{name: "01화.rar", want: 1, wantOK: true, wantNext: "01화.r00"},
+		{name: "01화.part1.rar", want: 1, wantOK: true, wantNext: "01화.part2.rar"},
+		{name: "01화.part09.rar", want: 9, wantOK: true, wantNext: "01화.part10.rar"},
+		{name: "01화.PART2.RAR", want: 2, wantOK: true, wantNext: "01화.PART3.RAR"},
+		{name: "01화.r00", want: 2, wantOK: true, wantNext: "01화.r01"},
+		{name: "01화.r09", want: 11, wantOK: true, wantNext: "01화.r10"},
+		{name: "01화.zip", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := RarVolume(tt.name)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("RarVolume(%q) = %d, %t, want %d, %t", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+		if !ok {
+			continue
+		}
+		if next := NextRarVolume(tt.name); next != tt.wantNext {
+			t.Errorf("NextRarVolume(%q) = %q, want %q", tt.name, next, tt.wantNext)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// partState는 이어 받기 위해 .part 파일과 함께 저장하는 다운로드 상태입니다.
+// 받은 바이트 수는 .part 파일의 크기입니다.
+type partState struct {
+	URL          string `json:"url"`                    // 원본 URL (Resolve하기 전의 공유 링크)
+	ETag         string `json:"etag,omitempty"`         // 처음 응답의 ETag
+	LastModified string `json:"lastModified,omitempty"` // 처음 응답의 Last-Modified
+	AcceptRanges bool   `json:"acceptRanges"`           // 서버가 Accept-Ranges: bytes 를 알려주었는지 여부
+	Size         int64  `json:"size"`                   // 전체 파일 크기, 알 수 없으면 -1
+	FileName     string `json:"fileName"`               // 저장할 파일 이름
+	MIMEType     string `json:"mimeType,omitempty"`     // 원본이 알려준 MIME 타입
+	MD5          string `json:"md5,omitempty"`          // 원본이 알려준 MD5 해시 (hex), Drive API로 받는 파일만 있습니다.
+}
+
+// validator는 If-Range에 사용할 값을 반환합니다.
+// 약한 ETag(W/)는 If-Range에 사용할 수 없으므로 Last-Modified를 사용합니다.
+func (s *partState) validator() string {
+	if s.ETag != "" && !strings.HasPrefix(s.ETag, "W/") {
+		return s.ETag
+	}
+	return s.LastModified
+}
+
+// canResume은 저장된 상태로 Range 요청을 보낼 수 있는지 확인합니다.
+// MD5를 아는 파일은 If-Range 없이 이어 받고, 다 받은 뒤 MD5로 검증합니다.
+func (s *partState) canResume() bool {
+	return s.MD5 != "" || (s.AcceptRanges && s.validator() != "")
+}
+
+// partPath는 원본 url을 dest.Dir에 받는 다운로드의 .part 파일 경로를 반환합니다.
+// MediaFire처럼 Resolve할 때마다 다운로드 URL이 바뀌는 호스트도 있으므로 실제 요청 URL이 아닌 원본 URL을 사용합니다.
+// 같은 다운로드는 프로세스를 다시 시작해도 같은 경로를 사용합니다.
+func (d *Downloader) partPath(url string, dest Dest) string {
+	sum := sha256.Sum256([]byte(dest.Dir + "\n" + url))
+	return filepath.Join(d.TempDir, hex.EncodeToString(sum[:16])+".part")
+}
+
+// loadPartState는 .part 파일의 상태와 받은 바이트 수를 읽습니다.
+// 상태가 없거나 다른 원본 URL의 상태이면 nil을 반환합니다.
+func loadPartState(partPath string, url string) (*partState, int64) {
+	data, err := os.ReadFile(partPath + ".json")
+	if err != nil {
+		return nil, 0
+	}
+	var state partState
+	if err := json.Unmarshal(data, &state); err != nil || state.URL != url {
+		return nil, 0
+	}
+	info, err := os.Stat(partPath)
+	if err != nil {
+		return nil, 0
+	}
+	return &state, info.Size()
+}
+
+// savePartState는 .part 파일의 상태를 저장합니다.
+func savePartState(partPath string, state *partState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(partPath+".json", data, 0o644); err != nil {
+		return fmt.Errorf("failed to save download state: %w", err)
+	}
+	return nil
+}
+
+// removePart는 .part 파일과 상태를 지웁니다.
+func removePart(partPath string) {
+	os.Remove(partPath)
+	os.Remove(partPath + ".json")
+}
+
+// parseContentRange는 Content-Range 헤더(bytes START-END/TOTAL)의 시작, 끝 위치와 전체 크기를 반환합니다.
+// 전체 크기가 *이면 -1을 반환합니다.
+func parseContentRange(header string) (int64, int64, int64, error) {
+	invalid := fmt.Errorf("invalid content-range: %q", header)
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes ")
+	if !ok {
+		return 0, 0, 0, invalid
+	}
+	byteRange, totalSpec, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, 0, invalid
+	}
+	startSpec, endSpec, ok := strings.Cut(byteRange, "-")
+	if !ok {
+		return 0, 0, 0, invalid
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(startSpec), 10, 64)
+	if err != nil {
+		return 0, 0, 0, invalid
+	}
+	end, err := strconv.ParseInt(strings.TrimSpace(endSpec), 10, 64)
+	if err != nil || end < start {
+		return 0, 0, 0, invalid
+	}
+	total := int64(-1)
+	if totalSpec = strings.TrimSpace(totalSpec); totalSpec != "*" {
+		total, err = strconv.ParseInt(totalSpec, 10, 64)
+		if err != nil || end >= total {
+			return 0, 0, 0, invalid
+		}
+	}
+	return start, end, total, nil
+}
+
+// part는 .part 파일로 이어 받는 다운로드 하나입니다.
+type part struct {
+	path   string     // .part 파일 경로
+	state  *partState // 저장된 상태, 처음 받거나 이어 받을 수 없으면 nil
+	offset int64      // 이미 받은 바이트 수
+}
+
+// openPart는 원본 sourceURL을 dest.Dir에 받는 .part 파일을 엽니다.
+// expected.md5가 저장된 상태의 MD5와 다르면 원본 파일이 바뀐 것이므로 처음부터 받습니다.
+func (d *Downloader) openPart(sourceURL string, dest Dest, expected expectation) (*part, error) {
+	partPath := d.partPath(sourceURL, dest)
+	if err := os.MkdirAll(filepath.Dir(partPath), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	state, offset := loadPartState(partPath, sourceURL)
+	if state != nil && expected.md5 != "" && !strings.EqualFold(state.MD5, expected.md5) {
+		state, offset = nil, 0
+	}
+	return &part{path: partPath, state: state, offset: offset}, nil
+}
+
+// complete는 다 받은 뒤 옮기기 전에 종료된 다운로드인지 확인합니다.
+func (p *part) complete() bool {
+	return p.state != nil && p.state.Size > 0 && p.offset == p.state.Size
+}
+
+// matchesRange는 206 응답의 Content-Range 헤더가 받은 부분 바로 다음부터
+// 저장된 상태와 같은 크기의 파일을 보낸다고 알려주는지 확인합니다. 저장된 상태에 크기가 없으면 크기는 확인하지 않습니다.
+func (p *part) matchesRange(header string) bool {
+	start, _, total, err := parseContentRange(header)
+	if err != nil || p.state == nil || p.offset == 0 || start != p.offset {
+		return false
+	}
+	return p.state.Size <= 0 || total == p.state.Size
+}
+
+// setRange는 이어 받을 수 있으면 req에 Range, If-Range 헤더를 설정합니다.
+func (p *part) setRange(req *http.Request) {
+	if p.state == nil || p.offset == 0 || !p.state.canResume() {
+		p.offset = 0
+		return
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", p.offset))
+	if validator := p.state.validator(); validator != "" {
+		req.Header.Set("If-Range", validator)
+	}
+	log.Printf("Resume: %s from %d bytes\n", req.URL, p.offset)
+}
+
+// downloadRequest는 원본 sourceURL을 Resolve한 req를 보내 응답을 dest에 저장합니다.
+// 응답은 TempDir의 .part 파일에 받으며, 도중에 연결이 끊기거나 프로세스가 종료되면
+// 다음 시도에서 Range 요청으로 받은 부분 다음부터 이어서 받습니다.
+// .part 파일은 sourceURL로 찾으므로 다시 Resolve하여 요청 URL이 바뀌어도 이어서 받습니다.
+// 서버가 Accept-Ranges를 알려주지 않았거나 ETag/Last-Modified가 바뀌어 서버가 전체 파일을 보내면 처음부터 받습니다.
+// 서버가 받은 부분과 맞지 않는 부분(다른 시작 위치나 전체 크기)을 보내면 .part 파일을 지우고 Range 없이 다시 요청합니다.
+// 다 받은 파일은 expected로 검증합니다. 크기가 없으면 응답의 Content-Length로 검증합니다.
+func (d *Downloader) downloadRequest(sourceURL string, req *http.Request, dest Dest, fallbackName string, expected expectation) (*Result, error) {
+	p, err := d.openPart(sourceURL, dest, expected)
+	if err != nil {
+		return nil, err
+	}
+	if p.complete() {
+		return d.finishPart(p, dest, expected)
+	}
+	p.setRange(req)
+
+	resp, err := d.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPartialContent && !p.matchesRange(resp.Header.Get("Content-Range")) {
+		log.Printf("Restart: %s sent %q for %d bytes\n", req.URL, resp.Header.Get("Content-Range"), p.offset)
+		resp.Body.Close()
+		removePart(p.path)
+		p.state, p.offset = nil, 0
+
+		req = req.Clone(req.Context())
+		req.Header.Del("Range")
+		req.Header.Del("If-Range")
+		resp, err = d.HTTPClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+	}
+
+	return d.savePart(p, sourceURL, resp, dest, fallbackName, expected)
+}
+
+// savePart는 resp를 .part 파일에 받고, 다 받으면 검증하여 dest에 저장합니다.
+// 206 응답은 Content-Range가 받은 부분과 맞으면 뒤에 이어 쓰고 맞지 않으면 .part 파일을 지웁니다. 200 응답은 처음부터 다시 씁니다.
+func (d *Downloader) savePart(p *part, sourceURL string, resp *http.Response, dest Dest, fallbackName string, expected expectation) (*Result, error) {
+	reqURL := resp.Request.URL.String()
+
+	// response code를 확인합니다.
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if !p.matchesRange(resp.Header.Get("Content-Range")) {
+			removePart(p.path)
+			return nil, fmt.Errorf("unexpected partial content from %s: %q", reqURL, resp.Header.Get("Content-Range"))
+		}
+	case http.StatusOK:
+		// 이어 받기를 지원하지 않거나 파일이 바뀌었으면 처음부터 받습니다.
+		if p.offset > 0 {
+			log.Printf("Restart: %s\n", reqURL)
+		}
+		p.offset = 0
+		p.state = newPartState(sourceURL, resp, fallbackName)
+		p.state.MD5 = expected.md5
+		if err := savePartState(p.path, p.state); err != nil {
+			return nil, err
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// 저장된 상태가 서버의 파일과 맞지 않으므로 다음 시도에서 처음부터 받습니다.
+		removePart(p.path)
+		return nil, &StatusError{URL: reqURL, StatusCode: resp.StatusCode}
+	default:
+		return nil, &StatusError{URL: reqURL, StatusCode: resp.StatusCode}
+	}
+	log.Printf("File Name: %s\n", p.state.FileName)
+
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if p.offset > 0 {
+		flag = os.O_WRONLY | os.O_APPEND
+	}
+	partFile, err := os.OpenFile(p.path, flag, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open part file: %w", err)
+	}
+	// 에러가 발생해도 .part 파일을 남겨 두어 다음 시도에서 이어서 받습니다.
+	_, err = io.Copy(partFile, resp.Body)
+	if closeErr := partFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return d.finishPart(p, dest, expected)
+}
+
+// newPartState는 원본 sourceURL의 전체 파일을 보낸 응답으로 다운로드 상태를 만듭니다.
+// 파일 이름은 Content-Disposition 헤더, fallbackName, 최종 응답 URL 순서로 찾습니다.
+func newPartState(sourceURL string, resp *http.Response, fallbackName string) *partState {
+	fileName := fileNameFromContentDisposition(resp.Header.Get("Content-Disposition"))
+	if fileName == "" {
+		fileName = fallbackName
+	}
+	if fileName == "" {
+		fileName = fileNameFromURL(resp.Request.URL.String())
+	}
+
+	return &partState{
+		URL:          sourceURL,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		AcceptRanges: strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes"),
+		Size:         resp.ContentLength,
+		FileName:     fileName,
+		MIMEType:     resp.Header.Get("Content-Type"),
+	}
+}
+
+// finishPart는 다 받은 .part 파일을 검증하여 dest에 저장하고 상태를 지웁니다.
+// expected에 없는 크기와 MIME 타입은 저장된 상태의 값을 사용합니다.
+// 검증에 실패하면 .part 파일을 지우므로 다음 시도는 처음부터 받습니다.
+// 저장에 실패하면 .part 파일을 남겨 두어 다음 시도에서 다시 받지 않고 저장합니다.
+func (d *Downloader) finishPart(p *part, dest Dest, expected expectation) (*Result, error) {
+	if expected.size <= 0 {
+		expected.size = p.state.Size
+	}
+	if expected.mimeType == "" {
+		expected.mimeType = p.state.MIMEType
+	}
+	result, err := verifyFile(p.path, p.state.FileName, expected)
+	if err != nil {
+		removePart(p.path)
+		return nil, err
+	}
+	if err := dest.put(result, p.path); err != nil {
+		return nil, err
+	}
+	removePart(p.path)
+	return result, nil
+}
@@ -0,0 +1,335 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		header    string
+		wantStart int64
+		wantEnd   int64
+		wantTotal int64
+		wantErr   bool
+	}{
+		{header: "bytes 100-199/200", wantStart: 100, wantEnd: 199, wantTotal: 200},
+		{header: " bytes 0-99/* ", wantStart: 0, wantEnd: 99, wantTotal: -1},
+		{header: "bytes 5-9/10", wantStart: 5, wantEnd: 9, wantTotal: 10},
+		{header: "bytes */200", wantErr: true},
+		{header: "bytes 100-199", wantErr: true},
+		{header: "bytes 100-/200", wantErr: true},
+		{header: "bytes 199-100/200", wantErr: true},
+		{header: "bytes 100-200/200", wantErr: true},
+		{header: "100-199/200", wantErr: true},
+		{header: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		start, end, total, err := parseContentRange(tt.header)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseContentRange(%q) = %d, %d, %d, want error", tt.header, start, end, total)
+			}
+			continue
+		}
+		if err != nil || start != tt.wantStart || end != tt.wantEnd || total != tt.wantTotal {
+			t.Errorf("parseContentRange(%q) = %d, %d, %d, %v, want %d, %d, %d",
+				tt.header, start, end, total, err, tt.wantStart, tt.wantEnd, tt.wantTotal)
+		}
+	}
+}
+
+// seedPart는 sourceURL을 dest에 받다가 중단된 것처럼 .part 파일과 상태를 만듭니다.
+func seedPart(t *testing.T, d *Downloader, sourceURL string, dest Dest, data string, state partState) string {
+	t.Helper()
+	partPath := d.partPath(sourceURL, dest)
+	if err := os.MkdirAll(filepath.Dir(partPath), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(partPath, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	state.URL = sourceURL
+	if err := savePartState(partPath, &state); err != nil {
+		t.Fatal(err)
+	}
+	return partPath
+}
+
+// partialHandler는 Range 요청에 contentRange와 partial로 206 응답을 보내고,
+// Range가 없는 요청에는 content 전체를 보냅니다.
+func partialHandler(content string, contentRange string, partial string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") == "" {
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Write([]byte(content))
+			return
+		}
+		w.Header().Set("Content-Range", contentRange)
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(partial))
+	}
+}
+
+func TestDownloadRequestResume(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	modTime := time.Date(2023, 10, 22, 4, 24, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		etag       string // 서버 파일의 ETag
+		part       string // 이미 받은 내용
+		state      partState
+		handler    http.HandlerFunc // nil이면 http.ServeContent로 Range 요청을 처리합니다.
+		wantRanges []string         // 요청마다 보낸 Range 헤더
+		wantStatus int              // 기대하는 StatusError의 상태 코드, 0이면 성공
+		wantErr    bool
+		wantPart   bool // 실패한 뒤 .part 파일이 남아야 하는지 여부
+	}{
+		{
+			name:       "206 resumes",
+			etag:       `"v1"`,
+			part:       content[:300],
+			state:      partState{ETag: `"v1"`, AcceptRanges: true, Size: int64(len(content)), FileName: "subtitle.zip"},
+			wantRanges: []string{"bytes=300-"},
+		},
+		{
+			name:       "200 restarts when the file changed",
+			etag:       `"v2"`,
+			part:       "stale data",
+			state:      partState{ETag: `"v1"`, AcceptRanges: true, Size: int64(len(content)), FileName: "subtitle.zip"},
+			wantRanges: []string{"bytes=10-"},
+		},
+		{
+			name:       "no validator starts over",
+			etag:       `"v1"`,
+			part:       content[:300],
+			state:      partState{AcceptRanges: true, Size: int64(len(content)), FileName: "subtitle.zip"},
+			wantRanges: []string{""},
+		},
+		{
+			name:  "weak etag uses last-modified",
+			etag:  `W/"v1"`,
+			part:  content[:300],
+			state: partState{ETag: `W/"v1"`, LastModified: modTime.Format(http.TimeFormat), AcceptRanges: true, Size: int64(len(content)), FileName: "subtitle.zip"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("If-Range") != modTime.Format(http.TimeFormat) {
+					http.Error(w, "unexpected If-Range "+r.Header.Get("If-Range"), http.StatusBadRequest)
+					return
+				}
+				w.Header().Set("Content-Range", "bytes 300-999/1000")
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte(content[300:]))
+			},
+			wantRanges: []string{"bytes=300-"},
+		},
+		{
+			name:       "416 removes the part",
+			etag:       `"v1"`,
+			part:       content + "extra",
+			state:      partState{ETag: `"v1"`, AcceptRanges: true, Size: int64(len(content)) + 10, FileName: "subtitle.zip"},
+			wantRanges: []string{"bytes=1005-"},
+			wantStatus: http.StatusRequestedRangeNotSatisfiable,
+		},
+		{
+			// 받은 부분과 맞지 않는 206 응답은 .part 파일을 지우고 Range 없이 다시 요청합니다.
+			name:       "206 at the wrong offset restarts",
+			etag:       `"v1"`,
+			part:       content[:300],
+			state:      partState{ETag: `"v1"`, AcceptRanges: true, Size: int64(len(content)), FileName: "subtitle.zip"},
+			handler:    partialHandler(content, "bytes 0-999/1000", content),
+			wantRanges: []string{"bytes=300-", ""},
+		},
+		{
+			name:       "206 with a different total restarts",
+			etag:       `"v1"`,
+			part:       content[:300],
+			state:      partState{ETag: `"v1"`, AcceptRanges: true, Size: int64(len(content)), FileName: "subtitle.zip"},
+			handler:    partialHandler(content, "bytes 300-1009/1010", content[300:]+"0123456789"),
+			wantRanges: []string{"bytes=300-", ""},
+		},
+		{
+			name:       "206 without the total restarts",
+			etag:       `"v1"`,
+			part:       content[:300],
+			state:      partState{ETag: `"v1"`, AcceptRanges: true, Size: int64(len(content)), FileName: "subtitle.zip"},
+			handler:    partialHandler(content, "bytes 300-999/*", content[300:]),
+			wantRanges: []string{"bytes=300-", ""},
+		},
+		{
+			name:       "invalid content-range restarts",
+			etag:       `"v1"`,
+			part:       content[:300],
+			state:      partState{ETag: `"v1"`, AcceptRanges: true, Size: int64(len(content)), FileName: "subtitle.zip"},
+			handler:    partialHandler(content, "bytes 300-999", content[300:]),
+			wantRanges: []string{"bytes=300-", ""},
+		},
+		{
+			// 처음 받을 때 크기를 몰랐으면 전체 크기는 확인하지 않습니다.
+			name:       "206 with unknown size resumes",
+			etag:       `"v1"`,
+			part:       content[:300],
+			state:      partState{ETag: `"v1"`, AcceptRanges: true, Size: -1, FileName: "subtitle.zip"},
+			handler:    partialHandler(content, "bytes 300-999/*", content[300:]),
+			wantRanges: []string{"bytes=300-"},
+		},
+		{
+			// Range 없이 다시 요청해도 206으로 답하면 .part 파일을 지우고 실패합니다.
+			name:  "206 without range removes the part",
+			etag:  `"v1"`,
+			part:  content[:300],
+			state: partState{ETag: `"v1"`, AcceptRanges: true, Size: int64(len(content)), FileName: "subtitle.zip"},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Range", "bytes 0-999/1000")
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte(content))
+			},
+			wantRanges: []string{"bytes=300-", ""},
+			wantErr:    true,
+		},
+		{
+			name:       "other status keeps the part",
+			part:       content[:300],
+			state:      partState{ETag: `"v1"`, AcceptRanges: true, Size: int64(len(content)), FileName: "subtitle.zip"},
+			handler:    func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusServiceUnavailable) },
+			wantRanges: []string{"bytes=300-"},
+			wantStatus: http.StatusServiceUnavailable,
+			wantPart:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ranges []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ranges = append(ranges, r.Header.Get("Range"))
+				if tt.handler != nil {
+					tt.handler(w, r)
+					return
+				}
+				w.Header().Set("ETag", tt.etag)
+				http.ServeContent(w, r, "subtitle.zip", modTime, strings.NewReader(content))
+			}))
+			defer server.Close()
+
+			d := newTestDownloader(t, server)
+			dest := newTestDest(t)
+			sourceURL := server.URL + "/subtitle.zip"
+			partPath := seedPart(t, d, sourceURL, dest, tt.part, tt.state)
+
+			req, err := newRequest(context.Background(), sourceURL, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := d.downloadRequest(sourceURL, req, dest, "", expectation{})
+			if !reflect.DeepEqual(ranges, tt.wantRanges) {
+				t.Errorf("Range headers = %q, want %q", ranges, tt.wantRanges)
+			}
+
+			if tt.wantStatus != 0 || tt.wantErr {
+				var statusErr *StatusError
+				if tt.wantStatus != 0 && (!errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantStatus) {
+					t.Errorf("downloadRequest() error = %v, want status %d", err, tt.wantStatus)
+				}
+				if tt.wantErr && err == nil {
+					t.Error("downloadRequest() error = nil")
+				}
+				if _, statErr := os.Stat(partPath); (statErr == nil) != tt.wantPart {
+					t.Errorf(".part exists = %v, want %v", statErr == nil, tt.wantPart)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("downloadRequest() error = %v", err)
+			}
+			data := readStored(t, dest, result.Path)
+			if string(data) != content {
+				t.Errorf("content length = %d, want %d", len(data), len(content))
+			}
+			if _, err := os.Stat(partPath); !os.IsNotExist(err) {
+				t.Errorf(".part was not removed: %v", err)
+			}
+		})
+	}
+}
+
+func TestDownloadRequestFinishedPart(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL)
+	}))
+	defer server.Close()
+
+	// 다 받은 뒤 저장하기 전에 종료된 다운로드는 다시 요청하지 않습니다.
+	d := newTestDownloader(t, server)
+	dest := newTestDest(t)
+	sourceURL := server.URL + "/subtitle.zip"
+	seedPart(t, d, sourceURL, dest, "done", partState{ETag: `"v1"`, AcceptRanges: true, Size: 4, FileName: "subtitle.zip"})
+
+	req, err := newRequest(context.Background(), sourceURL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := d.downloadRequest(sourceURL, req, dest, "", expectation{})
+	if err != nil {
+		t.Fatalf("downloadRequest() error = %v", err)
+	}
+	if result.Name != "subtitle.zip" || result.Size != 4 {
+		t.Errorf("downloadRequest() = %+v", result)
+	}
+}
+
+// failingSink는 항상 저장에 실패하는 Sink입니다.
+type failingSink struct{}
+
+func (failingSink) Put(logicalPath string, srcPath string) error {
+	return errors.New("storage unavailable")
+}
+
+func TestDownloadRequestSinkFailureKeepsPart(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Write([]byte("subtitle content"))
+	}))
+	defer server.Close()
+
+	d := newTestDownloader(t, server)
+	sourceURL := server.URL + "/subtitle.zip"
+	req, err := newRequest(context.Background(), sourceURL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.downloadRequest(sourceURL, req, Dest{Dir: "1/2/tester", Sink: failingSink{}}, "", expectation{}); err == nil {
+		t.Fatal("downloadRequest() error = nil, want storage error")
+	}
+
+	// 저장소가 복구되면 다시 받지 않고 남아 있는 .part 파일을 저장합니다.
+	dest := newTestDest(t)
+	result, err := d.downloadRequest(sourceURL, req, dest, "", expectation{})
+	if err != nil {
+		t.Fatalf("downloadRequest() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+	if data := readStored(t, dest, result.Path); string(data) != "subtitle content" {
+		t.Errorf("content = %q, want %q", data, "subtitle content")
+	}
+	if _, err := os.Stat(d.partPath(sourceURL, dest)); !os.IsNotExist(err) {
+		t.Errorf(".part was not removed: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package downloader
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ratioMinSize는 압축률을 검사하기 시작하는 풀린 크기입니다.
+// 작은 텍스트 자막은 압축률이 높으므로 이보다 작으면 압축률을 검사하지 않습니다.
+const ratioMinSize = 1 << 20
+
+// UnsafeArchiveError는 안전하지 않은 압축 파일의 압축 해제를 중단했을 때의 에러입니다.
+// 압축 파일 밖을 가리키는 경로, 절대 경로, 풀린 크기, 파일 수, 압축률 제한을 넘는 압축 파일이 해당합니다.
+type UnsafeArchiveError struct {
+	Archive string // 압축 파일 이름
+	Entry   string // 문제가 된 파일 이름, 압축 파일 전체의 문제이면 비어 있습니다.
+	Reason  string // 중단한 이유
+}
+
+func (e *UnsafeArchiveError) Error() string {
+	if e.Entry == "" {
+		return fmt.Sprintf("unsafe archive %s: %s", e.Archive, e.Reason)
+	}
+	return fmt.Sprintf("unsafe archive %s: %s: %q", e.Archive, e.Reason, e.Entry)
+}
+
+// quota는 압축 파일 하나를 풀면서 풀린 크기와 파일 수를 세고 UnpackerImpl의 제한과 비교합니다.
+// 안쪽 압축 파일도 같은 quota로 풀므로 제한은 모든 깊이를 합쳐서 적용됩니다.
+type quota struct {
+	archive     string // 압축 파일 이름, 안쪽 압축 파일을 푸는 동안에는 바깥 압축 파일 이름/안쪽 압축 파일 경로
+	archiveSize int64  // 압축 파일 크기 (분할 압축 파일은 모든 볼륨의 합)
+	config      UnpackerConfig
+	entries     int   // 지금까지 풀린 파일, 디렉토리 수
+	total       int64 // 지금까지 풀린 크기 (byte)
+}
+
+// newQuota는 filePath 압축 파일의 quota를 생성합니다.
+func newQuota(filePath string, packType PackType, config UnpackerConfig) (*quota, error) {
+	size, err := archiveSize(filePath, packType)
+	if err != nil {
+		return nil, err
+	}
+	return &quota{
+		archive:     filepath.Base(filePath),
+		archiveSize: size,
+		config:      config,
+	}, nil
+}
+
+// archiveSize는 압축 파일의 크기를 반환합니다. 분할 압축된 rar 파일은 같은 디렉토리에 있는 볼륨 크기를 모두 더합니다.
+func archiveSize(filePath string, packType PackType) (int64, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	if packType != Rar {
+		return size, nil
+	}
+	for volume := NextRarVolume(filePath); ; volume = NextRarVolume(volume) {
+		info, err := os.Stat(volume)
+		if err != nil {
+			return size, nil
+		}
+		size += info.Size()
+	}
+}
+
+// unsafe는 entry에 대한 UnsafeArchiveError를 만듭니다.
+func (q *quota) unsafe(entry string, format string, args ...any) error {
+	return &UnsafeArchiveError{
+		Archive: q.archive,
+		Entry:   entry,
+		Reason:  fmt.Sprintf(format, args...),
+	}
+}
+
+// mark는 지금까지 센 값을 반환합니다. 비밀번호를 바꿔 다시 풀 때 restore로 되돌립니다.
+func (q *quota) mark() quota {
+	return *q
+}
+
+// restore는 mark로 저장한 값으로 되돌립니다.
+func (q *quota) restore(m quota) {
+	*q = m
+}
+
+// entryPath는 압축 파일 안의 이름 name을 unpackPath 아래의 경로로 바꾸고 파일 수를 셉니다.
+// 이름이 절대 경로이거나 unpackPath 밖을 가리키면 UnsafeArchiveError를 반환합니다.
+func (q *quota) entryPath(unpackPath string, name string) (string, string, error) {
+	dst, rel, err := entryPath(unpackPath, name)
+	if err != nil {
+		return "", "", q.unsafe(name, "%v", err)
+	}
+	q.entries++
+	if q.config.MaxEntries > 0 && q.entries > q.config.MaxEntries {
+		return "", "", q.unsafe("", "more than %d entries", q.config.MaxEntries)
+	}
+	return dst, rel, nil
+}
+
+// write는 r의 내용을 dst 파일에 저장하며 풀린 크기와 압축률 제한을 확인합니다.
+// 제한을 넘으면 저장을 중단하고 UnsafeArchiveError를 반환합니다.
+func (q *quota) write(name string, dst string, r io.Reader) (int64, error) {
+	lr := &quotaReader{q: q, name: name, r: r}
+	size, err := writeEntry(dst, lr)
+	if lr.err != nil {
+		os.Remove(dst)
+		return size, lr.err
+	}
+	return size, err
+}
+
+// quotaReader는 읽은 크기를 quota에 더하는 Reader입니다.
+type quotaReader struct {
+	q    *quota
+	name string
+	r    io.Reader
+	err  error // 제한을 넘었을 때의 에러
+}
+
+func (r *quotaReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.r.Read(p)
+	r.q.total += int64(n)
+	if r.err = r.q.check(r.name); r.err != nil {
+		return n, r.err
+	}
+	return n, err
+}
+
+// check는 풀린 크기와 압축률이 제한을 넘었는지 확인합니다.
+func (q *quota) check(name string) error {
+	if q.config.MaxTotalSize > 0 && q.total > q.config.MaxTotalSize {
+		return q.unsafe(name, "uncompressed size exceeds %d bytes", q.config.MaxTotalSize)
+	}
+	if q.config.MaxRatio > 0 && q.archiveSize > 0 && q.total > ratioMinSize &&
+		float64(q.total) > q.config.MaxRatio*float64(q.archiveSize) {
+		return q.unsafe(name, "compression ratio exceeds %g", q.config.MaxRatio)
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package downloader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// archiveFile은 테스트 압축 파일에 넣을 파일입니다.
+type archiveFile struct {
+	name     string
+	body     string
+	typeflag byte   // tar 항목의 타입, 0이면 일반 파일
+	linkname string // tar 링크의 대상
+}
+
+// buildZip은 files를 deflate로 압축한 zip 파일의 내용을 만듭니다.
+func buildZip(t *testing.T, files ...archiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(f.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// buildTar는 files로 PAX 형식의 tar 파일의 내용을 만듭니다.
+func buildTar(t *testing.T, files ...archiveFile) []byte {
+	t.Helper()
+	return buildTarFormat(t, tar.FormatPAX, files...)
+}
+
+// buildTarFormat은 files로 format 형식의 tar 파일의 내용을 만듭니다.
+func buildTarFormat(t *testing.T, format tar.Format, files ...archiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.name, Typeflag: f.typeflag, Linkname: f.linkname, Mode: 0o644, Format: format}
+		if hdr.Typeflag == 0 {
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = int64(len(f.body))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// writeArchive는 dir/name 파일에 data를 쓰고 경로를 반환합니다.
+func writeArchive(t *testing.T, dir string, name string, data []byte) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestEntryPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{name: "01화.ass", want: "01화.ass"},
+		{name: "fonts/a.ttf", want: "fonts/a.ttf"},
+		{name: "./fonts/../01화.ass", want: "01화.ass"},
+		{name: `fonts\a.ttf`, want: "fonts/a.ttf"},
+		{name: "../evil.ass", wantErr: errPathTraversal},
+		{name: "fonts/../../evil.ass", wantErr: errPathTraversal},
+		{name: `..\evil.ass`, wantErr: errPathTraversal},
+		{name: "..", wantErr: errPathTraversal},
+		{name: "/etc/passwd", wantErr: errAbsolutePath},
+		{name: `\evil.ass`, wantErr: errAbsolutePath},
+		{name: `C:\evil.ass`, wantErr: errAbsolutePath},
+		{name: "C:evil.ass", wantErr: errAbsolutePath},
+	}
+
+	unpackPath := filepath.Join("tmp", "unpack")
+	for _, tt := range tests {
+		dst, rel, err := entryPath(unpackPath, tt.name)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("entryPath(%q) error = %v, want %v", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || rel != tt.want || dst != filepath.Join(unpackPath, filepath.FromSlash(tt.want)) {
+			t.Errorf("entryPath(%q) = %q, %q, %v, want %q", tt.name, dst, rel, err, tt.want)
+		}
+	}
+}
+
+func TestUnpackZipSlip(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive string
+		data    func(t *testing.T) []byte
+	}{
+		{
+			name:    "zip parent path",
+			archive: "subtitle.zip",
+			data: func(t *testing.T) []byte {
+				return buildZip(t, archiveFile{name: "01화.ass", body: "ok"}, archiveFile{name: "../evil.ass", body: "evil"})
+			},
+		},
+		{
+			name:    "zip nested parent path",
+			archive: "subtitle.zip",
+			data: func(t *testing.T) []byte {
+				return buildZip(t, archiveFile{name: "fonts/../../evil.ass", body: "evil"})
+			},
+		},
+		{
+			name:    "zip backslash parent path",
+			archive: "subtitle.zip",
+			data: func(t *testing.T) []byte {
+				return buildZip(t, archiveFile{name: `..\evil.ass`, body: "evil"})
+			},
+		},
+		{
+			name:    "zip absolute path",
+			archive: "subtitle.zip",
+			data: func(t *testing.T) []byte {
+				return buildZip(t, archiveFile{name: "/evil.ass", body: "evil"})
+			},
+		},
+		{
+			name:    "tar parent path",
+			archive: "subtitle.tar",
+			data: func(t *testing.T) []byte {
+				return buildTar(t, archiveFile{name: "../evil.ass", body: "evil"})
+			},
+		},
+		{
+			name:    "tar symlink outside",
+			archive: "subtitle.tar",
+			data: func(t *testing.T) []byte {
+				return buildTar(t, archiveFile{name: "fonts/evil.ass", typeflag: tar.TypeSymlink, linkname: "../../evil.ass"})
+			},
+		},
+		{
+			name:    "tar absolute hardlink",
+			archive: "subtitle.tar",
+			data: func(t *testing.T) []byte {
+				return buildTar(t, archiveFile{name: "evil.ass", typeflag: tar.TypeLink, linkname: "/etc/passwd"})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := writeArchive(t, dir, tt.archive, tt.data(t))
+			unpackPath := filepath.Join(dir, "out", "files")
+
+			_, err := NewUnpacker(UnpackerConfig{}).Unpack(archivePath, unpackPath)
+			var unsafeErr *UnsafeArchiveError
+			if !errors.As(err, &unsafeErr) {
+				t.Fatalf("Unpack() error = %v, want *UnsafeArchiveError", err)
+			}
+			if unsafeErr.Archive != tt.archive {
+				t.Errorf("Archive = %q, want %q", unsafeErr.Archive, tt.archive)
+			}
+			for _, p := range []string{filepath.Join(dir, "evil.ass"), filepath.Join(dir, "out", "evil.ass")} {
+				if _, err := os.Stat(p); !os.IsNotExist(err) {
+					t.Errorf("file was written outside unpackPath: %s", p)
+				}
+			}
+		})
+	}
+}
+
+func TestUnpackLimits(t *testing.T) {
+	subtitle := strings.Repeat("Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,안녕하세요\n", 10)
+
+	tests := []struct {
+		name       string
+		archive    string
+		data       func(t *testing.T) []byte
+		config     UnpackerConfig
+		wantReason string // 비어 있으면 성공
+	}{
+		{
+			name:    "within limits",
+			archive: "subtitle.zip",
+			data: func(t *testing.T) []byte {
+				return buildZip(t, archiveFile{name: "01화.ass", body: subtitle}, archiveFile{name: "02화.ass", body: subtitle})
+			},
+			config: UnpackerConfig{MaxTotalSize: int64(2 * len(subtitle)), MaxEntries: 2},
+		},
+		{
+			name:    "total size",
+			archive: "subtitle.zip",
+			data: func(t *testing.T) []byte {
+				return buildZip(t, archiveFile{name: "01화.ass", body: subtitle}, archiveFile{name: "02화.ass", body: subtitle})
+			},
+			config:     UnpackerConfig{MaxTotalSize: int64(len(subtitle)) + 10},
+			wantReason: "uncompressed size exceeds",
+		},
+		{
+			name:    "total size with tar links",
+			archive: "subtitle.tar",
+			data: func(t *testing.T) []byte {
+				return buildTar(t,
+					archiveFile{name: "01화.ass", body: subtitle},
+					archiveFile{name: "02화.ass", typeflag: tar.TypeSymlink, linkname: "01화.ass"},
+				)
+			},
+			config:     UnpackerConfig{MaxTotalSize: int64(len(subtitle)) + 10},
+			wantReason: "uncompressed size exceeds",
+		},
+		{
+			name:    "entries",
+			archive: "subtitle.zip",
+			data: func(t *testing.T) []byte {
+				var files []archiveFile
+				for _, name := range []string{"a.ass", "b.ass", "c.ass", "d.ass"} {
+					files = append(files, archiveFile{name: name, body: "x"})
+				}
+				return buildZip(t, files...)
+			},
+			config:     UnpackerConfig{MaxEntries: 3},
+			wantReason: "more than 3 entries",
+		},
+		{
+			name:    "compression ratio",
+			archive: "bomb.zip",
+			data: func(t *testing.T) []byte {
+				return buildZip(t, archiveFile{name: "bomb.ass", body: strings.Repeat("\x00", 4<<20)})
+			},
+			config:     UnpackerConfig{MaxRatio: 200},
+			wantReason: "compression ratio exceeds 200",
+		},
+		{
+			name:    "small text is not checked for ratio",
+			archive: "subtitle.zip",
+			data: func(t *testing.T) []byte {
+				return buildZip(t, archiveFile{name: "01화.ass", body: strings.Repeat(" ", 512<<10)})
+			},
+			config: UnpackerConfig{MaxRatio: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := writeArchive(t, dir, tt.archive, tt.data(t))
+			unpackPath := filepath.Join(dir, "files")
+
+			manifest, err := NewUnpacker(tt.config).Unpack(archivePath, unpackPath)
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Fatalf("Unpack() error = %v", err)
+				}
+				for _, entry := range manifest.Entries {
+					if _, err := os.Stat(filepath.Join(unpackPath, filepath.FromSlash(entry.Path))); err != nil {
+						t.Errorf("unpacked file %s: %v", entry.Path, err)
+					}
+				}
+				return
+			}
+
+			var unsafeErr *UnsafeArchiveError
+			if !errors.As(err, &unsafeErr) || !strings.Contains(unsafeErr.Reason, tt.wantReason) {
+				t.Fatalf("Unpack() error = %v, want %q", err, tt.wantReason)
+			}
+			// 제한을 넘어 중단한 파일은 지웁니다.
+			if unsafeErr.Entry != "" {
+				if _, err := os.Stat(filepath.Join(unpackPath, filepath.FromSlash(unsafeErr.Entry))); !os.IsNotExist(err) {
+					t.Errorf("partially written %s was not removed: %v", unsafeErr.Entry, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package downloader
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+
+	"github.com/bodgit/sevenzip"
+)
+
+// unpackSevenZ은 7z 파일을 풉니다. LZMA, LZMA2 등으로 헤더까지 압축된 파일도 풀 수 있습니다.
+// 암호가 걸린 파일은 passwords를 차례로 시도합니다.
+func unpackSevenZ(filePath string, unpackPath string, q *quota, passwords []string) ([]ManifestEntry, error) {
+	return tryPasswords(q, passwords, func(password string) ([]ManifestEntry, error) {
+		return extractSevenZ(filePath, unpackPath, q, password)
+	})
+}
+
+// extractSevenZ은 password로 7z 파일을 풉니다.
+// 비밀번호가 없거나 틀려서 풀 수 없으면 ErrPasswordRequired를 감싼 에러를 반환합니다.
+func extractSevenZ(filePath string, unpackPath string, q *quota, password string) ([]ManifestEntry, error) {
+	r, err := sevenzip.OpenReaderWithPassword(filePath, password)
+	if err != nil {
+		return nil, sevenZError(err)
+	}
+	defer r.Close()
+
+	var entries []ManifestEntry
+	for _, f := range r.File {
+		dst, rel, err := q.entryPath(unpackPath, f.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		mode := f.FileInfo().Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(dst, os.ModePerm); err != nil {
+				return nil, fmt.Errorf("failed to create directory: %w", err)
+			}
+		case mode.IsRegular():
+			size, err := extractSevenZFile(q, f, dst)
+			if err != nil {
+				return nil, fmt.Errorf("failed to extract %s: %w", f.Name, sevenZError(err))
+			}
+			entries = append(entries, ManifestEntry{
+				Path:     rel,
+				Size:     size,
+				Modified: f.Modified,
+			})
+		default:
+			// 심볼릭 링크 등은 풀지 않습니다.
+			log.Printf("Skip 7z entry %s (mode %s)\n", f.Name, mode)
+		}
+	}
+	return entries, nil
+}
+
+// errSevenZChecksum은 풀린 파일의 CRC-32가 헤더에 기록된 값과 다를 때의 에러입니다.
+var errSevenZChecksum = errors.New("crc32 mismatch")
+
+// extractSevenZFile은 7z 파일 안의 f를 dst에 저장하고 CRC-32를 확인합니다.
+func extractSevenZFile(q *quota, f *sevenzip.File, dst string) (int64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	hash := crc32.NewIEEE()
+	size, err := q.write(f.Name, dst, io.TeeReader(rc, hash))
+	if err != nil {
+		return size, err
+	}
+	if f.CRC32 != 0 && hash.Sum32() != f.CRC32 {
+		return size, errSevenZChecksum
+	}
+	return size, nil
+}
+
+// sevenZError는 비밀번호 때문에 발생한 sevenzip 에러를 ErrPasswordRequired로 감쌉니다.
+// 틀린 비밀번호로 푼 내용은 디코딩 에러가 발생하거나, 압축하지 않고 암호화만 한 파일이면 CRC-32가 맞지 않습니다.
+// 암호화 여부를 알 수 없는 CRC-32 에러도 다른 비밀번호를 시도합니다.
+func sevenZError(err error) error {
+	var readErr *sevenzip.ReadError
+	if (errors.As(err, &readErr) && readErr.Encrypted) || errors.Is(err, errSevenZChecksum) {
+		return fmt.Errorf("%w: %v", ErrPasswordRequired, err)
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package downloader
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUnpackSevenZ(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		passwords []string
+		wantErr   error
+	}{
+		{name: "lzma", file: "lzma.7z"},
+		{name: "lzma2", file: "lzma2.7z"},
+		{
+			// 헤더도 LZMA로 압축되어 있습니다.
+			name: "compressed header",
+			file: "header.7z",
+		},
+		{
+			name:      "password",
+			file:      "password.7z",
+			passwords: []string{"wrong", testdataPassword},
+		},
+		{
+			name:      "wrong password",
+			file:      "password.7z",
+			passwords: []string{"wrong", "password"},
+			wantErr:   ErrPasswordRequired,
+		},
+		{name: "missing password", file: "password.7z", wantErr: ErrPasswordRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, files, err := unpackTestdata(t, tt.passwords, tt.file)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Unpack() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unpack() error = %v", err)
+			}
+			if manifest.PackType != SevenZ {
+				t.Errorf("PackType = %s, want %s", manifest.PackType, SevenZ)
+			}
+			if !reflect.DeepEqual(files, testdataFiles) {
+				t.Errorf("files = %q, want %q", files, testdataFiles)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ErrNotResolvable은 하나의 요청으로 받을 수 없는 URL(폴더 등)을 Resolve할 때의 에러입니다.
+var ErrNotResolvable = errors.New("url cannot be resolved to a single request")
+
+// Source는 다운로드 호스트 하나를 지원합니다.
+type Source interface {
+	// Type은 Source가 처리하는 다운로드 URL 타입입니다.
+	Type() DownloadURLType
+	// Match는 url이 이 Source로 받을 수 있는 다운로드 URL인지 판별합니다.
+	Match(url string) bool
+	// Resolve는 url을 파일을 바로 받을 수 있는 요청으로 바꿉니다. 요청은 ctx가 취소되면 중단됩니다.
+	Resolve(ctx context.Context, client *http.Client, url string) (*http.Request, error)
+	// Download는 url의 파일을 다운로드하여 dest에 저장합니다.
+	Download(ctx context.Context, d *Downloader, url string, dest Dest) ([]*Result, error)
+}
+
+// SourceRegistry는 Source를 등록하고 URL에 맞는 Source를 선택합니다.
+// 등록된 순서대로 Match를 확인합니다.
+type SourceRegistry struct {
+	mu      sync.RWMutex
+	sources []Source
+}
+
+// NewSourceRegistry는 SourceRegistry를 생성합니다.
+func NewSourceRegistry(sources ...Source) *SourceRegistry {
+	return &SourceRegistry{sources: sources}
+}
+
+// DefaultSourceRegistry는 기본 Source가 등록된 SourceRegistry를 생성합니다.
+// DirectSource는 확장자만 보고 판별하므로 마지막에 확인합니다.
+func DefaultSourceRegistry() *SourceRegistry {
+	return NewSourceRegistry(
+		&GoogleDriveFolderSource{},
+		&GoogleDriveSource{},
+		&NaverBlogSource{},
+		&TistorySource{},
+		&DropboxSource{},
+		&OneDriveSource{},
+		&MediaFireSource{},
+		&MegaSource{},
+		&DirectSource{},
+	)
+}
+
+// defaultSources는 Source가 지정되지 않은 ParserImpl이 사용하는 SourceRegistry입니다.
+var defaultSources = DefaultSourceRegistry()
+
+// Register는 Source를 등록합니다.
+// 나중에 등록한 Source가 먼저 확인되므로 기본 Source를 덮어쓸 수 있습니다.
+func (r *SourceRegistry) Register(source Source) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sources = append([]Source{source}, r.sources...)
+}
+
+// Source는 url을 처리할 Source를 반환합니다. 맞는 Source가 없으면 nil을 반환합니다.
+func (r *SourceRegistry) Source(url string) Source {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, source := range r.sources {
+		if source.Match(url) {
+			return source
+		}
+	}
+	return nil
+}
+
+// newRequest는 브라우저 User-Agent를 설정한 GET 요청을 생성합니다.
+func newRequest(ctx context.Context, reqURL string, referer string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if referer != "" {
+		req.Header.Set("Referer", referer)
+	}
+	return req, nil
+}
+
+// hostOf는 url의 호스트를 소문자로 반환합니다.
+func hostOf(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsedURL.Hostname())
+}
+
+// downloadResolved는 source로 url을 Resolve한 요청을 보내 응답을 저장합니다.
+// 파일 이름은 Content-Disposition 헤더, fallbackName, 최종 응답 URL 순서로 찾습니다.
+func (d *Downloader) downloadResolved(ctx context.Context, source Source, fileUrl string, dest Dest, fallbackName string) ([]*Result, error) {
+	req, err := source.Resolve(ctx, d.HTTPClient, fileUrl)
+	if err != nil {
+		return nil, err
+	}
+	result, err := d.downloadRequest(fileUrl, req, dest, fallbackName, expectation{})
+	if err != nil {
+		return nil, err
+	}
+	return []*Result{result}, nil
+}
+
+// GoogleDriveSource는 구글 드라이브 파일 링크를 다운로드합니다.
+type GoogleDriveSource struct{}
+
+// Type은 GoogleDriveURL을 반환합니다.
+func (s *GoogleDriveSource) Type() DownloadURLType { return GoogleDriveURL }
+
+// Match는 url이 구글 드라이브 링크인지 판별합니다.
+func (s *GoogleDriveSource) Match(url string) bool {
+	return hostOf(url) == "drive.google.com"
+}
+
+// Resolve는 구글 드라이브 웹 다운로드 요청을 반환합니다.
+// 용량이 큰 파일은 확인 페이지가 올 수 있으므로 Download는 이 요청을 사용하지 않습니다.
+func (s *GoogleDriveSource) Resolve(ctx context.Context, client *http.Client, fileUrl string) (*http.Request, error) {
+	fileID, err := (&ParserImpl{}).ParseGoogleDriveURL(fileUrl)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{"export": {"download"}, "id": {fileID}}
+	return newRequest(ctx, DefaultGDriveWebBaseURL+"/uc?"+query.Encode(), "")
+}
+
+// Download는 Drive API 또는 웹 다운로드로 파일을 받습니다.
+func (s *GoogleDriveSource) Download(ctx context.Context, d *Downloader, fileUrl string, dest Dest) ([]*Result, error) {
+	fileID, err := d.Parser.ParseGoogleDriveURL(fileUrl)
+	if err != nil {
+		return nil, err
+	}
+	result, err := d.downloadGoogleDrive(ctx, fileID, dest)
+	if err != nil {
+		return nil, err
+	}
+	return []*Result{result}, nil
+}
+
+// GoogleDriveFolderSource는 구글 드라이브 폴더 링크의 모든 파일을 다운로드합니다.
+type GoogleDriveFolderSource struct{}
+
+// Type은 GoogleDriveFolderURL을 반환합니다.
+func (s *GoogleDriveFolderSource) Type() DownloadURLType { return GoogleDriveFolderURL }
+
+// Match는 url이 구글 드라이브 폴더 링크인지 판별합니다.
+func (s *GoogleDriveFolderSource) Match(url string) bool {
+	if hostOf(url) != "drive.google.com" {
+		return false
+	}
+	_, err := (&ParserImpl{}).ParseGoogleDriveFolderURL(url)
+	return err == nil
+}
+
+// Resolve는 폴더를 하나의 요청으로 받을 수 없으므로 ErrNotResolvable을 반환합니다.
+func (s *GoogleDriveFolderSource) Resolve(ctx context.Context, client *http.Client, url string) (*http.Request, error) {
+	return nil, ErrNotResolvable
+}
+
+// Download는 Drive API로 폴더를 재귀적으로 조회하여 모든 파일을 받습니다.
+func (s *GoogleDriveFolderSource) Download(ctx context.Context, d *Downloader, fileUrl string, dest Dest) ([]*Result, error) {
+	folderID, err := d.Parser.ParseGoogleDriveFolderURL(fileUrl)
+	if err != nil {
+		return nil, err
+	}
+	if d.GDriveClient == nil {
+		// 폴더 목록은 Drive API로만 조회할 수 있습니다.
+		return nil, errors.New("google drive folder download requires an api key")
+	}
+	return d.downloadGoogleDriveFolder(ctx, folderID, dest)
+}
+
+// NaverBlogSource는 네이버 블로그 첨부 파일을 다운로드합니다.
+type NaverBlogSource struct{}
+
+// Type은 NaverBlogURL을 반환합니다.
+func (s *NaverBlogSource) Type() DownloadURLType { return NaverBlogURL }
+
+// Match는 url이 네이버 블로그 첨부 파일 링크인지 판별합니다.
+func (s *NaverBlogSource) Match(url string) bool {
+	return hostOf(url) == "download.blog.naver.com"
+}
+
+// Resolve는 블로그의 Referer를 설정한 요청을 반환합니다.
+// 네이버는 블로그에서 연 요청만 허용합니다.
+func (s *NaverBlogSource) Resolve(ctx context.Context, client *http.Client, url string) (*http.Request, error) {
+	return newRequest(ctx, url, "https://blog.naver.com/")
+}
+
+// Download는 첨부 파일을 받습니다.
+func (s *NaverBlogSource) Download(ctx context.Context, d *Downloader, url string, dest Dest) ([]*Result, error) {
+	return d.downloadResolved(ctx, s, url, dest, fileNameFromURL(url))
+}
+
+// TistorySource는 티스토리 첨부 파일을 다운로드합니다.
+type TistorySource struct{}
+
+// Type은 TistoryURL을 반환합니다.
+func (s *TistorySource) Type() DownloadURLType { return TistoryURL }
+
+// Match는 url이 티스토리 첨부 파일 링크인지 판별합니다.
+func (s *TistorySource) Match(url string) bool {
+	return IsTistoryAttachmentURL(url)
+}
+
+// Resolve는 url을 그대로 요청합니다.
+func (s *TistorySource) Resolve(ctx context.Context, client *http.Client, url string) (*http.Request, error) {
+	return newRequest(ctx, url, "")
+}
+
+// Download는 첨부 파일을 받습니다. 파일 이름은 kakaocdn URL의 knm 파라미터를 사용합니다.
+func (s *TistorySource) Download(ctx context.Context, d *Downloader, url string, dest Dest) ([]*Result, error) {
+	return d.downloadResolved(ctx, s, url, dest, TistoryFileName(url))
+}
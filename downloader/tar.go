@@ -0,0 +1,250 @@
+package downloader
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+)
+
+// decompressor는 압축된 스트림을 푸는 Reader를 만듭니다.
+type decompressor func(r io.Reader) (io.Reader, error)
+
+// gunzip은 gzip 스트림을 푸는 Reader를 만듭니다.
+func gunzip(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+// unxz는 xz 스트림을 푸는 Reader를 만듭니다.
+func unxz(r io.Reader) (io.Reader, error) {
+	return xz.NewReader(r)
+}
+
+// bunzip2는 bzip2 스트림을 푸는 Reader를 만듭니다.
+func bunzip2(r io.Reader) (io.Reader, error) {
+	return bzip2.NewReader(r), nil
+}
+
+// unpackTar은 tar 파일을 풉니다.
+func unpackTar(filePath string, unpackPath string, q *quota) ([]ManifestEntry, error) {
+	return unpackCompressedTar(filePath, unpackPath, q, nil)
+}
+
+// unpackTarGz은 tar.gz 파일을 풉니다.
+func unpackTarGz(filePath string, unpackPath string, q *quota) ([]ManifestEntry, error) {
+	return unpackCompressedTar(filePath, unpackPath, q, gunzip)
+}
+
+// unpackTarXz은 tar.xz 파일을 풉니다.
+func unpackTarXz(filePath string, unpackPath string, q *quota) ([]ManifestEntry, error) {
+	return unpackCompressedTar(filePath, unpackPath, q, unxz)
+}
+
+// unpackTarBz2은 tar.bz2 파일을 풉니다.
+func unpackTarBz2(filePath string, unpackPath string, q *quota) ([]ManifestEntry, error) {
+	return unpackCompressedTar(filePath, unpackPath, q, bunzip2)
+}
+
+// unpackGzip은 압축된 파일 하나(예: 01화.ass.gz)를 풉니다.
+func unpackGzip(filePath string, unpackPath string, q *quota) ([]ManifestEntry, error) {
+	return unpackCompressedFile(filePath, unpackPath, q, gunzip)
+}
+
+// unpackXz은 압축된 파일 하나(예: 01화.ass.xz)를 풉니다.
+func unpackXz(filePath string, unpackPath string, q *quota) ([]ManifestEntry, error) {
+	return unpackCompressedFile(filePath, unpackPath, q, unxz)
+}
+
+// unpackBzip2은 압축된 파일 하나(예: 01화.ass.bz2)를 풉니다.
+func unpackBzip2(filePath string, unpackPath string, q *quota) ([]ManifestEntry, error) {
+	return unpackCompressedFile(filePath, unpackPath, q, bunzip2)
+}
+
+// openDecompressed는 filePath를 열고 decompress로 푼 스트림을 반환합니다.
+// decompress가 nil이면 파일을 그대로 읽습니다.
+func openDecompressed(filePath string, decompress decompressor) (io.Reader, io.Closer, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if decompress == nil {
+		return f, f, nil
+	}
+	r, err := decompress(bufio.NewReader(f))
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("failed to open compressed stream: %w", err)
+	}
+	return r, f, nil
+}
+
+// unpackCompressedFile은 압축된 파일 하나를 풀어 확장자를 뺀 이름으로 저장합니다.
+func unpackCompressedFile(filePath string, unpackPath string, q *quota, decompress decompressor) ([]ManifestEntry, error) {
+	r, closer, err := openDecompressed(filePath, decompress)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	base := filepath.Base(filePath)
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	dst, rel, err := q.entryPath(unpackPath, name)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := q.write(name, dst, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %w", name, err)
+	}
+	return []ManifestEntry{{Path: rel, Size: size}}, nil
+}
+
+// tarLink는 대상 파일을 다 푼 뒤에 처리할 tar의 심볼릭 링크, 하드 링크입니다.
+type tarLink struct {
+	dst    string // 링크를 만들 경로
+	rel    string // 링크의 상대 경로
+	target string // 링크 대상의 상대 경로
+	entry  ManifestEntry
+}
+
+// unpackCompressedTar은 decompress로 푼 tar 스트림을 unpackPath에 풉니다.
+// PAX, GNU 형식의 긴 이름은 archive/tar가 처리합니다.
+// 심볼릭 링크와 하드 링크는 링크를 만들지 않고, 압축 파일 안의 대상 파일을 복사합니다.
+// 링크 대상이 압축 파일 밖을 가리키면 UnsafeArchiveError를 반환하고, 압축 파일 안에 없는 대상은 건너뜁니다.
+// 링크로 복사한 크기도 풀린 크기에 포함합니다.
+func unpackCompressedTar(filePath string, unpackPath string, q *quota, decompress decompressor) ([]ManifestEntry, error) {
+	r, closer, err := openDecompressed(filePath, decompress)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	var entries []ManifestEntry
+	var links []tarLink
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar: %w", err)
+		}
+
+		dst, rel, err := q.entryPath(unpackPath, hdr.Name)
+		if err != nil {
+			return nil, err
+		}
+		entry := ManifestEntry{Path: rel, Modified: hdr.ModTime}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dst, os.ModePerm); err != nil {
+				return nil, fmt.Errorf("failed to create directory: %w", err)
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			entry.Size, err = q.write(hdr.Name, dst, tr)
+			if err != nil {
+				return nil, fmt.Errorf("failed to extract %s: %w", hdr.Name, err)
+			}
+			entries = append(entries, entry)
+		case tar.TypeSymlink:
+			// 심볼릭 링크의 대상은 링크가 있는 디렉토리 기준입니다.
+			target, err := linkTarget(path.Dir(rel), hdr.Linkname)
+			if err != nil {
+				return nil, q.unsafe(hdr.Name+" -> "+hdr.Linkname, "symlink %v", err)
+			}
+			links = append(links, tarLink{dst: dst, rel: rel, target: target, entry: entry})
+		case tar.TypeLink:
+			// 하드 링크의 대상은 압축 파일의 루트 기준입니다.
+			target, err := linkTarget("", hdr.Linkname)
+			if err != nil {
+				return nil, q.unsafe(hdr.Name+" -> "+hdr.Linkname, "hardlink %v", err)
+			}
+			links = append(links, tarLink{dst: dst, rel: rel, target: target, entry: entry})
+		default:
+			// 장치 파일, FIFO 등은 풀지 않습니다.
+			log.Printf("Skip tar entry %s (type %c)\n", hdr.Name, hdr.Typeflag)
+		}
+	}
+
+	linked, err := resolveTarLinks(unpackPath, q, links)
+	if err != nil {
+		return nil, err
+	}
+	return append(entries, linked...), nil
+}
+
+// linkTarget은 dir 기준의 링크 대상 name을 압축 파일 루트 기준의 상대 경로로 바꿉니다.
+func linkTarget(dir string, name string) (string, error) {
+	if path.IsAbs(name) {
+		return "", errors.New("target is an absolute path")
+	}
+	target := path.Clean(path.Join(dir, name))
+	if !filepath.IsLocal(filepath.FromSlash(target)) {
+		return "", errors.New("target is outside the archive")
+	}
+	return target, nil
+}
+
+// resolveTarLinks는 링크 대상 파일을 링크 경로에 복사합니다.
+// 링크가 다른 링크를 가리킬 수 있으므로 더 이상 처리할 링크가 없을 때까지 반복합니다.
+func resolveTarLinks(unpackPath string, q *quota, links []tarLink) ([]ManifestEntry, error) {
+	var entries []ManifestEntry
+	for len(links) > 0 {
+		var pending []tarLink
+		for _, link := range links {
+			src := filepath.Join(unpackPath, filepath.FromSlash(link.target))
+			info, err := os.Stat(src)
+			if err != nil || !info.Mode().IsRegular() {
+				pending = append(pending, link)
+				continue
+			}
+			f, err := os.Open(src)
+			if err != nil {
+				return nil, err
+			}
+			link.entry.Size, err = q.write(link.rel, link.dst, f)
+			f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to extract %s: %w", link.rel, err)
+			}
+			entries = append(entries, link.entry)
+		}
+		if len(pending) == len(links) {
+			for _, link := range pending {
+				log.Printf("Skip tar link %s -> %s: target not found\n", link.rel, link.target)
+			}
+			break
+		}
+		links = pending
+	}
+	return entries, nil
+}
+
+// writeEntry는 r의 내용을 dst 파일에 저장합니다.
+func writeEntry(dst string, r io.Reader) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return 0, err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	size, err := io.Copy(out, r)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return size, err
+}
@@ -0,0 +1,292 @@
+package downloader
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+)
+
+// gzipData는 data를 gzip으로 압축합니다.
+func gzipData(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// xzData는 data를 xz로 압축합니다.
+func xzData(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw, err := xz.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// readTestdata는 testdata의 name 파일을 읽습니다.
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// unpackFiles는 archive 이름으로 data를 풀고 풀린 파일의 경로별 내용을 반환합니다.
+// 링크는 만들지 않으므로 풀린 파일이 모두 일반 파일인지도 확인합니다.
+func unpackFiles(t *testing.T, archive string, data []byte) (*Manifest, map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	archivePath := writeArchive(t, dir, archive, data)
+	unpackPath := filepath.Join(dir, "files")
+
+	manifest, err := NewUnpacker(UnpackerConfig{}).Unpack(archivePath, unpackPath)
+	if err != nil {
+		t.Fatalf("Unpack() error = %v", err)
+	}
+	files := make(map[string]string)
+	for _, entry := range manifest.Entries {
+		p := filepath.Join(unpackPath, filepath.FromSlash(entry.Path))
+		info, err := os.Lstat(p)
+		if err != nil {
+			t.Fatalf("unpacked file %s: %v", entry.Path, err)
+		}
+		if !info.Mode().IsRegular() {
+			t.Errorf("unpacked file %s is not a regular file: %s", entry.Path, info.Mode())
+		}
+		body, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(body)) != entry.Size {
+			t.Errorf("%s Size = %d, want %d", entry.Path, entry.Size, len(body))
+		}
+		files[entry.Path] = string(body)
+	}
+	return manifest, files
+}
+
+func TestUnpackTarLongNames(t *testing.T) {
+	longDir := "fonts/" + strings.Repeat("long-directory-name/", 6)
+	tests := []struct {
+		name   string
+		format tar.Format
+		file   string
+	}{
+		{name: "pax", format: tar.FormatPAX, file: longDir + strings.Repeat("긴이름", 20) + ".ttf"},
+		{name: "gnu", format: tar.FormatGNU, file: longDir + strings.Repeat("long-name-", 12) + ".ttf"},
+		// 100 byte를 넘는 이름은 prefix 필드에 나눠 기록합니다.
+		{name: "ustar prefix", format: tar.FormatUSTAR, file: longDir + "a.ttf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.file) <= 100 {
+				t.Fatalf("name %q is not longer than the ustar name field", tt.file)
+			}
+			_, files := unpackFiles(t, "fonts.tar", buildTarFormat(t, tt.format,
+				archiveFile{name: "01.ass", body: "subtitle"},
+				archiveFile{name: tt.file, body: "font"},
+			))
+			want := map[string]string{"01.ass": "subtitle", tt.file: "font"}
+			if !reflect.DeepEqual(files, want) {
+				t.Errorf("files = %q, want %q", files, want)
+			}
+		})
+	}
+}
+
+func TestUnpackTarLinks(t *testing.T) {
+	manifest, files := unpackFiles(t, "subtitle.tar", buildTar(t,
+		archiveFile{name: "01화.ass", body: "subtitle"},
+		archiveFile{name: "fonts/a.ttf", body: "font"},
+		// 심볼릭 링크의 대상은 링크가 있는 디렉토리 기준입니다.
+		archiveFile{name: "fonts/b.ttf", typeflag: tar.TypeSymlink, linkname: "a.ttf"},
+		archiveFile{name: "fonts/c.ass", typeflag: tar.TypeSymlink, linkname: "../01화.ass"},
+		// 하드 링크의 대상은 압축 파일의 루트 기준입니다.
+		archiveFile{name: "copy.ass", typeflag: tar.TypeLink, linkname: "01화.ass"},
+		// 링크를 가리키는 링크
+		archiveFile{name: "chain.ttf", typeflag: tar.TypeSymlink, linkname: "fonts/b.ttf"},
+		// 대상이 링크보다 뒤에 있는 링크
+		archiveFile{name: "early.ass", typeflag: tar.TypeLink, linkname: "late.ass"},
+		archiveFile{name: "late.ass", body: "late"},
+		// 대상이 없거나 파일이 아닌 링크는 건너뜁니다.
+		archiveFile{name: "missing.ass", typeflag: tar.TypeSymlink, linkname: "nope.ass"},
+		archiveFile{name: "missing-hardlink.ass", typeflag: tar.TypeLink, linkname: "fonts/nope.ass"},
+		archiveFile{name: "fonts-link", typeflag: tar.TypeSymlink, linkname: "fonts"},
+	))
+
+	want := map[string]string{
+		"01화.ass":     "subtitle",
+		"fonts/a.ttf": "font",
+		"fonts/b.ttf": "font",
+		"fonts/c.ass": "subtitle",
+		"copy.ass":    "subtitle",
+		"chain.ttf":   "font",
+		"early.ass":   "late",
+		"late.ass":    "late",
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %q, want %q", files, want)
+	}
+	if len(manifest.Entries) != len(want) {
+		t.Errorf("Entries = %d, want %d", len(manifest.Entries), len(want))
+	}
+}
+
+func TestUnpackTarEscapingLinks(t *testing.T) {
+	tests := []struct {
+		name       string
+		link       archiveFile
+		wantReason string
+	}{
+		{
+			name:       "symlink parent",
+			link:       archiveFile{name: "fonts/evil.ass", typeflag: tar.TypeSymlink, linkname: "../../evil.ass"},
+			wantReason: "symlink target is outside the archive",
+		},
+		{
+			name:       "symlink absolute",
+			link:       archiveFile{name: "evil.ass", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"},
+			wantReason: "symlink target is an absolute path",
+		},
+		{
+			// 하드 링크는 링크가 있는 디렉토리가 아닌 루트 기준이므로 밖을 가리킵니다.
+			name:       "hardlink parent",
+			link:       archiveFile{name: "fonts/evil.ass", typeflag: tar.TypeLink, linkname: "../01화.ass"},
+			wantReason: "hardlink target is outside the archive",
+		},
+		{
+			name:       "hardlink absolute",
+			link:       archiveFile{name: "evil.ass", typeflag: tar.TypeLink, linkname: "/etc/passwd"},
+			wantReason: "hardlink target is an absolute path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := writeArchive(t, dir, "subtitle.tar", buildTar(t,
+				archiveFile{name: "01화.ass", body: "subtitle"},
+				tt.link,
+			))
+
+			_, err := NewUnpacker(UnpackerConfig{}).Unpack(archivePath, filepath.Join(dir, "files"))
+			var unsafeErr *UnsafeArchiveError
+			if !errors.As(err, &unsafeErr) || unsafeErr.Reason != tt.wantReason {
+				t.Fatalf("Unpack() error = %v, want %q", err, tt.wantReason)
+			}
+			if want := tt.link.name + " -> " + tt.link.linkname; unsafeErr.Entry != want {
+				t.Errorf("Entry = %q, want %q", unsafeErr.Entry, want)
+			}
+		})
+	}
+}
+
+func TestUnpackCompressedStreams(t *testing.T) {
+	subtitle := "[Script Info]\nTitle: 01\n"
+	tarball := buildTar(t,
+		archiveFile{name: "01화.ass", body: subtitle},
+		archiveFile{name: "fonts/a.ttf", body: "font"},
+	)
+	tarFiles := map[string]string{"01화.ass": subtitle, "fonts/a.ttf": "font"}
+
+	tests := []struct {
+		archive  string
+		data     func(t *testing.T) []byte
+		wantType PackType
+		want     map[string]string
+	}{
+		{
+			archive:  "01화.ass.gz",
+			data:     func(t *testing.T) []byte { return gzipData(t, []byte(subtitle)) },
+			wantType: Gzip,
+			want:     map[string]string{"01화.ass": subtitle},
+		},
+		{
+			archive:  "01화.ass.xz",
+			data:     func(t *testing.T) []byte { return xzData(t, []byte(subtitle)) },
+			wantType: Xz,
+			want:     map[string]string{"01화.ass": subtitle},
+		},
+		{
+			archive:  "subtitle.ass.bz2",
+			data:     func(t *testing.T) []byte { return readTestdata(t, "subtitle.ass.bz2") },
+			wantType: Bzip2,
+			want:     map[string]string{"subtitle.ass": subtitle},
+		},
+		{
+			archive:  "subtitle.tar.gz",
+			data:     func(t *testing.T) []byte { return gzipData(t, tarball) },
+			wantType: TarGz,
+			want:     tarFiles,
+		},
+		{
+			archive:  "subtitle.tgz",
+			data:     func(t *testing.T) []byte { return gzipData(t, tarball) },
+			wantType: TarGz,
+			want:     tarFiles,
+		},
+		{
+			archive:  "subtitle.tar.xz",
+			data:     func(t *testing.T) []byte { return xzData(t, tarball) },
+			wantType: TarXz,
+			want:     tarFiles,
+		},
+		{
+			// testdata/subtitle.tar.bz2는 archive/tar로 만든 tarball(01화.ass, fonts/a.ttf)을 bzip2로 압축한 파일입니다.
+			archive:  "subtitle.tar.bz2",
+			data:     func(t *testing.T) []byte { return readTestdata(t, "subtitle.tar.bz2") },
+			wantType: TarBz2,
+			want:     tarFiles,
+		},
+		{
+			// 확장자가 .gz여도 압축을 푼 앞부분에 tar 매직이 있으면 tar로 풉니다.
+			archive:  "subtitle.gz",
+			data:     func(t *testing.T) []byte { return gzipData(t, tarball) },
+			wantType: TarGz,
+			want:     tarFiles,
+		},
+		{
+			archive:  "subtitle.bz2",
+			data:     func(t *testing.T) []byte { return readTestdata(t, "subtitle.tar.bz2") },
+			wantType: TarBz2,
+			want:     tarFiles,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.archive, func(t *testing.T) {
+			manifest, files := unpackFiles(t, tt.archive, tt.data(t))
+			if manifest.PackType != tt.wantType {
+				t.Errorf("PackType = %s, want %s", manifest.PackType, tt.wantType)
+			}
+			if !reflect.DeepEqual(files, tt.want) {
+				t.Errorf("files = %q, want %q", files, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,549 @@
+//go:build ignore
+
+// gen_archives는 rar, 7z 압축 해제 테스트에 사용하는 testdata 파일을 만듭니다.
+// 테스트 환경에 rar, 7z 도구가 없어도 같은 파일을 다시 만들 수 있도록 형식을 직접 기록합니다.
+// rar 파일은 압축하지 않고(store) 저장하며, 7z 파일은 LZMA, LZMA2로 압축합니다.
+//
+//	cd downloader && go run testdata/gen_archives.go
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/crc32"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf16"
+
+	"github.com/ulikunitz/xz/lzma"
+)
+
+const (
+	subtitle = "[Script Info]\nTitle: 01\n"
+	font     = "font"
+	password = "anisub"
+)
+
+// file은 압축 파일에 넣을 파일입니다.
+type file struct {
+	name string
+	data []byte
+}
+
+var (
+	files = []file{
+		{name: "01화.ass", data: []byte(subtitle)},
+		{name: "fonts/a.ttf", data: []byte(font)},
+	}
+	modified = time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func main() {
+	write := func(name string, data []byte) {
+		if err := os.WriteFile(filepath.Join("testdata", name), data, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	write("subtitle.rar", rar4(files, nil))
+	write("password.rar", rar4(files, []byte(password)))
+	part1, part2 := rar4Volumes(files)
+	write("subtitle.part1.rar", part1)
+	write("subtitle.part2.rar", part2)
+
+	write("subtitle5.rar", rar5(files, nil))
+	write("password5.rar", rar5(files, []byte(password)))
+	part1, part2 = rar5Volumes(files)
+	write("subtitle5.part1.rar", part1)
+	write("subtitle5.part2.rar", part2)
+
+	write("lzma.7z", sevenZ(files, lzmaCoder, false, false))
+	write("lzma2.7z", sevenZ(files, lzma2Coder, false, false))
+	write("header.7z", sevenZ(files, lzma2Coder, true, false))
+	write("password.7z", sevenZ(files, lzma2Coder, true, true))
+}
+
+// fixed는 n byte의 고정된 salt나 IV를 만듭니다. 같은 파일이 만들어지도록 난수를 사용하지 않습니다.
+func fixed(n int, seed byte) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = seed + byte(i)
+	}
+	return b
+}
+
+// encryptCBC는 data를 0으로 채워 블록 크기에 맞춘 뒤 AES-CBC로 암호화합니다.
+func encryptCBC(key, iv, data []byte) []byte {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	padded := make([]byte, (len(data)+aes.BlockSize-1)/aes.BlockSize*aes.BlockSize)
+	copy(padded, data)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(padded, padded)
+	return padded
+}
+
+func le16(v uint16) []byte { return binary.LittleEndian.AppendUint16(nil, v) }
+func le32(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+func le64(v uint64) []byte { return binary.LittleEndian.AppendUint64(nil, v) }
+
+func join(parts ...[]byte) []byte { return bytes.Join(parts, nil) }
+
+// ---- RAR4 ----
+
+const (
+	rar4Signature = "Rar!\x1a\x07\x00"
+
+	rar4BlockArc  = 0x73
+	rar4BlockFile = 0x74
+	rar4BlockEnd  = 0x7b
+
+	rar4HasData       = 0x8000
+	rar4ArcVolume     = 0x0001
+	rar4ArcNewNaming  = 0x0010
+	rar4ArcFirst      = 0x0100
+	rar4SplitBefore   = 0x0001
+	rar4SplitAfter    = 0x0002
+	rar4FileEncrypted = 0x0004
+	rar4FileUnicode   = 0x0200
+	rar4FileSalt      = 0x0400
+	rar4EndNotLast    = 0x0001
+)
+
+// rar4Block은 CRC-16(CRC-32의 하위 16bit)을 기록한 RAR4 블록 헤더를 만듭니다.
+func rar4Block(htype byte, flags uint16, body []byte) []byte {
+	b := join([]byte{0, 0, htype}, le16(flags), le16(uint16(7+len(body))), body)
+	copy(b, le16(uint16(crc32.ChecksumIEEE(b[2:]))))
+	return b
+}
+
+// rar4FileBlock은 파일 헤더와 데이터를 만듭니다. sum은 헤더에 기록할 CRC-32입니다.
+func rar4FileBlock(name string, size int, sum uint32, data []byte, flags uint16, salt []byte) []byte {
+	dos := modified
+	ftime := uint32(dos.Year()-1980)<<25 | uint32(dos.Month())<<21 | uint32(dos.Day())<<16 |
+		uint32(dos.Hour())<<11 | uint32(dos.Minute())<<5 | uint32(dos.Second()/2)
+	body := join(
+		le32(uint32(len(data))), // PACK_SIZE
+		le32(uint32(size)),      // UNP_SIZE
+		[]byte{2},               // HOST_OS: Win32
+		le32(sum),
+		le32(ftime),
+		[]byte{29, 0x30}, // UNP_VER, METHOD: store
+		le16(uint16(len(name))),
+		le32(0x20), // ATTR: archive
+		[]byte(name),
+		salt,
+	)
+	return join(rar4Block(rar4BlockFile, rar4HasData|rar4FileUnicode|flags, body), data)
+}
+
+// rar4Key는 RAR 3.x/4.x의 AES-128 키와 IV를 계산합니다.
+func rar4Key(pass, salt []byte) (key, iv []byte) {
+	var p []byte
+	for _, v := range utf16.Encode([]rune(string(pass))) {
+		p = append(p, byte(v), byte(v>>8))
+	}
+	p = append(p, salt...)
+
+	const rounds = 0x40000
+	h := sha1.New()
+	iv = make([]byte, 16)
+	for i := 0; i < rounds; i++ {
+		h.Write(p)
+		h.Write([]byte{byte(i), byte(i >> 8), byte(i >> 16)})
+		if i%(rounds/16) == 0 {
+			iv[i/(rounds/16)] = h.Sum(nil)[19]
+		}
+	}
+	sum := h.Sum(nil)
+	key = make([]byte, 16)
+	for i := 0; i < 16; i += 4 {
+		key[i], key[i+1], key[i+2], key[i+3] = sum[i+3], sum[i+2], sum[i+1], sum[i]
+	}
+	return key, iv
+}
+
+// rar4는 files를 담은 RAR4 파일을 만듭니다. pass가 있으면 파일마다 암호를 겁니다.
+func rar4(files []file, pass []byte) []byte {
+	out := join([]byte(rar4Signature), rar4Block(rar4BlockArc, 0, make([]byte, 6)))
+	for i, f := range files {
+		data, flags, salt := f.data, uint16(0), []byte(nil)
+		if pass != nil {
+			salt = fixed(8, byte(16*i))
+			key, iv := rar4Key(pass, salt)
+			data = encryptCBC(key, iv, f.data)
+			flags = rar4FileEncrypted | rar4FileSalt
+		}
+		out = join(out, rar4FileBlock(f.name, len(f.data), crc32.ChecksumIEEE(f.data), data, flags, salt))
+	}
+	return join(out, rar4Block(rar4BlockEnd, 0, nil))
+}
+
+// rar4Volumes는 files의 첫 파일을 두 볼륨에 나눠 담은 RAR4 분할 압축 파일(새 방식 이름)을 만듭니다.
+func rar4Volumes(files []file) (part1, part2 []byte) {
+	first := files[0]
+	half := len(first.data) / 2
+	sum := crc32.ChecksumIEEE(first.data)
+
+	part1 = join(
+		[]byte(rar4Signature),
+		rar4Block(rar4BlockArc, rar4ArcVolume|rar4ArcNewNaming|rar4ArcFirst, make([]byte, 6)),
+		rar4FileBlock(first.name, len(first.data), crc32.ChecksumIEEE(first.data[:half]), first.data[:half], rar4SplitAfter, nil),
+		rar4Block(rar4BlockEnd, rar4EndNotLast, nil),
+	)
+	part2 = join(
+		[]byte(rar4Signature),
+		rar4Block(rar4BlockArc, rar4ArcVolume|rar4ArcNewNaming, make([]byte, 6)),
+		rar4FileBlock(first.name, len(first.data), sum, first.data[half:], rar4SplitBefore, nil),
+	)
+	for _, f := range files[1:] {
+		part2 = join(part2, rar4FileBlock(f.name, len(f.data), crc32.ChecksumIEEE(f.data), f.data, 0, nil))
+	}
+	return part1, join(part2, rar4Block(rar4BlockEnd, 0, nil))
+}
+
+// ---- RAR5 ----
+
+const (
+	rar5Signature = "Rar!\x1a\x07\x01\x00"
+
+	rar5BlockArc  = 1
+	rar5BlockFile = 2
+	rar5BlockEnd  = 5
+
+	rar5HasExtra     = 0x0001
+	rar5HasData      = 0x0002
+	rar5DataNotFirst = 0x0008
+	rar5DataNotLast  = 0x0010
+
+	rar5ArcMultiVolume = 0x0001
+	rar5ArcVolumeNum   = 0x0002
+	rar5FileHasMtime   = 0x0002
+	rar5FileHasCRC32   = 0x0004
+	rar5EndNotLast     = 0x0001
+
+	rar5EncCheck  = 0x0001
+	rar5EncUseMac = 0x0002
+	// rar5KdfCount는 PBKDF2 반복 횟수의 log2입니다.
+	rar5KdfCount = 15
+)
+
+func vint(v uint64) []byte {
+	var b []byte
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// rar5Block은 CRC-32를 기록한 RAR5 블록 헤더를 만듭니다.
+func rar5Block(htype, flags uint64, dataSize int, fields, extra []byte) []byte {
+	if extra != nil {
+		flags |= rar5HasExtra
+	}
+	header := join(vint(htype), vint(flags))
+	if extra != nil {
+		header = join(header, vint(uint64(len(extra))))
+	}
+	if flags&rar5HasData != 0 {
+		header = join(header, vint(uint64(dataSize)))
+	}
+	header = join(header, fields, extra)
+	b := join(vint(uint64(len(header))), header)
+	return join(le32(crc32.ChecksumIEEE(b)), b)
+}
+
+// rar5Keys는 RAR5의 AES-256 키, 체크섬 키, 비밀번호 확인 값을 계산합니다.
+func rar5Keys(pass, salt []byte) (key, hashKey, check []byte) {
+	prf := hmac.New(sha256.New, pass)
+	prf.Write(salt)
+	prf.Write([]byte{0, 0, 0, 1})
+	t := prf.Sum(nil)
+	u := append([]byte(nil), t...)
+
+	keys := make([][]byte, 3)
+	for i, iter := range []int{1<<rar5KdfCount - 1, 16, 16} {
+		for ; iter > 0; iter-- {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range u {
+				t[j] ^= u[j]
+			}
+		}
+		keys[i] = append([]byte(nil), t...)
+	}
+
+	check = make([]byte, 8)
+	for i, v := range keys[2] {
+		check[i%8] ^= v
+	}
+	sum := sha256.Sum256(check)
+	return keys[0], keys[1], append(check, sum[:4]...)
+}
+
+// rar5FileBlock은 파일 헤더와 데이터를 만듭니다. sum은 헤더에 기록할 CRC-32입니다.
+func rar5FileBlock(name string, size int, sum []byte, data []byte, flags uint64, extra []byte) []byte {
+	fields := join(
+		vint(rar5FileHasMtime|rar5FileHasCRC32),
+		vint(uint64(size)),
+		vint(0x20), // attributes: archive
+		le32(uint32(modified.Unix())),
+		sum,
+		vint(0), // compression: store
+		vint(0), // host OS: Windows
+		vint(uint64(len(name))),
+		[]byte(name),
+	)
+	return join(rar5Block(rar5BlockFile, rar5HasData|flags, len(data), fields, extra), data)
+}
+
+// rar5는 files를 담은 RAR5 파일을 만듭니다. pass가 있으면 파일마다 암호를 겁니다.
+func rar5(files []file, pass []byte) []byte {
+	out := join([]byte(rar5Signature), rar5Block(rar5BlockArc, 0, 0, vint(0), nil))
+	for i, f := range files {
+		data, sum, extra := f.data, le32(crc32.ChecksumIEEE(f.data)), []byte(nil)
+		if pass != nil {
+			salt, iv := fixed(16, byte(32*i)), fixed(16, byte(32*i+16))
+			key, hashKey, check := rar5Keys(pass, salt)
+			data = encryptCBC(key, iv, f.data)
+
+			// 체크섬은 HMAC-SHA256으로 바꿔 기록합니다.
+			mac := hmac.New(sha256.New, hashKey)
+			mac.Write(sum)
+			folded := mac.Sum(nil)
+			for j, v := range folded[4:] {
+				folded[j&3] ^= v
+			}
+			sum = folded[:4]
+
+			record := join(vint(1), vint(0), vint(rar5EncCheck|rar5EncUseMac), []byte{rar5KdfCount}, salt, iv, check)
+			extra = join(vint(uint64(len(record))), record)
+		}
+		out = join(out, rar5FileBlock(f.name, len(f.data), sum, data, 0, extra))
+	}
+	return join(out, rar5Block(rar5BlockEnd, 0, 0, vint(0), nil))
+}
+
+// rar5Volumes는 files의 첫 파일을 두 볼륨에 나눠 담은 RAR5 분할 압축 파일을 만듭니다.
+func rar5Volumes(files []file) (part1, part2 []byte) {
+	first := files[0]
+	half := len(first.data) / 2
+
+	part1 = join(
+		[]byte(rar5Signature),
+		rar5Block(rar5BlockArc, 0, 0, vint(rar5ArcMultiVolume), nil),
+		rar5FileBlock(first.name, len(first.data), le32(crc32.ChecksumIEEE(first.data[:half])), first.data[:half], rar5DataNotLast, nil),
+		rar5Block(rar5BlockEnd, 0, 0, vint(rar5EndNotLast), nil),
+	)
+	part2 = join(
+		[]byte(rar5Signature),
+		rar5Block(rar5BlockArc, 0, 0, join(vint(rar5ArcMultiVolume|rar5ArcVolumeNum), vint(1)), nil),
+		rar5FileBlock(first.name, len(first.data), le32(crc32.ChecksumIEEE(first.data)), first.data[half:], rar5DataNotFirst, nil),
+	)
+	for _, f := range files[1:] {
+		part2 = join(part2, rar5FileBlock(f.name, len(f.data), le32(crc32.ChecksumIEEE(f.data)), f.data, 0, nil))
+	}
+	return part1, join(part2, rar5Block(rar5BlockEnd, 0, 0, vint(0), nil))
+}
+
+// ---- 7z ----
+
+const (
+	sevenZSignature = "7z\xbc\xaf\x27\x1c\x00\x04"
+
+	kEnd             = 0x00
+	kHeader          = 0x01
+	kMainStreamsInfo = 0x04
+	kFilesInfo       = 0x05
+	kPackInfo        = 0x06
+	kUnPackInfo      = 0x07
+	kSubStreamsInfo  = 0x08
+	kSize            = 0x09
+	kCRC             = 0x0a
+	kFolder          = 0x0b
+	kCodersUnPackSz  = 0x0c
+	kNumUnPackStream = 0x0d
+	kName            = 0x11
+	kEncodedHeader   = 0x17
+
+	// sevenZCycles는 AES 키를 계산할 때 SHA-256 반복 횟수의 log2입니다.
+	sevenZCycles = 19
+)
+
+// coder는 7z folder의 coder 하나입니다.
+type coder struct {
+	id    []byte
+	props []byte
+}
+
+// folder는 하나의 pack stream을 coders 순서로 풀어 파일 내용을 만드는 7z folder입니다.
+// coders[0]이 pack stream을 읽고, coders[i]는 coders[i-1]의 출력을 읽습니다. unpackSizes는 coder별 출력 크기입니다.
+type folder struct {
+	coders      []coder
+	unpackSizes []int
+	crc         uint32
+	packed      []byte
+}
+
+// compressor는 data를 압축한 스트림과 coder를 반환합니다.
+type compressor func(data []byte) (coder, []byte)
+
+// lzmaCoder는 data를 LZMA로 압축합니다.
+func lzmaCoder(data []byte) (coder, []byte) {
+	var buf bytes.Buffer
+	w, err := lzma.WriterConfig{DictCap: 1 << 16, Size: int64(len(data))}.NewWriter(&buf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		log.Fatal(err)
+	}
+	// .lzma 헤더(properties 1 byte, dictionary size 4 byte, 크기 8 byte) 중 앞의 5 byte가 coder properties입니다.
+	b := buf.Bytes()
+	return coder{id: []byte{0x03, 0x01, 0x01}, props: append([]byte(nil), b[:5]...)}, b[13:]
+}
+
+// lzma2Coder는 data를 LZMA2로 압축합니다.
+func lzma2Coder(data []byte) (coder, []byte) {
+	var buf bytes.Buffer
+	w, err := lzma.Writer2Config{DictCap: 1 << 16}.NewWriter2(&buf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		log.Fatal(err)
+	}
+	// dictionary size = 2 << (8/2 + 11) = 64 KiB
+	return coder{id: []byte{0x21}, props: []byte{8}}, buf.Bytes()
+}
+
+// newFolder는 data를 compress로 압축하고, encrypt이면 AES-256으로 암호화한 folder를 만듭니다.
+func newFolder(data []byte, compress compressor, encrypt bool, ivSeed byte) folder {
+	c, packed := compress(data)
+	f := folder{coders: []coder{c}, unpackSizes: []int{len(data)}, crc: crc32.ChecksumIEEE(data), packed: packed}
+	if encrypt {
+		iv := fixed(16, ivSeed)
+		aes := coder{id: []byte{0x06, 0xf1, 0x07, 0x01}, props: join([]byte{0x40 | sevenZCycles, 0x0f}, iv)}
+		f.coders = append([]coder{aes}, f.coders...)
+		f.unpackSizes = append([]int{len(packed)}, f.unpackSizes...)
+		f.packed = encryptCBC(sevenZKey([]byte(password)), iv, packed)
+	}
+	return f
+}
+
+// sevenZKey는 7zAES의 AES-256 키를 계산합니다. salt는 사용하지 않습니다.
+func sevenZKey(pass []byte) []byte {
+	var p []byte
+	for _, v := range utf16.Encode([]rune(string(pass))) {
+		p = append(p, byte(v), byte(v>>8))
+	}
+	h := sha256.New()
+	for i := uint64(0); i < 1<<sevenZCycles; i++ {
+		h.Write(p)
+		h.Write(le64(i))
+	}
+	return h.Sum(nil)
+}
+
+// number는 7z의 가변 길이 정수를 만듭니다.
+func number(v uint64) []byte {
+	first, mask := byte(0), byte(0x80)
+	n := 0
+	for ; n < 8; n++ {
+		if v < 1<<(7*(n+1)) {
+			first |= byte(v >> (8 * n))
+			break
+		}
+		first |= mask
+		mask >>= 1
+	}
+	b := []byte{first}
+	for i := 0; i < n; i++ {
+		b = append(b, byte(v>>(8*i)))
+	}
+	return b
+}
+
+// streamsInfo는 packPos부터 기록된 folders의 PackInfo, UnPackInfo를 만듭니다.
+// 파일 내용의 CRC-32는 SubStreamsInfo에 기록하므로 folderCRC이면 folder의 CRC-32를 기록합니다.
+func streamsInfo(packPos int, folders []folder, folderCRC bool) []byte {
+	b := join([]byte{kPackInfo}, number(uint64(packPos)), number(uint64(len(folders))), []byte{kSize})
+	for _, f := range folders {
+		b = join(b, number(uint64(len(f.packed))))
+	}
+	b = join(b, []byte{kEnd, kUnPackInfo, kFolder}, number(uint64(len(folders))), []byte{0})
+	for _, f := range folders {
+		b = join(b, number(uint64(len(f.coders))))
+		for _, c := range f.coders {
+			b = join(b, []byte{byte(len(c.id)) | 0x20}, c.id, number(uint64(len(c.props))), c.props)
+		}
+		for i := 1; i < len(f.coders); i++ {
+			// coders[i]의 입력(i)은 coders[i-1]의 출력(i-1)입니다.
+			b = join(b, number(uint64(i)), number(uint64(i-1)))
+		}
+	}
+	b = join(b, []byte{kCodersUnPackSz})
+	for _, f := range folders {
+		for _, size := range f.unpackSizes {
+			b = join(b, number(uint64(size)))
+		}
+	}
+	if folderCRC {
+		b = join(b, []byte{kCRC, 1})
+		for _, f := range folders {
+			b = join(b, le32(f.crc))
+		}
+	}
+	return join(b, []byte{kEnd})
+}
+
+// sevenZ는 files를 파일마다 하나의 folder로 압축한 7z 파일을 만듭니다.
+// encodeHeader이면 헤더도 LZMA로 압축하고, encrypt이면 파일 내용을 암호화합니다.
+func sevenZ(files []file, compress compressor, encodeHeader, encrypt bool) []byte {
+	var folders []folder
+	var packed []byte
+	for i, f := range files {
+		folder := newFolder(f.data, compress, encrypt, byte(16*i))
+		folders = append(folders, folder)
+		packed = join(packed, folder.packed)
+	}
+
+	header := join([]byte{kHeader, kMainStreamsInfo}, streamsInfo(0, folders, false), []byte{kSubStreamsInfo, kNumUnPackStream})
+	for range folders {
+		header = join(header, number(1))
+	}
+	header = join(header, []byte{kCRC, 1})
+	for _, f := range folders {
+		header = join(header, le32(f.crc))
+	}
+	header = join(header, []byte{kEnd, kEnd, kFilesInfo}, number(uint64(len(files))))
+	var names []byte
+	for _, f := range files {
+		for _, v := range utf16.Encode([]rune(f.name)) {
+			names = append(names, le16(v)...)
+		}
+		names = append(names, 0, 0)
+	}
+	header = join(header, []byte{kName}, number(uint64(len(names)+1)), []byte{0}, names, []byte{kEnd, kEnd})
+
+	if encodeHeader {
+		f := newFolder(header, lzmaCoder, false, 0)
+		header = join([]byte{kEncodedHeader}, streamsInfo(len(packed), []folder{f}, true), []byte{kEnd})
+		packed = join(packed, f.packed)
+	}
+
+	start := join(le64(uint64(len(packed))), le64(uint64(len(header))), le32(crc32.ChecksumIEEE(header)))
+	return join([]byte(sevenZSignature), le32(crc32.ChecksumIEEE(start)), start, packed, header)
+}
@@ -0,0 +1,90 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ErrReadTimeout은 응답 본문을 ReadTimeout 동안 한 바이트도 받지 못했을 때의 에러입니다.
+// 멈춘 다운로드는 이 에러로 끝나므로 작업이 다시 시도되어 .part 파일부터 이어서 받습니다.
+var ErrReadTimeout = errors.New("no data received within the read timeout")
+
+// newTransport는 연결, TLS 핸드셰이크, 응답 헤더와 본문 읽기에 타임아웃을 둔 http.RoundTripper를 생성합니다.
+// 전체 다운로드 시간은 파일 크기에 따라 다르므로 제한하지 않고, 데이터가 오지 않는 시간만 제한합니다.
+func newTransport(config Config) http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   config.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = config.ConnectTimeout
+	transport.ResponseHeaderTimeout = config.ResponseHeaderTimeout
+	return &readTimeoutTransport{base: transport, timeout: config.ReadTimeout}
+}
+
+// readTimeoutTransport는 응답 본문을 timeout 동안 읽지 못하면 요청을 취소하는 http.RoundTripper입니다.
+type readTimeoutTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+// RoundTrip은 요청을 보내고 응답 본문을 readTimeoutBody로 감쌉니다.
+func (t *readTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancelCause(req.Context())
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel(nil)
+		return nil, err
+	}
+
+	body := &readTimeoutBody{body: resp.Body, ctx: ctx, cancel: cancel, timeout: t.timeout}
+	body.timer = time.AfterFunc(t.timeout, func() { cancel(ErrReadTimeout) })
+	resp.Body = body
+	return resp, nil
+}
+
+// readTimeoutBody는 데이터를 받을 때마다 타이머를 다시 시작하고,
+// 타이머가 끝나면 요청을 취소하여 멈춘 Read를 ErrReadTimeout으로 끝내는 응답 본문입니다.
+type readTimeoutBody struct {
+	body    io.ReadCloser
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (b *readTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	if err != nil && err != io.EOF && errors.Is(context.Cause(b.ctx), ErrReadTimeout) {
+		return n, ErrReadTimeout
+	}
+	return n, err
+}
+
+func (b *readTimeoutBody) Close() error {
+	b.timer.Stop()
+	err := b.body.Close()
+	b.cancel(nil)
+	return err
+}
+
+// apiKeyTransport는 Drive API 요청에 API 키를 X-Goog-Api-Key 헤더로 붙이는 http.RoundTripper입니다.
+// URL의 key 파라미터와 달리 로그에 남는 URL에 API 키가 드러나지 않습니다.
+type apiKeyTransport struct {
+	key  string
+	base http.RoundTripper
+}
+
+// RoundTrip은 API 키 헤더를 붙인 요청을 보냅니다.
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Goog-Api-Key", t.key)
+	return t.base.RoundTrip(req)
+}
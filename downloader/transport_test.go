@@ -0,0 +1,112 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReadTimeoutKeepsPart(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 응답의 일부만 보내고 멈춥니다.
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("Content-Length", "1000")
+		w.Write(make([]byte, 100))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	d, err := NewDownloader(context.Background(), Config{
+		TempDir:     t.TempDir(),
+		ReadTimeout: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewDownloader() error = %v", err)
+	}
+	dest := newTestDest(t)
+
+	sourceURL := server.URL + "/subtitle.zip"
+	req, err := newRequest(context.Background(), sourceURL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	_, err = d.downloadRequest(sourceURL, req, dest, "", expectation{})
+	if !errors.Is(err, ErrReadTimeout) {
+		t.Fatalf("downloadRequest() error = %v, want ErrReadTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("downloadRequest() took %s", elapsed)
+	}
+
+	// 다음 시도에서 이어서 받을 수 있도록 받은 부분이 남아 있어야 합니다.
+	info, err := os.Stat(d.partPath(sourceURL, dest))
+	if err != nil || info.Size() != 100 {
+		t.Errorf(".part = %v, %v, want 100 bytes", info, err)
+	}
+}
+
+func TestReadTimeoutAllowsSlowProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 전체 시간은 ReadTimeout보다 길지만 데이터는 계속 옵니다.
+		w.Header().Set("Content-Length", "10")
+		for i := 0; i < 10; i++ {
+			w.Write([]byte{'x'})
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	d, err := NewDownloader(context.Background(), Config{
+		TempDir:     t.TempDir(),
+		ReadTimeout: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewDownloader() error = %v", err)
+	}
+	dest := newTestDest(t)
+
+	sourceURL := server.URL + "/subtitle.zip"
+	req, err := newRequest(context.Background(), sourceURL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := d.downloadRequest(sourceURL, req, dest, "", expectation{})
+	if err != nil {
+		t.Fatalf("downloadRequest() error = %v", err)
+	}
+	if result.Size != 10 {
+		t.Errorf("Size = %d, want 10", result.Size)
+	}
+}
+
+func TestDownloadCanceled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		w.Write(make([]byte, 100))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	d := newTestDownloader(t, server)
+	dest := newTestDest(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := d.Download(ctx, server.URL+"/subtitle.zip", dest)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Download() error = %v, want context.DeadlineExceeded", err)
+	}
+}
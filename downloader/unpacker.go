@@ -2,7 +2,10 @@ package downloader
 
 import (
 	"errors"
+	"fmt"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 // PackType은 압축 파일의 타입을 나타냅니다.
@@ -21,95 +24,223 @@ const (
 	TarXz PackType = "tar.xz"
 	// TarBz2은 tar.bz2 파일을 나타냅니다.
 	TarBz2 PackType = "tar.bz2"
+	// Gzip은 tar가 아닌 파일 하나를 gzip으로 압축한 파일(예: .ass.gz)을 나타냅니다.
+	Gzip PackType = "gz"
+	// Xz은 tar가 아닌 파일 하나를 xz로 압축한 파일(예: .ass.xz)을 나타냅니다.
+	Xz PackType = "xz"
+	// Bzip2은 tar가 아닌 파일 하나를 bzip2로 압축한 파일(예: .ass.bz2)을 나타냅니다.
+	Bzip2 PackType = "bz2"
 	// 7z은 7z 파일을 나타냅니다.
 	SevenZ PackType = "7z"
+	// Subtitle은 압축 파일이 아닌 자막 파일(.ass, .smi, .srt 등)을 나타냅니다. 압축을 풀지 않고 그대로 둡니다.
+	Subtitle PackType = "subtitle"
+	// Font는 압축 파일이 아닌 글꼴 파일(.ttf, .otf 등)을 나타냅니다. 압축을 풀지 않고 그대로 둡니다.
+	Font PackType = "font"
 	// NotSupported은 지원하지 않는 파일을 나타냅니다.
 	NotSupported PackType = "not_supported"
 )
 
+// IsArchive는 풀 수 있는 압축 파일 타입인지 확인합니다.
+func (t PackType) IsArchive() bool {
+	return t != Subtitle && t != Font && t != NotSupported
+}
+
+var (
+	// ErrNotSupportedPackType은 압축 파일이 아니거나 지원하지 않는 압축 형식일 때 반환됩니다.
+	ErrNotSupportedPackType = errors.New("not supported pack type")
+	// ErrNotFirstVolume은 분할 압축 파일의 첫 볼륨이 아닐 때 반환됩니다. 첫 볼륨을 풀 때 함께 읽습니다.
+	ErrNotFirstVolume = errors.New("not the first volume of a multi-volume archive")
+	// ErrPasswordRequired는 암호가 걸린 압축 파일을 비밀번호 목록의 어떤 비밀번호로도 풀 수 없을 때 반환됩니다.
+	ErrPasswordRequired = errors.New("archive is encrypted and no password matched")
+
+	errAbsolutePath  = errors.New("absolute path")
+	errPathTraversal = errors.New("path traversal")
+)
+
+// Manifest는 압축 파일에서 풀린 파일 목록입니다.
+// 압축 파일 안의 압축 파일도 풀어서, 그 안의 파일을 하나의 목록으로 펼칩니다.
+type Manifest struct {
+	Archive  string          // 압축 파일 경로
+	PackType PackType        // 압축 파일의 타입
+	Entries  []ManifestEntry // 풀린 파일, 디렉토리와 안쪽 압축 파일은 포함하지 않습니다.
+}
+
+// ManifestEntry는 압축 파일에서 풀린 파일 하나입니다.
+type ManifestEntry struct {
+	Path     string    // unpackPath 기준 상대 경로 ("/"로 구분)
+	RawName  string    // 압축 파일에 기록된 원래 이름, 디코딩한 이름과 같으면 비어 있습니다.
+	Size     int64     // 파일 크기 (byte)
+	Modified time.Time // 수정 시각
+	// Lineage는 파일이 들어 있던 압축 파일 목록입니다. 첫 항목은 바깥 압축 파일의 이름이고,
+	// 그 다음은 안쪽 압축 파일의 unpackPath 기준 상대 경로입니다. (예: ["01화.zip", "fonts.7z"])
+	Lineage []string
+}
+
+const (
+	// DefaultMaxTotalSize는 압축 파일 하나에서 풀 수 있는 기본 최대 크기입니다.
+	DefaultMaxTotalSize = 1 << 30
+	// DefaultMaxEntries는 압축 파일 하나에서 풀 수 있는 기본 최대 파일 수입니다.
+	DefaultMaxEntries = 10000
+	// DefaultMaxRatio는 기본 최대 압축률(풀린 크기 / 압축 파일 크기)입니다.
+	DefaultMaxRatio = 200
+	// DefaultMaxDepth는 압축 파일 안의 압축 파일을 푸는 기본 최대 깊이입니다.
+	DefaultMaxDepth = 3
+)
+
+// UnpackerConfig는 UnpackerImpl 설정입니다.
+type UnpackerConfig struct {
+	// Passwords는 암호가 걸린 7z, rar 파일에 차례로 시도할 비밀번호 목록입니다.
+	// 자막 제작자가 글에 적어 두는 비밀번호를 등록합니다.
+	Passwords    []string
+	MaxTotalSize int64   // 압축 파일 하나에서 풀 수 있는 최대 크기 (byte)
+	MaxEntries   int     // 압축 파일 하나에서 풀 수 있는 최대 파일, 디렉토리 수
+	MaxRatio     float64 // 최대 압축률 (풀린 크기 / 압축 파일 크기), 풀린 크기가 1MiB 이상일 때만 검사합니다.
+	MaxDepth     int     // 압축 파일 안의 압축 파일을 푸는 최대 깊이, 바깥 압축 파일이 1입니다.
+}
+
+// DefaultUnpackerConfig는 기본 UnpackerImpl 설정을 반환합니다.
+func DefaultUnpackerConfig() UnpackerConfig {
+	return UnpackerConfig{
+		MaxTotalSize: DefaultMaxTotalSize,
+		MaxEntries:   DefaultMaxEntries,
+		MaxRatio:     DefaultMaxRatio,
+		MaxDepth:     DefaultMaxDepth,
+	}
+}
+
 // UnpackerImpl은 downloader.Unpacker interface 의 구현체입니다.
-type UnpackerImpl struct{}
+type UnpackerImpl struct {
+	config UnpackerConfig
+}
+
+// NewUnpacker는 UnpackerImpl을 생성합니다. 0 이하의 제한은 기본값을 사용합니다.
+func NewUnpacker(config UnpackerConfig) *UnpackerImpl {
+	defaults := DefaultUnpackerConfig()
+	if config.MaxTotalSize <= 0 {
+		config.MaxTotalSize = defaults.MaxTotalSize
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaults.MaxEntries
+	}
+	if config.MaxRatio <= 0 {
+		config.MaxRatio = defaults.MaxRatio
+	}
+	if config.MaxDepth <= 0 {
+		config.MaxDepth = defaults.MaxDepth
+	}
+	return &UnpackerImpl{config: config}
+}
 
-// Unpack는 압축 파일을 풉니다.
-func (u *UnpackerImpl) Unpack(filePath string, unpackPath string) error {
+// Unpack는 압축 파일을 풉니다. 풀린 파일 중 압축 파일은 MaxDepth 깊이까지 다시 풉니다.
+// 압축 파일 밖을 가리키는 경로, 절대 경로가 있거나 풀린 크기, 파일 수, 압축률이 제한을 넘으면
+// 압축 해제를 중단하고 *UnsafeArchiveError를 반환합니다. 제한은 안쪽 압축 파일까지 합쳐서 적용합니다.
+func (u *UnpackerImpl) Unpack(filePath string, unpackPath string) (*Manifest, error) {
 	// 압축 파일의 타입을 판별합니다.
-	packType := getPackType(filePath)
+	packType, err := getPackType(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect pack type: %w", err)
+	}
+	if !packType.IsArchive() {
+		// 자막, 글꼴 파일은 압축을 풀지 않고 그대로 둡니다.
+		return nil, ErrNotSupportedPackType
+	}
+	q, err := newQuota(filePath, packType, u.config)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := u.unpack(filePath, packType, unpackPath, q)
+	if err != nil {
+		return nil, err
+	}
+	entries, err = u.unpackNested(unpackPath, uniqueEntries(entries), []string{filepath.Base(filePath)}, 2, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Manifest{
+		Archive:  filePath,
+		PackType: packType,
+		Entries:  entries,
+	}, nil
+}
+
+// unpack은 packType 압축 파일 filePath를 unpackPath에 풉니다. 안쪽 압축 파일은 풀지 않습니다.
+func (u *UnpackerImpl) unpack(filePath string, packType PackType, unpackPath string, q *quota) ([]ManifestEntry, error) {
+	var entries []ManifestEntry
+	var err error
 	switch packType {
 	case Zip:
-		return unpackZip(filePath, unpackPath)
+		entries, err = unpackZip(filePath, unpackPath, q)
 	case Rar:
-		return unpackRar(filePath, unpackPath)
+		entries, err = unpackRar(filePath, unpackPath, q, u.config.Passwords)
 	case Tar:
-		return unpackTar(filePath, unpackPath)
+		entries, err = unpackTar(filePath, unpackPath, q)
 	case TarGz:
-		return unpackTarGz(filePath, unpackPath)
+		entries, err = unpackTarGz(filePath, unpackPath, q)
 	case TarXz:
-		return unpackTarXz(filePath, unpackPath)
+		entries, err = unpackTarXz(filePath, unpackPath, q)
 	case TarBz2:
-		return unpackTarBz2(filePath, unpackPath)
+		entries, err = unpackTarBz2(filePath, unpackPath, q)
+	case Gzip:
+		entries, err = unpackGzip(filePath, unpackPath, q)
+	case Xz:
+		entries, err = unpackXz(filePath, unpackPath, q)
+	case Bzip2:
+		entries, err = unpackBzip2(filePath, unpackPath, q)
 	case SevenZ:
-		return unpackSevenZ(filePath, unpackPath)
-	case NotSupported:
-		return errors.New("not supported pack type")
-	}
-	return nil
-}
-
-// getPackType은 압축 파일의 타입을 판별합니다.
-func getPackType(filePath string) PackType {
-	// 확장자를 추출합니다.
-	ext := filepath.Ext(filePath)
-	switch ext {
-	case ".zip":
-		return Zip
-	case ".rar":
-		return Rar
-	case ".tar":
-		return Tar
-	case ".gz":
-		return TarGz
-	case ".xz":
-		return TarXz
-	case ".bz2":
-		return TarBz2
-	case ".7z":
-		return SevenZ
+		entries, err = unpackSevenZ(filePath, unpackPath, q, u.config.Passwords)
 	default:
-		return NotSupported
+		return nil, ErrNotSupportedPackType
 	}
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
 }
 
-// unpackZip은 zip 파일을 풉니다.
-func unpackZip(filePath string, unpackPath string) error {
-	return nil
-}
-
-// unpackRar은 rar 파일을 풉니다.
-func unpackRar(filePath string, unpackPath string) error {
-	return nil
-}
-
-// unpackTar은 tar 파일을 풉니다.
-func unpackTar(filePath string, unpackPath string) error {
-	return nil
-}
-
-// unpackTarGz은 tar.gz 파일을 풉니다.
-func unpackTarGz(filePath string, unpackPath string) error {
-	return nil
-}
-
-// unpackTarXz은 tar.xz 파일을 풉니다.
-func unpackTarXz(filePath string, unpackPath string) error {
-	return nil
+// uniqueEntries는 같은 경로에 여러 번 풀린 파일 중 마지막 항목만 남깁니다.
+// 압축 파일에 같은 이름이 여러 번 들어 있으면 나중에 풀린 파일이 앞의 파일을 덮어씁니다.
+func uniqueEntries(entries []ManifestEntry) []ManifestEntry {
+	index := make(map[string]int, len(entries))
+	unique := entries[:0]
+	for _, entry := range entries {
+		if i, ok := index[entry.Path]; ok {
+			unique[i] = entry
+			continue
+		}
+		index[entry.Path] = len(unique)
+		unique = append(unique, entry)
+	}
+	return unique
 }
 
-// unpackTarBz2은 tar.bz2 파일을 풉니다.
-func unpackTarBz2(filePath string, unpackPath string) error {
-	return nil
+// entryPath는 압축 파일 안의 이름을 unpackPath 아래의 경로로 바꿉니다.
+// 이름이 절대 경로이거나 unpackPath 밖을 가리키면 에러를 반환합니다.
+func entryPath(unpackPath string, name string) (string, string, error) {
+	slashed := strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(slashed, "/") || filepath.VolumeName(slashed) != "" || (len(slashed) >= 2 && slashed[1] == ':') {
+		return "", "", errAbsolutePath
+	}
+	rel := filepath.Clean(filepath.FromSlash(slashed))
+	if !filepath.IsLocal(rel) {
+		return "", "", errPathTraversal
+	}
+	return filepath.Join(unpackPath, rel), filepath.ToSlash(rel), nil
 }
 
-// unpackSevenZ은 7z 파일을 풉니다.
-func unpackSevenZ(filePath string, unpackPath string) error {
-	return nil
+// tryPasswords는 비밀번호 없이, 그 다음 passwords의 비밀번호로 차례로 extract를 시도합니다.
+// extract가 ErrPasswordRequired가 아닌 에러를 반환하거나 성공하면 바로 반환합니다.
+// 다시 시도할 때는 실패한 시도에서 센 크기와 파일 수를 되돌립니다.
+func tryPasswords(q *quota, passwords []string, extract func(password string) ([]ManifestEntry, error)) ([]ManifestEntry, error) {
+	m := q.mark()
+	entries, err := extract("")
+	for _, password := range passwords {
+		if !errors.Is(err, ErrPasswordRequired) {
+			break
+		}
+		q.restore(m)
+		entries, err = extract(password)
+	}
+	return entries, err
 }
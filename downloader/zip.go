@@ -0,0 +1,150 @@
+package downloader
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// zipFlagUTF8은 파일 이름이 UTF-8로 기록되었음을 나타내는 general purpose 플래그 비트(11)입니다.
+	zipFlagUTF8 = 0x800
+	// zipExtraUnicodePath는 Info-ZIP Unicode Path extra field의 header ID입니다.
+	zipExtraUnicodePath = 0x7075
+)
+
+// unpackZip은 zip 파일을 풉니다.
+func unpackZip(filePath string, unpackPath string, q *quota) ([]ManifestEntry, error) {
+	r, err := zip.OpenReader(filePath)
+	if err != nil && !errors.Is(err, zip.ErrInsecurePath) {
+		return nil, err
+	}
+	defer r.Close()
+
+	var entries []ManifestEntry
+	for _, f := range r.File {
+		name := zipFileName(f)
+		dst, rel, err := q.entryPath(unpackPath, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(dst, os.ModePerm); err != nil {
+				return nil, fmt.Errorf("failed to create directory: %w", err)
+			}
+			continue
+		}
+
+		size, err := extractZipFile(q, f, name, dst)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+
+		entry := ManifestEntry{
+			Path:     rel,
+			Size:     size,
+			Modified: f.Modified,
+		}
+		if f.Name != name {
+			entry.RawName = f.Name
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// extractZipFile은 zip 파일 안의 f를 dst에 저장합니다.
+func extractZipFile(q *quota, f *zip.File, name string, dst string) (int64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	return q.write(name, dst, rc)
+}
+
+// zipFileName은 zip 파일 안의 파일 이름을 UTF-8로 디코딩합니다.
+// UTF-8 플래그가 있으면 그대로 사용하고, Info-ZIP Unicode Path extra field가 있으면 그 이름을 사용합니다.
+// 둘 다 없는 이름은 국내 자막 압축 파일 대부분이 사용하는 CP949로 디코딩합니다.
+func zipFileName(f *zip.File) string {
+	if f.Flags&zipFlagUTF8 != 0 {
+		return f.Name
+	}
+	if name, ok := zipUnicodePath(f.Extra, f.Name); ok {
+		return name
+	}
+	if decoded, ok := cp949Hangul(f.Name); ok {
+		return decoded
+	}
+	if decoded, err := decodeFileName(f.Name); err == nil {
+		return decoded
+	}
+	return f.Name
+}
+
+// cp949Hangul은 UTF-8로도 읽을 수 있는 CP949 이름을 디코딩합니다.
+// "화"(C8 AD)처럼 CP949 한글 두 바이트는 UTF-8의 2바이트 문자(U+0080~U+07FF)로도 읽히므로,
+// 이름에 2바이트 문자만 있고 CP949로 읽으면 한글이 되는 경우에만 CP949 이름으로 봅니다.
+func cp949Hangul(name string) (string, bool) {
+	if !utf8.ValidString(name) {
+		return "", false
+	}
+	twoByte := false
+	for _, r := range name {
+		if r >= 0x800 {
+			return "", false
+		}
+		if r >= 0x80 {
+			twoByte = true
+		}
+	}
+	if !twoByte {
+		return "", false
+	}
+
+	decoded, err := decodeCP949(name)
+	if err != nil {
+		return "", false
+	}
+	for _, r := range decoded {
+		if r >= 0x80 && !unicode.Is(unicode.Hangul, r) {
+			return "", false
+		}
+	}
+	return decoded, true
+}
+
+// zipUnicodePath는 extra field에서 Info-ZIP Unicode Path(0x7075)를 찾습니다.
+// 필드의 CRC-32가 헤더에 기록된 원래 이름과 다르면 이름이 바뀐 뒤 남은 필드이므로 무시합니다.
+func zipUnicodePath(extra []byte, rawName string) (string, bool) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra[0:2])
+		size := int(binary.LittleEndian.Uint16(extra[2:4]))
+		extra = extra[4:]
+		if size > len(extra) {
+			return "", false
+		}
+		data := extra[:size]
+		extra = extra[size:]
+
+		// Version(1) + NameCRC32(4) + UnicodeName
+		if id != zipExtraUnicodePath || len(data) < 5 || data[0] != 1 {
+			continue
+		}
+		if binary.LittleEndian.Uint32(data[1:5]) != crc32.ChecksumIEEE([]byte(rawName)) {
+			continue
+		}
+		if name := string(data[5:]); name != "" && utf8.ValidString(name) {
+			return name, true
+		}
+	}
+	return "", false
+}
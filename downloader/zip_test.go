@@ -0,0 +1,229 @@
+package downloader
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/text/encoding/korean"
+)
+
+// cp949는 s를 CP949로 인코딩합니다.
+func cp949(t *testing.T, s string) string {
+	t.Helper()
+	encoded, err := korean.EUCKR.NewEncoder().String(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded
+}
+
+// unicodePathExtra는 crcName의 CRC-32와 name을 기록한 Info-ZIP Unicode Path extra field를 만듭니다.
+func unicodePathExtra(version byte, crcName string, name string) []byte {
+	data := make([]byte, 5, 5+len(name))
+	data[0] = version
+	binary.LittleEndian.PutUint32(data[1:5], crc32.ChecksumIEEE([]byte(crcName)))
+	data = append(data, name...)
+	return extraField(zipExtraUnicodePath, data)
+}
+
+// extraField는 header ID와 data로 extra field 하나를 만듭니다.
+func extraField(id uint16, data []byte) []byte {
+	field := make([]byte, 4, 4+len(data))
+	binary.LittleEndian.PutUint16(field[0:2], id)
+	binary.LittleEndian.PutUint16(field[2:4], uint16(len(data)))
+	return append(field, data...)
+}
+
+// buildRawZip은 headers의 이름, 플래그, extra field를 그대로 기록한 zip 파일의 내용을 만듭니다.
+func buildRawZip(t *testing.T, headers ...*zip.FileHeader) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, hdr := range headers {
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte("subtitle")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestZipFileName(t *testing.T) {
+	tests := []struct {
+		name   string
+		header func(t *testing.T) *zip.FileHeader
+		want   string
+	}{
+		{
+			// archive/zip은 UTF-8 이름에 UTF-8 플래그를 기록합니다.
+			name: "utf-8 flag",
+			header: func(t *testing.T) *zip.FileHeader {
+				return &zip.FileHeader{Name: "01화.ass"}
+			},
+			want: "01화.ass",
+		},
+		{
+			name: "cp949",
+			header: func(t *testing.T) *zip.FileHeader {
+				return &zip.FileHeader{Name: cp949(t, "자막/01화.ass"), NonUTF8: true}
+			},
+			want: "자막/01화.ass",
+		},
+		{
+			// CP949 "화"(C8 AD)는 UTF-8로도 읽힙니다.
+			name: "cp949 valid as utf-8",
+			header: func(t *testing.T) *zip.FileHeader {
+				return &zip.FileHeader{Name: cp949(t, "02화.ass"), NonUTF8: true}
+			},
+			want: "02화.ass",
+		},
+		{
+			name: "utf-8 without flag",
+			header: func(t *testing.T) *zip.FileHeader {
+				return &zip.FileHeader{Name: "01화 Pokémon.ass", NonUTF8: true}
+			},
+			want: "01화 Pokémon.ass",
+		},
+		{
+			name: "ascii without flag",
+			header: func(t *testing.T) *zip.FileHeader {
+				return &zip.FileHeader{Name: "fonts/a.ttf", NonUTF8: true}
+			},
+			want: "fonts/a.ttf",
+		},
+		{
+			name: "unicode path",
+			header: func(t *testing.T) *zip.FileHeader {
+				return &zip.FileHeader{Name: "__.ttf", NonUTF8: true, Extra: unicodePathExtra(1, "__.ttf", "폰트.ttf")}
+			},
+			want: "폰트.ttf",
+		},
+		{
+			name: "unicode path over cp949",
+			header: func(t *testing.T) *zip.FileHeader {
+				raw := cp949(t, "폰트.ttf")
+				return &zip.FileHeader{Name: raw, NonUTF8: true, Extra: unicodePathExtra(1, raw, "폰트.ttf")}
+			},
+			want: "폰트.ttf",
+		},
+		{
+			name: "unicode path after another field",
+			header: func(t *testing.T) *zip.FileHeader {
+				extra := append(extraField(0x000a, make([]byte, 32)), unicodePathExtra(1, "__.ttf", "폰트.ttf")...)
+				return &zip.FileHeader{Name: "__.ttf", NonUTF8: true, Extra: extra}
+			},
+			want: "폰트.ttf",
+		},
+		{
+			// 이름을 바꾼 뒤 남은 필드는 무시합니다.
+			name: "unicode path crc mismatch",
+			header: func(t *testing.T) *zip.FileHeader {
+				return &zip.FileHeader{Name: "renamed.ttf", NonUTF8: true, Extra: unicodePathExtra(1, "__.ttf", "폰트.ttf")}
+			},
+			want: "renamed.ttf",
+		},
+		{
+			name: "unicode path crc mismatch falls back to cp949",
+			header: func(t *testing.T) *zip.FileHeader {
+				return &zip.FileHeader{Name: cp949(t, "02화.ass"), NonUTF8: true, Extra: unicodePathExtra(1, "01화.ass", "01화.ass")}
+			},
+			want: "02화.ass",
+		},
+		{
+			name: "unknown unicode path version",
+			header: func(t *testing.T) *zip.FileHeader {
+				return &zip.FileHeader{Name: "__.ttf", NonUTF8: true, Extra: unicodePathExtra(2, "__.ttf", "폰트.ttf")}
+			},
+			want: "__.ttf",
+		},
+		{
+			name: "invalid utf-8 unicode path",
+			header: func(t *testing.T) *zip.FileHeader {
+				return &zip.FileHeader{Name: "__.ttf", NonUTF8: true, Extra: unicodePathExtra(1, "__.ttf", "\xff\xfe.ttf")}
+			},
+			want: "__.ttf",
+		},
+		{
+			// UTF-8 플래그가 있으면 extra field를 확인하지 않습니다.
+			name: "utf-8 flag over unicode path",
+			header: func(t *testing.T) *zip.FileHeader {
+				return &zip.FileHeader{Name: "01화.ass", Extra: unicodePathExtra(1, "01화.ass", "02화.ass")}
+			},
+			want: "01화.ass",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := buildRawZip(t, tt.header(t))
+			r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := zipFileName(r.File[0]); got != tt.want {
+				t.Errorf("zipFileName(%q) = %q, want %q", r.File[0].Name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestZipUnicodePathTruncated(t *testing.T) {
+	extra := unicodePathExtra(1, "__.ttf", "폰트.ttf")
+	tests := []struct {
+		name  string
+		extra []byte
+	}{
+		{name: "empty", extra: nil},
+		{name: "short header", extra: extra[:3]},
+		{name: "size over extra", extra: extra[:len(extra)-1]},
+		{name: "no name crc", extra: extraField(zipExtraUnicodePath, []byte{1, 0, 0})},
+	}
+	for _, tt := range tests {
+		if name, ok := zipUnicodePath(tt.extra, "__.ttf"); ok {
+			t.Errorf("%s: zipUnicodePath() = %q, want not found", tt.name, name)
+		}
+	}
+}
+
+func TestUnpackZipNames(t *testing.T) {
+	dir := t.TempDir()
+	raw := cp949(t, "자막/01화.ass")
+	archivePath := writeArchive(t, dir, "subtitle.zip", buildRawZip(t,
+		&zip.FileHeader{Name: raw, NonUTF8: true},
+		&zip.FileHeader{Name: "__.ttf", NonUTF8: true, Extra: unicodePathExtra(1, "__.ttf", "폰트.ttf")},
+		&zip.FileHeader{Name: "02화.ass"},
+	))
+	unpackPath := filepath.Join(dir, "files")
+
+	manifest, err := NewUnpacker(UnpackerConfig{}).Unpack(archivePath, unpackPath)
+	if err != nil {
+		t.Fatalf("Unpack() error = %v", err)
+	}
+	want := []struct{ path, rawName string }{
+		{path: "자막/01화.ass", rawName: raw},
+		{path: "폰트.ttf", rawName: "__.ttf"},
+		{path: "02화.ass"},
+	}
+	if len(manifest.Entries) != len(want) {
+		t.Fatalf("Entries = %+v, want %d entries", manifest.Entries, len(want))
+	}
+	for i, entry := range manifest.Entries {
+		if entry.Path != want[i].path || entry.RawName != want[i].rawName {
+			t.Errorf("Entries[%d] = %q (raw %q), want %q (raw %q)", i, entry.Path, entry.RawName, want[i].path, want[i].rawName)
+		}
+		if _, err := os.Stat(filepath.Join(unpackPath, filepath.FromSlash(entry.Path))); err != nil {
+			t.Errorf("unpacked file %s: %v", entry.Path, err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package event
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Type은 이벤트의 종류를 나타냅니다.
+type Type string
+
+const (
+	// AnimeAdded는 새로운 애니메이션이 편성표에 추가되었음을 나타냅니다.
+	AnimeAdded Type = "AnimeAdded"
+	// AnimeEnded는 애니메이션의 방영이 끝났음을 나타냅니다.
+	AnimeEnded Type = "AnimeEnded"
+	// AnimeRescheduled는 애니메이션의 방영 요일이나 시간이 바뀌었음을 나타냅니다.
+	AnimeRescheduled Type = "AnimeRescheduled"
+	// NewEpisodeCaption은 새로운 회차의 자막이 올라왔음을 나타냅니다.
+	NewEpisodeCaption Type = "NewEpisodeCaption"
+	// CaptionUpdated는 이미 저장된 자막의 웹사이트나 업로드 시간이 바뀌었음을 나타냅니다.
+	CaptionUpdated Type = "CaptionUpdated"
+	// ReleaserChanged는 회차의 선호 자막 제작자가 바뀌었음을 나타냅니다.
+	ReleaserChanged Type = "ReleaserChanged"
+)
+
+// Event는 Poller가 감지한 변경 사항입니다.
+// 애니메이션 이벤트는 RecordID가 "anime_info" 레코드를,
+// 자막 이벤트는 RecordID가 "anime_subtitle" 레코드를 가리킵니다.
+type Event struct {
+	Type     Type      // 이벤트 종류
+	Time     time.Time // 이벤트 발생 시간
+	RecordID string    // 변경된 레코드 ID
+	AnimeNo  int       // 애니메이션 No
+	Subject  string    // 애니메이션 제목
+	Episode  string    // 자막 회차, 자막 이벤트에만 설정됩니다.
+	Name     string    // 자막 제작자 이름, 자막 이벤트에만 설정됩니다.
+	Previous string    // 변경 전 값 (예: 이전 방영 요일, 이전 자막 제작자)
+}
+
+func (e Event) String() string {
+	if e.Episode == "" {
+		return fmt.Sprintf("%s Anime[%d] %s", e.Type, e.AnimeNo, e.Subject)
+	}
+	return fmt.Sprintf("%s Anime[%d] %s episode %s by %s", e.Type, e.AnimeNo, e.Subject, e.Episode, e.Name)
+}
+
+// Handler는 이벤트를 처리하는 함수입니다.
+type Handler func(e Event)
+
+// Bus는 프로세스 내부의 이벤트 버스입니다.
+// Publish는 구독자의 Handler를 순서대로 동기 호출하므로
+// 오래 걸리는 작업은 Handler 안에서 별도의 고루틴이나 큐로 넘겨야 합니다.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[Type][]Handler
+	all      []Handler
+}
+
+// NewBus는 Bus를 생성합니다.
+func NewBus() *Bus {
+	return &Bus{
+		handlers: make(map[Type][]Handler),
+	}
+}
+
+// Subscribe는 types 이벤트를 처리할 handler를 등록합니다.
+// types를 지정하지 않으면 모든 이벤트를 받습니다.
+func (b *Bus) Subscribe(handler Handler, types ...Type) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(types) == 0 {
+		b.all = append(b.all, handler)
+		return
+	}
+	for _, t := range types {
+		b.handlers[t] = append(b.handlers[t], handler)
+	}
+}
+
+// Publish는 이벤트를 구독자에게 전달합니다.
+// Handler에서 발생한 panic은 다른 구독자에게 영향을 주지 않도록 복구합니다.
+func (b *Bus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	handlers := make([]Handler, 0, len(b.handlers[e.Type])+len(b.all))
+	handlers = append(handlers, b.handlers[e.Type]...)
+	handlers = append(handlers, b.all...)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		dispatch(handler, e)
+	}
+}
+
+// dispatch는 handler를 호출하고 panic을 복구합니다.
+func dispatch(handler Handler, e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[Event] - handler panicked on %s: %v", e.Type, r)
+		}
+	}()
+	handler(e)
+}
@@ -0,0 +1,6 @@
+//go:build !goexperiment.jsonv2
+
+package testapp
+
+// jsonv2는 encoding/json이 v2 구현으로 빌드되었는지 여부입니다.
+const jsonv2 = false
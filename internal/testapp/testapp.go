@@ -0,0 +1,44 @@
+// Package testapp은 테스트에서 사용하는 PocketBase 앱을 생성합니다.
+package testapp
+
+import (
+	"testing"
+
+	"github.com/huketo/anisub-scraper/db"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/migrations"
+	"github.com/pocketbase/pocketbase/tools/migrate"
+)
+
+// New는 임시 디렉토리에 데이터베이스를 만들고 이 프로젝트의 Collection을 정의한 PocketBase 앱을 생성합니다.
+// 테스트가 끝나면 데이터베이스 연결을 닫습니다.
+//
+// PocketBase v0.19는 encoding/json v2에서 Collection 스키마를 읽지 못하므로,
+// GOEXPERIMENT=jsonv2로 빌드한 테스트는 건너뜁니다. (GOEXPERIMENT=nojsonv2로 실행합니다.)
+func New(t testing.TB) *pocketbase.PocketBase {
+	t.Helper()
+	if jsonv2 {
+		t.Skip("PocketBase v0.19 does not support encoding/json v2, run with GOEXPERIMENT=nojsonv2")
+	}
+
+	app := pocketbase.NewWithConfig(pocketbase.Config{DefaultDataDir: t.TempDir()})
+	if err := app.Bootstrap(); err != nil {
+		t.Fatalf("failed to bootstrap app: %v", err)
+	}
+	t.Cleanup(func() {
+		app.ResetBootstrapState()
+	})
+
+	runner, err := migrate.NewRunner(app.DB(), migrations.AppMigrations)
+	if err != nil {
+		t.Fatalf("failed to create migrations runner: %v", err)
+	}
+	if _, err := runner.Up(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	if err := db.InitCollection(app); err != nil {
+		t.Fatalf("failed to init collections: %v", err)
+	}
+	return app
+}
@@ -1,213 +1,95 @@
 package main
 
 import (
-	"archive/zip"
-	"errors"
+	"context"
 	"fmt"
-	"io"
 	"log"
-	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
-	"unicode/utf8"
 
+	"github.com/huketo/anisub-scraper/anissia"
 	"github.com/huketo/anisub-scraper/db"
+	"github.com/huketo/anisub-scraper/downloader"
+	"github.com/huketo/anisub-scraper/event"
+	"github.com/huketo/anisub-scraper/pipeline"
 	"github.com/huketo/anisub-scraper/poller"
+	"github.com/huketo/anisub-scraper/queue"
+	"github.com/huketo/anisub-scraper/scraper"
+	"github.com/huketo/anisub-scraper/storage"
+	"github.com/huketo/anisub-scraper/store"
 
 	"github.com/pocketbase/pocketbase"
 	"github.com/pocketbase/pocketbase/apis"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tools/cron"
 
-	"github.com/gocolly/colly/v2"
 	"github.com/joho/godotenv"
-	"golang.org/x/text/encoding/korean"
-	"golang.org/x/text/transform"
 )
 
-// link의 타입을 정의
-type LinkType int // 0: CommonBlog, 1: NaverBlog
-
-const (
-	CommonBlog LinkType = iota
-	NaverBlog
-)
-
-// link의 타입을 판별한다.
-func getLinkType(link string) LinkType {
-	if strings.Contains(link, "https://blog.naver.com/") {
-		return NaverBlog
+// 환경 변수를 time.Duration으로 읽는다. 값이 없으면 def를 반환한다.
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
 	}
-	return CommonBlog
-}
-
-// a 태그의 href 속성이 https://drive.google.com 인 링크를 전부 찾는다.
-func findGoogleDriveLink(url string) ([]string, error) {
-	var links []string
-	c := colly.NewCollector()
-
-	// On every a element which has href attribute call callback
-	c.OnHTML("a[href]", func(e *colly.HTMLElement) {
-		link := e.Attr("href")
-		// Check if the link contains the Google Drive prefix
-		if strings.HasPrefix(link, "https://drive.google.com") {
-			links = append(links, link)
-		}
-	})
-
-	// Before making a request print "Visiting ..."
-	c.OnRequest(func(r *colly.Request) {
-		fmt.Println("Visiting", r.URL.String())
-	})
-
-	// Start scraping
-	err := c.Visit(url)
+	d, err := time.ParseDuration(value)
 	if err != nil {
-		return nil, err
+		log.Fatalf("invalid %s: %v", key, err)
 	}
-
-	return links, nil
+	return d
 }
 
-// iframe 태그의 src 속성이 https://download.blog.naver.com 인 링크를 전부 찾는다.
-func findNaverBlogLink(url string) ([]string, error) {
-	var links []string
-	c := colly.NewCollector()
-
-	// iframe의 src 속성을 찾는다
-	c.OnHTML("iframe", func(e *colly.HTMLElement) {
-		iframeSrc := e.Attr("src")
-
-		// iframe의 src가 존재하면 iframe 내부의 콘텐츠에 대한 크롤링 시작
-		if iframeSrc != "" {
-			innerCollector := colly.NewCollector()
-
-			innerCollector.OnHTML("a[href]", func(e *colly.HTMLElement) {
-				link := e.Attr("href")
-				if link != "" && e.Request.AbsoluteURL(link) != "" {
-					// "https://download.blog.naver.com"으로 시작하는 링크를 출력
-					if strings.HasPrefix(e.Request.AbsoluteURL(link), "https://download.blog.naver.com") {
-						log.Println("Found link:", e.Request.AbsoluteURL(link))
-						links = append(links, e.Request.AbsoluteURL(link))
-					}
-				}
-			})
-
-			innerCollector.OnError(func(r *colly.Response, err error) {
-				log.Println("Request URL:", r.Request.URL, "failed with response:", r, "\nError:", err)
-			})
-
-			// iframe의 src로 요청을 보낸다
-			innerCollector.Visit(e.Request.AbsoluteURL(iframeSrc))
-		}
-	})
-
-	// Start scraping
-	err := c.Visit(url)
-	if err != nil {
-		return nil, err
+// 환경 변수를 int로 읽는다. 값이 없으면 def를 반환한다.
+func getEnvInt(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
 	}
-
-	return links, nil
-}
-
-// 구글 드라이브 파일 링크에서 파일 ID를 추출한다.
-func parseGDriveFileID(urlStr string) (string, error) {
-	parsedURL, err := url.Parse(urlStr)
+	n, err := strconv.Atoi(value)
 	if err != nil {
-		return "", err
-	}
-
-	// Google Drive 파일 링크의 경로 부분을 확인
-	switch {
-	case strings.HasPrefix(parsedURL.Path, "/file/d/"):
-		// 링크 형태: https://drive.google.com/file/d/FILE_ID/view?usp=sharing
-		parts := strings.Split(parsedURL.Path, "/")
-		if len(parts) >= 3 {
-			return parts[3], nil
-		}
-	case strings.HasPrefix(parsedURL.Path, "/uc"):
-		// 링크 형태: https://drive.google.com/uc?authuser=0&id=FILE_ID&export=download
-		queryParams := parsedURL.Query()
-		if id, ok := queryParams["id"]; ok && len(id) > 0 {
-			return id[0], nil
-		}
+		log.Fatalf("invalid %s: %v", key, err)
 	}
-
-	return "", errors.New("invalid Google Drive URL format")
+	return n
 }
 
-func decodeCP949(s string) (string, error) {
-	reader := transform.NewReader(strings.NewReader(s), korean.EUCKR.NewDecoder())
-	buf := new(strings.Builder)
-	_, err := io.Copy(buf, reader)
+// 환경 변수를 bool로 읽는다. 값이 없으면 def를 반환한다.
+func getEnvBool(key string, def bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
 	if err != nil {
-		return "", err
+		log.Fatalf("invalid %s: %v", key, err)
 	}
-	return buf.String(), nil
+	return b
 }
 
-func decodeFileName(encodedName string) (string, error) {
-	// 먼저 UTF-8로 시도
-	if utf8.ValidString(encodedName) {
-		return encodedName, nil
+// 환경 변수를 float64로 읽는다. 값이 없으면 def를 반환한다.
+func getEnvFloat(key string, def float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
 	}
-
-	// UTF-8이 아니면 CP949로 시도
-	decodedName, err := decodeCP949(encodedName)
+	f, err := strconv.ParseFloat(value, 64)
 	if err != nil {
-		return "", err
+		log.Fatalf("invalid %s: %v", key, err)
 	}
-
-	return decodedName, nil
+	return f
 }
 
-func unzip(src, dest string) error {
-	r, err := zip.OpenReader(src)
-	if err != nil {
-		return err
-	}
-	defer r.Close()
-
-	for _, f := range r.File {
-		decodedName, err := decodeFileName(f.Name)
-		if err != nil {
-			return err
-		}
-
-		fPath := filepath.Join(dest, decodedName)
-		if f.FileInfo().IsDir() {
-			os.MkdirAll(fPath, os.ModePerm)
-			continue
-		}
-
-		if err := os.MkdirAll(filepath.Dir(fPath), os.ModePerm); err != nil {
-			return err
-		}
-
-		outFile, err := os.OpenFile(fPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-		if err != nil {
-			return err
-		}
-
-		rc, err := f.Open()
-		if err != nil {
-			return err
-		}
-
-		_, err = io.Copy(outFile, rc)
-
-		// Close the file without overwriting the error
-		outFile.Close()
-		rc.Close()
-
-		if err != nil {
-			return err
+// 환경 변수를 쉼표로 구분된 목록으로 읽는다. 빈 항목은 버린다.
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
 		}
 	}
-	return nil
+	return list
 }
 
 func main() {
@@ -216,50 +98,147 @@ func main() {
 	if err != nil {
 		log.Fatalf("failed to load .env file: %v", err)
 	}
-	// 다운로드 디렉토리를 설정한다.
-	// downloadDir := os.Getenv("DOWNLOAD_DIR")
+	// 다운로더를 설정한다. API 키가 없으면 구글 드라이브 파일을 웹 다운로드로 받는다.
+	// 받는 중인 파일과 압축을 푸는 파일은 TEMP_DIR에 두고, 다 받은 파일은 저장소에 저장한다.
+	downloaderConfig := downloader.DefaultConfig()
+	if tempDir := os.Getenv("TEMP_DIR"); tempDir != "" {
+		downloaderConfig.TempDir = tempDir
+	} else if downloadDir := os.Getenv("DOWNLOAD_DIR"); downloadDir != "" {
+		downloaderConfig.TempDir = filepath.Join(downloadDir, ".tmp")
+	}
+	if baseURL := os.Getenv("GDRIVE_WEB_BASE_URL"); baseURL != "" {
+		downloaderConfig.GDriveWebBaseURL = baseURL
+	}
+	downloaderConfig.ConnectTimeout = getEnvDuration("DOWNLOAD_CONNECT_TIMEOUT", downloaderConfig.ConnectTimeout)
+	downloaderConfig.ResponseHeaderTimeout = getEnvDuration("DOWNLOAD_RESPONSE_TIMEOUT", downloaderConfig.ResponseHeaderTimeout)
+	downloaderConfig.ReadTimeout = getEnvDuration("DOWNLOAD_READ_TIMEOUT", downloaderConfig.ReadTimeout)
+	downloaderConfig.GDriveAPIKey = os.Getenv("GDRIVE_API_KEY")
+	if downloaderConfig.GDriveAPIKey == "" {
+		log.Println("GDRIVE_API_KEY is not set, google drive files are downloaded without the api")
+	}
+	fileDownloader, err := downloader.NewDownloader(context.Background(), downloaderConfig)
+	if err != nil {
+		log.Fatalf("failed to create downloader: %v", err)
+	}
 
-	// API 키를 설정한다.
-	apiKey := os.Getenv("GDRIVE_API_KEY")
-	if apiKey == "" {
-		log.Fatalf("failed to get api key")
+	// Anissia API 클라이언트를 생성한다.
+	anissiaConfig := anissia.DefaultConfig()
+	if baseURL := os.Getenv("ANISSIA_BASE_URL"); baseURL != "" {
+		anissiaConfig.BaseURL = baseURL
 	}
+	anissiaConfig.Timeout = getEnvDuration("ANISSIA_TIMEOUT", anissiaConfig.Timeout)
+	anissiaConfig.MaxRetries = getEnvInt("ANISSIA_MAX_RETRIES", anissiaConfig.MaxRetries)
+	anissiaConfig.RateLimit = getEnvFloat("ANISSIA_RATE_LIMIT", anissiaConfig.RateLimit)
+	anissiaClient := anissia.NewClient(anissiaConfig)
 
 	// PocketBase를 생성한다.
 	app := pocketbase.New()
 
+	// 이벤트 버스를 생성한다.
+	bus := event.NewBus()
+	bus.Subscribe(func(e event.Event) {
+		log.Printf("[Event] - %v", e)
+	})
+
 	// Poller를 생성한다.
-	pollingInterval := os.Getenv("POLLING_INTERVAL")
-	poller := poller.NewPoller(pollingInterval, app)
+	pollingInterval, err := poller.ParseInterval(os.Getenv("POLLING_INTERVAL"))
+	if err != nil {
+		log.Fatalf("failed to parse POLLING_INTERVAL: %v", err)
+	}
+	log.Printf("[Poller] - Polling interval: %s", pollingInterval)
+	pollerWorkers := getEnvInt("POLLER_WORKERS", poller.DefaultWorkers)
+	poller := poller.NewPoller(pollingInterval, pollerWorkers, app, anissiaClient, bus)
+
+	// 다운로드한 파일을 SHA-256 해시로 저장하는 저장소를 생성한다.
+	// STORAGE_TYPE이 s3이면 S3 호환 오브젝트 스토리지에, 아니면 DOWNLOAD_DIR에 저장한다.
+	storageConfig := storage.DefaultConfig()
+	if downloadDir := os.Getenv("DOWNLOAD_DIR"); downloadDir != "" {
+		storageConfig.LocalDir = downloadDir
+	}
+	if storageType := os.Getenv("STORAGE_TYPE"); storageType != "" {
+		storageConfig.Type = storage.Type(storageType)
+	}
+	storageConfig.S3Bucket = os.Getenv("S3_BUCKET")
+	storageConfig.S3Region = os.Getenv("S3_REGION")
+	storageConfig.S3Endpoint = os.Getenv("S3_ENDPOINT")
+	storageConfig.S3AccessKey = os.Getenv("S3_ACCESS_KEY")
+	storageConfig.S3SecretKey = os.Getenv("S3_SECRET_KEY")
+	storageConfig.S3ForcePathStyle = getEnvBool("S3_FORCE_PATH_STYLE", false)
+	fileStorage, err := storage.New(storageConfig)
+	if err != nil {
+		log.Fatalf("failed to create storage: %v", err)
+	}
+	fileStore := store.NewStore(app, fileStorage)
+	gcSchedule := os.Getenv("STORE_GC_SCHEDULE")
+	if gcSchedule == "" {
+		gcSchedule = store.DefaultGCSchedule
+	}
+
+	// 압축 해제 설정을 읽는다.
+	unpackerConfig := downloader.DefaultUnpackerConfig()
+	unpackerConfig.Passwords = getEnvList("UNPACK_PASSWORDS")
+	unpackerConfig.MaxTotalSize = int64(getEnvInt("UNPACK_MAX_TOTAL_SIZE", int(unpackerConfig.MaxTotalSize)))
+	unpackerConfig.MaxEntries = getEnvInt("UNPACK_MAX_ENTRIES", unpackerConfig.MaxEntries)
+	unpackerConfig.MaxRatio = getEnvFloat("UNPACK_MAX_RATIO", unpackerConfig.MaxRatio)
+	unpackerConfig.MaxDepth = getEnvInt("UNPACK_MAX_DEPTH", unpackerConfig.MaxDepth)
+
+	// 자막 수집 Pipeline을 생성한다.
+	pipelineWorkers := getEnvInt("PIPELINE_WORKERS", pipeline.DefaultWorkers)
+	pipeline := pipeline.NewPipeline(
+		app,
+		queue.NewQueue(app, queue.DefaultConfig()),
+		scraper.DefaultRegistry(),
+		fileDownloader,
+		downloader.NewUnpacker(unpackerConfig),
+		fileStore,
+		pipelineWorkers,
+	)
+	pipeline.Subscribe(bus)
+
+	// 서버가 종료되면 백그라운드 작업을 멈추고, 실행 중이던 작업을 돌려놓을 때까지 기다린다.
+	ctx, cancel := context.WithCancel(context.Background())
+	app.OnTerminate().Add(func(e *core.TerminateEvent) error {
+		cancel()
+		pipeline.Wait()
+		return nil
+	})
 
 	// 서버 시작 전에 실행할 함수를 등록한다.
 	app.OnBeforeServe().Add(func(e *core.ServeEvent) error {
 		e.Router.GET("/*", apis.StaticDirectoryHandler(os.DirFS("./pb_public"), false))
 
+		// Collection이 정의되어 있지 않으면 정의한다.
+		// 워커와 poller가 Collection을 사용하므로 먼저 정의하고, 실패하면 서버를 시작하지 않는다.
+		if err := db.InitCollection(app); err != nil {
+			return fmt.Errorf("failed to init collections: %w", err)
+		}
+
 		scheduler := cron.New()
 
-		// call poller every 1 minute
-		scheduler.MustAdd("poller", "*/1 * * * *", func() {
-			log.Println("[Poller] - Request Anime Schedule")
-			poller.Run()
-		})
-		// // call poller every 10 minute
-		// scheduler.MustAdd("poller", "*/10 * * * *", func() {
-		// 	log.Println("[Poller] - Request Anime Schedule")
-		// 	poller.Run()
-		// })
+		// 새로 올라온 자막을 수집한다.
+		pipeline.Start(ctx)
+
+		// POLLING_INTERVAL 주기로 poller를 실행한다.
+		if err := poller.Start(ctx, scheduler); err != nil {
+			return err
+		}
+
+		// STORE_GC_SCHEDULE 주기로 참조되지 않는 파일을 지운다.
+		if err := scheduler.Add("store_gc", gcSchedule, func() {
+			removed, err := fileStore.GC()
+			if err != nil {
+				log.Printf("[Store] - %v", err)
+			}
+			log.Printf("[Store] - removed %d unreferenced blobs", removed)
+		}); err != nil {
+			return fmt.Errorf("failed to schedule store gc: %w", err)
+		}
 
 		scheduler.Start()
 
 		return nil
 	})
 
-	// 고루틴을 사용해서 서버 시작 후에 Collection이 정의되어 있지 않으면 정의한다.
-	go func() {
-		time.Sleep(10 * time.Second)
-		db.InitCollection(app)
-	}()
-
 	// 서버를 시작한다.
 	if err := app.Start(); err != nil {
 		log.Fatal(err)
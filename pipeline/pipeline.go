@@ -0,0 +1,472 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/huketo/anisub-scraper/downloader"
+	"github.com/huketo/anisub-scraper/event"
+	"github.com/huketo/anisub-scraper/queue"
+	"github.com/huketo/anisub-scraper/scraper"
+	"github.com/huketo/anisub-scraper/store"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// Status는 anime_subtitle 레코드의 자막 수집 상태입니다.
+type Status string
+
+const (
+	// StatusPending은 자막 수집을 기다리는 상태입니다.
+	StatusPending Status = "pending"
+	// StatusScraping은 블로그 글에서 다운로드 링크를 찾는 상태입니다.
+	StatusScraping Status = "scraping"
+	// StatusDownloading은 자막 파일을 다운로드하는 상태입니다.
+	StatusDownloading Status = "downloading"
+	// StatusUnpacking은 압축 파일을 푸는 상태입니다.
+	StatusUnpacking Status = "unpacking"
+	// StatusDone은 자막 수집이 끝난 상태입니다.
+	StatusDone Status = "done"
+	// StatusFailed는 자막 수집에 실패한 상태입니다.
+	StatusFailed Status = "failed"
+)
+
+// DefaultWorkers는 단계별로 동시에 작업을 실행하는 기본 고루틴 수입니다.
+const DefaultWorkers = 2
+
+// File은 자막 수집 결과로 저장된 파일입니다.
+type File struct {
+	Path     string `json:"path"`               // store.Store의 논리 경로 (anime_no/episode/name/파일 이름)
+	Source   string `json:"source"`             // 다운로드 URL
+	Archive  string `json:"archive,omitempty"`  // 압축 파일에서 풀린 경우 압축 파일의 논리 경로
+	Size     int64  `json:"size,omitempty"`     // 파일 크기 (byte)
+	SHA256   string `json:"sha256,omitempty"`   // SHA-256 해시 (hex)
+	MIMEType string `json:"mimeType,omitempty"` // MIME 타입
+	// 압축 파일 안의 압축 파일에서 풀린 경우 바깥 압축 파일부터 파일이 들어 있던 압축 파일의 논리 경로
+	Lineage []string `json:"lineage,omitempty"`
+}
+
+// downloadPayload는 download 작업의 payload입니다.
+type downloadPayload struct {
+	Link scraper.DownloadLink `json:"link"`
+}
+
+// unpackPayload는 unpack 작업의 payload입니다.
+type unpackPayload struct {
+	Path   string `json:"path"`   // 다운로드한 파일의 논리 경로
+	Source string `json:"source"` // 다운로드 URL
+}
+
+// Pipeline은 새로 저장된 anime_subtitle 레코드의 자막을 수집합니다.
+// 블로그 글에서 다운로드 링크를 찾고(scrape), 파일을 다운로드하고(download),
+// 압축 파일을 풀어(unpack) 결과를 레코드에 기록합니다.
+// 각 단계는 queue.Queue의 작업으로 저장되어 실패하면 재시도되고, 재시작한 뒤에도 이어서 실행됩니다.
+// 다운로드하거나 압축을 푼 파일은 store.Store에 저장되므로 같은 내용의 파일은 한 번만 저장됩니다.
+type Pipeline struct {
+	app        *pocketbase.PocketBase
+	queue      *queue.Queue
+	scraper    scraper.Scraper
+	downloader *downloader.Downloader
+	unpacker   downloader.Unpacker
+	store      *store.Store
+	workers    int
+	recordMu   sync.Mutex // anime_subtitle 레코드의 files 필드를 동시에 수정하지 않도록 막는 잠금
+}
+
+// NewPipeline은 Pipeline을 생성합니다.
+// workers가 0 이하이면 DefaultWorkers를 사용합니다.
+func NewPipeline(app *pocketbase.PocketBase, q *queue.Queue, s scraper.Scraper, d *downloader.Downloader, u downloader.Unpacker, st *store.Store, workers int) *Pipeline {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	p := &Pipeline{
+		app:        app,
+		queue:      q,
+		scraper:    s,
+		downloader: d,
+		unpacker:   u,
+		store:      st,
+		workers:    workers,
+	}
+	q.OnSettled(p.onSettled)
+	return p
+}
+
+// Subscribe는 자막이 새로 올라오거나 바뀌었을 때 자막을 수집하도록 bus에 등록합니다.
+func (p *Pipeline) Subscribe(bus *event.Bus) {
+	bus.Subscribe(func(e event.Event) {
+		if e.RecordID == "" {
+			return
+		}
+		if err := p.Enqueue(e.RecordID); err != nil {
+			log.Printf("[Pipeline] - failed to enqueue anime_subtitle[%s]: %v", e.RecordID, err)
+		}
+	}, event.NewEpisodeCaption, event.CaptionUpdated)
+}
+
+// Enqueue는 anime_subtitle 레코드의 자막 수집 작업을 추가합니다.
+// 이미 진행 중인 수집 작업이 있으면 추가하지 않습니다.
+// 다시 수집하는 경우 이전 결과를 지웁니다. 이전 파일의 blob은 참조가 없으면 GC가 지웁니다.
+func (p *Pipeline) Enqueue(recordID string) error {
+	active, err := p.queue.HasActive(queue.StageScrape, recordID)
+	if err != nil {
+		return err
+	}
+	if active {
+		return nil
+	}
+
+	// scrape 작업이 다시 실행될 때 이미 다운로드한 파일을 지우지 않도록, 이전 결과는 작업을 추가하기 전에 지웁니다.
+	if err := p.store.ReleaseSubtitle(recordID); err != nil {
+		return err
+	}
+	if err := p.updateRecord(recordID, func(record *models.Record) {
+		record.Set("status", string(StatusPending))
+		record.Set("error", "")
+		record.Set("files", []File{})
+		record.Set("unmatched_links", []scraper.DownloadLink{})
+	}); err != nil {
+		return err
+	}
+	_, err = p.queue.Enqueue(queue.StageScrape, recordID, nil)
+	return err
+}
+
+// Start는 ctx가 끝날 때까지 단계별 작업을 실행하는 워커를 실행합니다.
+func (p *Pipeline) Start(ctx context.Context) {
+	p.queue.Work(ctx, queue.StageScrape, p.workers, p.scrape)
+	p.queue.Work(ctx, queue.StageDownload, p.workers, p.download)
+	p.queue.Work(ctx, queue.StageUnpack, p.workers, p.unpack)
+}
+
+// Wait는 Start로 실행한 워커가 모두 끝날 때까지 기다립니다.
+func (p *Pipeline) Wait() {
+	p.queue.Wait()
+}
+
+// scrape는 블로그 글에서 다운로드 링크를 찾아 download 작업을 추가합니다.
+func (p *Pipeline) scrape(ctx context.Context, job *queue.Job) error {
+	record, err := p.app.Dao().FindRecordById("anime_subtitle", job.SubtitleID())
+	if err != nil {
+		return queue.Permanent(fmt.Errorf("failed to find anime_subtitle record: %w", err))
+	}
+	website := record.GetString("website")
+	if website == "" {
+		return queue.Permanent(errors.New("no website"))
+	}
+
+	if err := p.updateRecord(record.Id, func(record *models.Record) {
+		record.Set("status", string(StatusScraping))
+	}); err != nil {
+		return err
+	}
+
+	episode := record.GetString("episode")
+	links, err := p.scraper.FindDownloadLinks(ctx, website, episode)
+	if err != nil {
+		return fmt.Errorf("failed to find download links: %w", err)
+	}
+	if len(links) == 0 {
+		return fmt.Errorf("no download links found in %s", website)
+	}
+
+	// 글에 여러 회차의 링크가 있으면 처리 중인 회차의 링크만 다운로드하고, 나머지는 레코드에 기록합니다.
+	links, unmatched := scraper.SelectByEpisode(links, episode)
+	if len(unmatched) > 0 {
+		log.Printf("[Pipeline] - %d download links in %s do not match episode %s", len(unmatched), website, episode)
+		if err := p.updateRecord(record.Id, func(record *models.Record) {
+			record.Set("unmatched_links", unmatched)
+		}); err != nil {
+			return err
+		}
+	}
+	if len(links) == 0 {
+		return fmt.Errorf("no download links for episode %s in %s (%d unmatched)", episode, website, len(unmatched))
+	}
+
+	// 점유를 잃어 작업이 다시 실행되어도 같은 링크의 download 작업은 한 번만 추가합니다.
+	for _, link := range links {
+		if _, err := p.queue.EnqueueUnique(queue.StageDownload, record.Id, dedupKey(job, link.URL), downloadPayload{Link: link}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// download는 파일을 다운로드하고 unpack 작업을 추가합니다.
+func (p *Pipeline) download(ctx context.Context, job *queue.Job) error {
+	var payload downloadPayload
+	if err := job.DecodePayload(&payload); err != nil {
+		return queue.Permanent(fmt.Errorf("failed to decode payload: %w", err))
+	}
+	record, err := p.app.Dao().FindRecordById("anime_subtitle", job.SubtitleID())
+	if err != nil {
+		return queue.Permanent(fmt.Errorf("failed to find anime_subtitle record: %w", err))
+	}
+
+	sink := &storeSink{store: p.store, subtitleID: record.Id, entries: make(map[string]*store.Entry)}
+	results, err := p.downloader.Download(ctx, payload.Link.URL, downloader.Dest{Dir: recordDir(record), Sink: sink})
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", payload.Link.URL, err)
+	}
+
+	files := make([]File, len(results))
+	for i, result := range results {
+		entry, ok := sink.entries[result.Path]
+		if !ok {
+			return fmt.Errorf("downloaded %s was not stored", result.Path)
+		}
+		files[i] = File{
+			Path:     entry.Path,
+			Source:   payload.Link.URL,
+			Size:     entry.Size,
+			SHA256:   entry.SHA256,
+			MIMEType: result.MIMEType,
+		}
+	}
+	if err := p.addFiles(record.Id, StatusDownloading, files...); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if _, err := p.queue.EnqueueUnique(queue.StageUnpack, record.Id, dedupKey(job, file.Path), unpackPayload{Path: file.Path, Source: file.Source}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unpack은 다운로드한 파일이 압축 파일이면 압축을 풉니다.
+func (p *Pipeline) unpack(ctx context.Context, job *queue.Job) error {
+	var payload unpackPayload
+	if err := job.DecodePayload(&payload); err != nil {
+		return queue.Permanent(fmt.Errorf("failed to decode payload: %w", err))
+	}
+
+	entry, err := p.store.Lookup(payload.Path)
+	if err != nil {
+		return fmt.Errorf("failed to find %s: %w", payload.Path, err)
+	}
+
+	// TempDir에 압축을 푼 뒤 풀린 파일을 저장소로 옮깁니다.
+	if err := os.MkdirAll(p.downloader.TempDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	workDir, err := os.MkdirTemp(p.downloader.TempDir, "unpack-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	// 저장소의 압축 파일을 원래 파일 이름으로 가져와서 Unpacker에 넘깁니다.
+	archivePath := filepath.Join(workDir, path.Base(payload.Path))
+	if err := p.store.Fetch(entry.SHA256, archivePath); err != nil {
+		return err
+	}
+	if err := p.fetchRarVolumes(payload.Path, workDir); err != nil {
+		return err
+	}
+	unpackPath := filepath.Join(workDir, "files")
+
+	manifest, err := p.unpacker.Unpack(archivePath, unpackPath)
+	if errors.Is(err, downloader.ErrNotSupportedPackType) {
+		return nil // 압축 파일이 아니면 그대로 둡니다.
+	}
+	if errors.Is(err, downloader.ErrNotFirstVolume) {
+		return nil // 분할 압축 파일은 첫 볼륨의 작업이 풉니다.
+	}
+	var unsafeErr *downloader.UnsafeArchiveError
+	if errors.As(err, &unsafeErr) {
+		// 안전하지 않은 압축 파일은 다시 시도하지 않고 작업에 에러를 기록합니다.
+		log.Printf("[Pipeline] - %v", unsafeErr)
+		return queue.Permanent(fmt.Errorf("failed to unpack %s: %w", payload.Path, err))
+	}
+	if errors.Is(err, downloader.ErrPasswordRequired) {
+		// 비밀번호 목록이 바뀌기 전에는 다시 시도해도 풀 수 없습니다.
+		return queue.Permanent(fmt.Errorf("failed to unpack %s: %w", payload.Path, err))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to unpack %s: %w", payload.Path, err)
+	}
+
+	// 풀린 파일의 논리 경로는 압축 파일의 논리 경로에서 확장자를 뺀 디렉토리 아래입니다.
+	archiveDir := strings.TrimSuffix(payload.Path, path.Ext(payload.Path))
+	files := make([]File, len(manifest.Entries))
+	for i, manifestEntry := range manifest.Entries {
+		rel := manifestEntry.Path
+		unpackedPath := filepath.Join(unpackPath, filepath.FromSlash(rel))
+		unpackedEntry, err := p.store.Put(job.SubtitleID(), path.Join(archiveDir, rel), unpackedPath)
+		if err != nil {
+			return fmt.Errorf("failed to store %s: %w", rel, err)
+		}
+		files[i] = File{
+			Path:     unpackedEntry.Path,
+			Source:   payload.Source,
+			Archive:  payload.Path,
+			Size:     unpackedEntry.Size,
+			SHA256:   unpackedEntry.SHA256,
+			MIMEType: unpackedEntry.MIMEType,
+		}
+		// 안쪽 압축 파일에서 풀린 파일은 압축 파일의 논리 경로를 순서대로 기록합니다.
+		if len(manifestEntry.Lineage) > 1 {
+			files[i].Lineage = []string{payload.Path}
+			for _, nested := range manifestEntry.Lineage[1:] {
+				files[i].Lineage = append(files[i].Lineage, path.Join(archiveDir, nested))
+			}
+		}
+	}
+	return p.addFiles(job.SubtitleID(), StatusUnpacking, files...)
+}
+
+// fetchRarVolumes는 logicalPath가 분할 압축된 rar 파일의 첫 볼륨이면 저장소에 있는 다음 볼륨을 workDir로 가져옵니다.
+// 아직 다운로드되지 않은 볼륨이 있으면 압축 해제가 실패하고 작업은 나중에 다시 시도됩니다.
+func (p *Pipeline) fetchRarVolumes(logicalPath string, workDir string) error {
+	if n, ok := downloader.RarVolume(logicalPath); !ok || n != 1 {
+		return nil
+	}
+	for volume := downloader.NextRarVolume(logicalPath); ; volume = downloader.NextRarVolume(volume) {
+		entry, err := p.store.Lookup(volume)
+		if errors.Is(err, store.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to find %s: %w", volume, err)
+		}
+		if err := p.store.Fetch(entry.SHA256, filepath.Join(workDir, path.Base(volume))); err != nil {
+			return err
+		}
+	}
+}
+
+// onSettled는 작업이 끝날 때마다 anime_subtitle 레코드의 자막 수집 상태를 갱신합니다.
+// 남은 작업이 없으면 dead 작업이 있는지에 따라 done 또는 failed 상태가 됩니다.
+func (p *Pipeline) onSettled(settled *queue.Job) {
+	jobs, err := p.queue.Jobs(settled.SubtitleID())
+	if err != nil {
+		log.Printf("[Pipeline] - %v", err)
+		return
+	}
+
+	// 다시 수집한 경우 이전 수집의 작업은 확인하지 않습니다.
+	for i := len(jobs) - 1; i >= 0; i-- {
+		if jobs[i].Stage() == queue.StageScrape {
+			jobs = jobs[i:]
+			break
+		}
+	}
+
+	var lastErr string
+	for _, job := range jobs {
+		if job.Active() {
+			return // 아직 남은 작업이 있습니다.
+		}
+		if job.State() == queue.StateDead {
+			lastErr = fmt.Sprintf("%s: %s", job.Stage(), job.LastError())
+		}
+	}
+
+	err = p.updateRecord(settled.SubtitleID(), func(record *models.Record) {
+		if lastErr != "" {
+			record.Set("status", string(StatusFailed))
+			record.Set("error", lastErr)
+			return
+		}
+		record.Set("status", string(StatusDone))
+		record.Set("error", "")
+	})
+	if err != nil {
+		log.Printf("[Pipeline] - %v", err)
+	}
+}
+
+// addFiles는 anime_subtitle 레코드에 파일을 추가하고 상태를 저장합니다.
+// 작업이 다시 실행되어 같은 경로의 파일을 다시 추가하면 기존 파일을 바꿉니다.
+func (p *Pipeline) addFiles(recordID string, status Status, files ...File) error {
+	return p.updateRecord(recordID, func(record *models.Record) {
+		var current []File
+		if err := record.UnmarshalJSONField("files", &current); err != nil {
+			current = nil
+		}
+		for _, file := range files {
+			replaced := false
+			for i := range current {
+				if current[i].Path == file.Path {
+					current[i] = file
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				current = append(current, file)
+			}
+		}
+		record.Set("status", string(status))
+		record.Set("files", current)
+	})
+}
+
+// updateRecord는 anime_subtitle 레코드를 다시 읽어 update를 적용한 뒤 저장합니다.
+func (p *Pipeline) updateRecord(recordID string, update func(record *models.Record)) error {
+	p.recordMu.Lock()
+	defer p.recordMu.Unlock()
+
+	record, err := p.app.Dao().FindRecordById("anime_subtitle", recordID)
+	if err != nil {
+		return fmt.Errorf("failed to find anime_subtitle record: %w", err)
+	}
+	update(record)
+	if err := p.app.Dao().SaveRecord(record); err != nil {
+		return fmt.Errorf("failed to save anime_subtitle record: %w", err)
+	}
+	return nil
+}
+
+// storeSink는 다운로드한 파일을 store.Store에 저장하는 downloader.Sink입니다.
+// 저장한 파일의 Entry를 논리 경로별로 기록합니다.
+type storeSink struct {
+	store      *store.Store
+	subtitleID string
+	entries    map[string]*store.Entry
+}
+
+// Put은 srcPath 파일을 logicalPath로 저장소에 저장합니다.
+func (s *storeSink) Put(logicalPath string, srcPath string) error {
+	entry, err := s.store.Put(s.subtitleID, logicalPath, srcPath)
+	if err != nil {
+		return err
+	}
+	s.entries[logicalPath] = entry
+	return nil
+}
+
+// dedupKey는 parent 작업이 key에 대해 추가하는 작업의 중복 확인 키를 만듭니다.
+// 같은 작업이 다시 실행되면 같은 키가 되고, 다시 수집하면 새 scrape 작업부터 키가 달라집니다.
+func dedupKey(parent *queue.Job, key string) string {
+	return parent.ID() + ":" + key
+}
+
+// recordDir은 레코드의 파일을 저장할 논리 경로의 디렉토리를 anime_no/episode/name 형태로 만듭니다.
+func recordDir(record *models.Record) string {
+	return path.Join(
+		fmt.Sprint(record.GetInt("anime_no")),
+		safeName(record.GetString("episode")),
+		safeName(record.GetString("name")),
+	)
+}
+
+// safeName은 이름을 디렉토리 이름으로 쓸 수 있도록 경로 구분자를 바꿉니다.
+func safeName(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(strings.TrimSpace(name))
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
@@ -0,0 +1,291 @@
+package pipeline
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huketo/anisub-scraper/downloader"
+	"github.com/huketo/anisub-scraper/internal/testapp"
+	"github.com/huketo/anisub-scraper/queue"
+	"github.com/huketo/anisub-scraper/scraper"
+	"github.com/huketo/anisub-scraper/storage"
+	"github.com/huketo/anisub-scraper/store"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// fakeExtractor는 모든 블로그 글에서 정해진 링크를 찾는 scraper.Extractor입니다.
+// Extract가 받은 회차를 순서대로 기록합니다.
+type fakeExtractor struct {
+	links    []scraper.DownloadLink
+	episodes []string
+}
+
+func (e *fakeExtractor) Match(url string) bool { return true }
+
+func (e *fakeExtractor) Extract(ctx context.Context, url string, episode string) ([]scraper.DownloadLink, error) {
+	e.episodes = append(e.episodes, episode)
+	return append([]scraper.DownloadLink(nil), e.links...), nil
+}
+
+// buildZip은 이름과 내용의 쌍을 순서대로 넣은 zip 파일의 내용을 만듭니다.
+func buildZip(t *testing.T, files ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range files {
+		name, body := file[0], file[1]
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newFileServer는 path별로 files의 내용을 보내는 다운로드 서버를 생성합니다. 없는 path는 404입니다.
+func newFileServer(t *testing.T, files map[string][]byte) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestPipeline은 테스트 앱과 로컬 저장소를 사용하고, links를 찾는 Extractor로 수집하는 Pipeline을 생성합니다.
+func newTestPipeline(t *testing.T, app *pocketbase.PocketBase, links []scraper.DownloadLink) *Pipeline {
+	t.Helper()
+	d, err := downloader.NewDownloader(context.Background(), downloader.Config{TempDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewDownloader() error = %v", err)
+	}
+	local, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal() error = %v", err)
+	}
+	q := queue.NewQueue(app, queue.Config{
+		MaxAttempts:  1,
+		PollInterval: 10 * time.Millisecond,
+	})
+	return NewPipeline(
+		app,
+		q,
+		scraper.NewRegistry(&fakeExtractor{links: links}),
+		d,
+		downloader.NewUnpacker(downloader.DefaultUnpackerConfig()),
+		store.NewStore(app, local),
+		1,
+	)
+}
+
+// createSubtitle은 1번 애니메이션 1화의 anime_subtitle 레코드를 저장합니다.
+func createSubtitle(t *testing.T, app *pocketbase.PocketBase) *models.Record {
+	t.Helper()
+	collection, err := app.Dao().FindCollectionByNameOrId("anime_subtitle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := models.NewRecord(collection)
+	record.Set("anime_no", 1)
+	record.Set("subject", "테스트 애니메이션")
+	record.Set("episode", "1")
+	record.Set("name", "tester")
+	record.Set("website", "https://blog.example.com/1")
+	if err := app.Dao().SaveRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+// recordStatuses는 anime_subtitle 레코드가 저장될 때마다 바뀐 status를 순서대로 기록합니다.
+func recordStatuses(app *pocketbase.PocketBase) func() []string {
+	var mu sync.Mutex
+	var statuses []string
+	app.OnModelAfterUpdate("anime_subtitle").Add(func(e *core.ModelEvent) error {
+		status := e.Model.(*models.Record).GetString("status")
+		mu.Lock()
+		defer mu.Unlock()
+		if len(statuses) == 0 || statuses[len(statuses)-1] != status {
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), statuses...)
+	}
+}
+
+// run은 recordID의 자막을 수집하고 done 또는 failed 상태가 될 때까지 기다린 뒤 워커를 멈춥니다.
+func run(t *testing.T, app *pocketbase.PocketBase, p *Pipeline, recordID string) *models.Record {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer p.Wait()
+	defer cancel()
+
+	if err := p.Enqueue(recordID); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	p.Start(ctx)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		record, err := app.Dao().FindRecordById("anime_subtitle", recordID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status := Status(record.GetString("status")); status == StatusDone || status == StatusFailed {
+			return record
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status = %s, want done or failed", record.GetString("status"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// filePaths는 레코드의 files 필드에 기록된 파일의 논리 경로를 반환합니다.
+func filePaths(t *testing.T, record *models.Record) []string {
+	t.Helper()
+	var files []File
+	if err := record.UnmarshalJSONField("files", &files); err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{}
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	return paths
+}
+
+func TestPipeline(t *testing.T) {
+	app := testapp.New(t)
+	server := newFileServer(t, map[string][]byte{
+		"/sub_01.zip": buildZip(t, [2]string{"01화.ass", "subtitle"}, [2]string{"fonts/a.ttf", "font"}),
+		"/sub_02.ass": []byte("other episode"),
+	})
+	p := newTestPipeline(t, app, []scraper.DownloadLink{
+		{URL: server.URL + "/sub_01.zip", Label: "1화 자막"},
+		{URL: server.URL + "/sub_02.ass", Label: "2화 자막"},
+	})
+	statuses := recordStatuses(app)
+	record := createSubtitle(t, app)
+
+	record = run(t, app, p, record.Id)
+
+	if record.GetString("status") != string(StatusDone) || record.GetString("error") != "" {
+		t.Fatalf("status = %s, error = %q, want done", record.GetString("status"), record.GetString("error"))
+	}
+	want := []string{"pending", "scraping", "downloading", "unpacking", "done"}
+	if got := statuses(); !reflect.DeepEqual(got, want) {
+		t.Errorf("status transitions = %q, want %q", got, want)
+	}
+	wantPaths := []string{"1/1/tester/sub_01.zip", "1/1/tester/sub_01/01화.ass", "1/1/tester/sub_01/fonts/a.ttf"}
+	if got := filePaths(t, record); !reflect.DeepEqual(got, wantPaths) {
+		t.Errorf("files = %q, want %q", got, wantPaths)
+	}
+	// 다른 회차의 링크는 다운로드하지 않고 기록합니다.
+	var unmatched []scraper.DownloadLink
+	if err := record.UnmarshalJSONField("unmatched_links", &unmatched); err != nil {
+		t.Fatal(err)
+	}
+	if len(unmatched) != 1 || unmatched[0].URL != server.URL+"/sub_02.ass" || unmatched[0].Episode != "2" {
+		t.Errorf("unmatched_links = %+v, want the episode 2 link", unmatched)
+	}
+	if _, err := p.store.Lookup("1/1/tester/sub_01/01화.ass"); err != nil {
+		t.Errorf("Lookup() error = %v", err)
+	}
+
+	// 다시 수집하면 이전 결과를 지우고 처음부터 수집합니다.
+	record = run(t, app, p, record.Id)
+	if got := filePaths(t, record); record.GetString("status") != string(StatusDone) || !reflect.DeepEqual(got, wantPaths) {
+		t.Errorf("after rescrape status = %s, files = %q, want done and %q", record.GetString("status"), got, wantPaths)
+	}
+}
+
+func TestPipelineDownloadFailed(t *testing.T) {
+	app := testapp.New(t)
+	server := newFileServer(t, nil)
+	p := newTestPipeline(t, app, []scraper.DownloadLink{{URL: server.URL + "/sub_01.zip", Label: "1화 자막"}})
+	statuses := recordStatuses(app)
+	record := createSubtitle(t, app)
+
+	record = run(t, app, p, record.Id)
+
+	if record.GetString("status") != string(StatusFailed) || !strings.HasPrefix(record.GetString("error"), "download: ") {
+		t.Errorf("status = %s, error = %q, want failed by download", record.GetString("status"), record.GetString("error"))
+	}
+	want := []string{"pending", "scraping", "failed"}
+	if got := statuses(); !reflect.DeepEqual(got, want) {
+		t.Errorf("status transitions = %q, want %q", got, want)
+	}
+	if got := filePaths(t, record); len(got) != 0 {
+		t.Errorf("files = %q, want none", got)
+	}
+}
+
+func TestScrapeRetryEnqueuesOnce(t *testing.T) {
+	app := testapp.New(t)
+	p := newTestPipeline(t, app, []scraper.DownloadLink{
+		{URL: "https://example.com/sub_01.zip", Label: "1화 자막"},
+		{URL: "https://example.com/fonts.zip", Label: "폰트"},
+	})
+	record := createSubtitle(t, app)
+	if err := p.Enqueue(record.Id); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	job, err := p.queue.Claim(queue.StageScrape)
+	if err != nil || job == nil {
+		t.Fatalf("Claim() = %v, %v", job, err)
+	}
+
+	// 점유를 잃은 뒤 같은 scrape 작업이 다시 실행된 경우입니다.
+	for i := 0; i < 2; i++ {
+		if err := p.scrape(context.Background(), job); err != nil {
+			t.Fatalf("scrape() error = %v", err)
+		}
+	}
+
+	jobs, err := p.queue.Jobs(record.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloads := 0
+	for _, job := range jobs {
+		if job.Stage() == queue.StageDownload {
+			downloads++
+		}
+	}
+	if downloads != 2 {
+		t.Errorf("download jobs = %d, want 2", downloads)
+	}
+	// 처리 중인 회차를 Extractor에 넘깁니다.
+	extractor := p.scraper.(*scraper.Registry).Extractor(record.GetString("website")).(*fakeExtractor)
+	if want := []string{"1", "1"}; !reflect.DeepEqual(extractor.episodes, want) {
+		t.Errorf("Extract() episodes = %q, want %q", extractor.episodes, want)
+	}
+}
@@ -0,0 +1,117 @@
+package poller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/huketo/anisub-scraper/anissia"
+	"github.com/huketo/anisub-scraper/event"
+
+	"github.com/pocketbase/pocketbase/models"
+)
+
+// change는 저장된 레코드와 API 응답 사이의 변경 사항입니다.
+// Type이 비어 있으면 이벤트를 발행하지 않는 단순 변경입니다.
+type change struct {
+	Type     event.Type
+	Previous string
+}
+
+// diffAnimeInfo는 저장된 anime_info 레코드와 animeInfo를 비교합니다.
+func diffAnimeInfo(record *models.Record, animeInfo anissia.AnimeInfo) []change {
+	if record.IsNew() {
+		return []change{{Type: event.AnimeAdded}}
+	}
+
+	var changes []change
+	prevWeek, prevTime := record.GetString("week"), record.GetString("time")
+	if prevWeek != animeInfo.Week || prevTime != animeInfo.Time {
+		changes = append(changes, change{
+			Type:     event.AnimeRescheduled,
+			Previous: fmt.Sprintf("%s %s", prevWeek, prevTime),
+		})
+	}
+
+	// 종영일은 마지막 회차 전에 미리 입력되고, 결방 등으로 ON이 아닌 상태가 될 수도 있으므로
+	// 상태가 종영으로 바뀐 경우에만 AnimeEnded 이벤트를 발행합니다.
+	prevStatus := record.GetString("status")
+	if prevStatus != StatusEnded && animeInfo.Status == StatusEnded {
+		changes = append(changes, change{Type: event.AnimeEnded, Previous: prevStatus})
+	} else if prevStatus != animeInfo.Status || record.GetString("end_date") != animeInfo.EndDate {
+		changes = append(changes, change{})
+	}
+
+	if record.GetString("subject") != animeInfo.Subject ||
+		record.GetString("genres") != animeInfo.Genres ||
+		record.GetInt("caption_count") != animeInfo.CaptionCount ||
+		record.GetString("start_date") != animeInfo.StartDate ||
+		record.GetString("website") != animeInfo.Website {
+		changes = append(changes, change{})
+	}
+
+	return changes
+}
+
+// diffSubtitleInfo는 저장된 anime_subtitle 레코드와 subtitleInfo를 비교합니다.
+func diffSubtitleInfo(record *models.Record, subject string, subtitleInfo anissia.SubtitleInfo, updDt time.Time, preferred bool) []change {
+	if record.IsNew() {
+		return []change{{Type: event.NewEpisodeCaption}}
+	}
+
+	var changes []change
+	prevWebsite := record.GetString("website")
+	if prevWebsite != subtitleInfo.Website || !record.GetDateTime("upd_dt").Time().Equal(updDt) {
+		changes = append(changes, change{Type: event.CaptionUpdated, Previous: prevWebsite})
+	}
+	if record.GetBool("preferred") != preferred || record.GetString("subject") != subject {
+		changes = append(changes, change{})
+	}
+	return changes
+}
+
+// storedSubtitles는 DB에 저장된 애니메이션 한 편의 자막 정보입니다.
+type storedSubtitles struct {
+	records   map[string]*models.Record // 회차와 자막 제작자로 찾는 레코드
+	preferred map[string]string         // 회차별 선호 자막 제작자
+}
+
+// newStoredSubtitles는 anime_subtitle 레코드로 storedSubtitles를 생성합니다.
+func newStoredSubtitles(records []*models.Record) *storedSubtitles {
+	s := &storedSubtitles{
+		records:   make(map[string]*models.Record, len(records)),
+		preferred: make(map[string]string),
+	}
+	for _, record := range records {
+		episode, name := record.GetString("episode"), record.GetString("name")
+		s.records[releaseKey(episode, name)] = record
+		if record.GetBool("preferred") {
+			s.preferred[episode] = name
+		}
+	}
+	return s
+}
+
+// get은 회차와 자막 제작자로 레코드를 찾습니다. 없으면 nil을 반환합니다.
+func (s *storedSubtitles) get(episode, name string) *models.Record {
+	return s.records[releaseKey(episode, name)]
+}
+
+// preferredName은 회차의 선호 자막 제작자를 반환합니다.
+func (s *storedSubtitles) preferredName(episode string) string {
+	return s.preferred[episode]
+}
+
+// releaseKey는 회차와 자막 제작자로 자막을 구분하는 키를 만듭니다.
+func releaseKey(episode, name string) string {
+	return episode + "\x00" + name
+}
+
+// containsRelease는 subtitleInfos에 회차와 자막 제작자가 같은 자막이 있는지 확인합니다.
+func containsRelease(subtitleInfos []anissia.SubtitleInfo, episode, name string) bool {
+	for _, subtitleInfo := range subtitleInfos {
+		if subtitleInfo.Episode == episode && subtitleInfo.Name == name {
+			return true
+		}
+	}
+	return false
+}
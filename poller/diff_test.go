@@ -0,0 +1,255 @@
+package poller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/huketo/anisub-scraper/anissia"
+	"github.com/huketo/anisub-scraper/event"
+
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/models/schema"
+)
+
+// newTestRecord는 DB 없이 fields 필드를 가진 레코드를 생성합니다.
+// data가 nil이 아니면 저장된 레코드로 표시합니다.
+func newTestRecord(name string, fields []string, data map[string]any) *models.Record {
+	s := schema.NewSchema()
+	for _, field := range fields {
+		fieldType := schema.FieldTypeText
+		switch field {
+		case "anime_no", "caption_count":
+			fieldType = schema.FieldTypeNumber
+		case "upd_dt":
+			fieldType = schema.FieldTypeDate
+		case "preferred":
+			fieldType = schema.FieldTypeBool
+		}
+		s.AddField(&schema.SchemaField{Name: field, Type: fieldType})
+	}
+	record := models.NewRecord(&models.Collection{Name: name, Type: models.CollectionTypeBase, Schema: s})
+	if data != nil {
+		record.Load(data)
+		record.MarkAsNotNew()
+	}
+	return record
+}
+
+// newAnimeInfoRecord는 animeInfo가 저장된 anime_info 레코드를 생성합니다.
+func newAnimeInfoRecord(animeInfo anissia.AnimeInfo) *models.Record {
+	return newTestRecord("anime_info",
+		[]string{"week", "anime_no", "status", "time", "subject", "genres", "caption_count", "start_date", "end_date", "website"},
+		map[string]any{
+			"week":          animeInfo.Week,
+			"anime_no":      animeInfo.AnimeNo,
+			"status":        animeInfo.Status,
+			"time":          animeInfo.Time,
+			"subject":       animeInfo.Subject,
+			"genres":        animeInfo.Genres,
+			"caption_count": animeInfo.CaptionCount,
+			"start_date":    animeInfo.StartDate,
+			"end_date":      animeInfo.EndDate,
+			"website":       animeInfo.Website,
+		})
+}
+
+func TestDiffAnimeInfo(t *testing.T) {
+	stored := anissia.AnimeInfo{
+		Week:         "1",
+		AnimeNo:      100,
+		Status:       "ON",
+		Time:         "23:00",
+		Subject:      "테스트 애니메이션",
+		Genres:       "판타지",
+		CaptionCount: 3,
+		StartDate:    "2023-10-02",
+		Website:      "https://example.com",
+	}
+
+	tests := []struct {
+		name   string
+		update func(a *anissia.AnimeInfo)
+		want   []change
+	}{
+		{
+			name:   "unchanged",
+			update: func(a *anissia.AnimeInfo) {},
+		},
+		{
+			name:   "rescheduled",
+			update: func(a *anissia.AnimeInfo) { a.Week, a.Time = "2", "01:00" },
+			want:   []change{{Type: event.AnimeRescheduled, Previous: "1 23:00"}},
+		},
+		{
+			name:   "status ended",
+			update: func(a *anissia.AnimeInfo) { a.Status = StatusEnded },
+			want:   []change{{Type: event.AnimeEnded, Previous: "ON"}},
+		},
+		{
+			name:   "end date scheduled in advance",
+			update: func(a *anissia.AnimeInfo) { a.EndDate = "2023-12-25" },
+			want:   []change{{}},
+		},
+		{
+			name:   "hiatus",
+			update: func(a *anissia.AnimeInfo) { a.Status = "OFF" },
+			want:   []change{{}},
+		},
+		{
+			name:   "ended with end date",
+			update: func(a *anissia.AnimeInfo) { a.Status, a.EndDate = StatusEnded, "2023-12-25" },
+			want:   []change{{Type: event.AnimeEnded, Previous: "ON"}},
+		},
+		{
+			name:   "caption count",
+			update: func(a *anissia.AnimeInfo) { a.CaptionCount = 4 },
+			want:   []change{{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			animeInfo := stored
+			tt.update(&animeInfo)
+			got := diffAnimeInfo(newAnimeInfoRecord(stored), animeInfo)
+			if !equalChanges(got, tt.want) {
+				t.Errorf("diffAnimeInfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffAnimeInfoAlreadyEnded(t *testing.T) {
+	stored := anissia.AnimeInfo{AnimeNo: 100, Status: StatusEnded, Subject: "테스트 애니메이션"}
+	animeInfo := stored
+	animeInfo.EndDate = "2023-12-25"
+	got := diffAnimeInfo(newAnimeInfoRecord(stored), animeInfo)
+	if want := []change{{}}; !equalChanges(got, want) {
+		t.Errorf("diffAnimeInfo() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiffAnimeInfoNew(t *testing.T) {
+	record := newTestRecord("anime_info", []string{"anime_no"}, nil)
+	got := diffAnimeInfo(record, anissia.AnimeInfo{AnimeNo: 100, Status: "ON"})
+	if want := []change{{Type: event.AnimeAdded}}; !equalChanges(got, want) {
+		t.Errorf("diffAnimeInfo() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiffSubtitleInfo(t *testing.T) {
+	updDt := time.Date(2023, 10, 22, 4, 24, 0, 0, time.UTC)
+	stored := func() *models.Record {
+		return newTestRecord("anime_subtitle",
+			[]string{"subject", "episode", "name", "website", "upd_dt", "preferred"},
+			map[string]any{
+				"subject":   "테스트 애니메이션",
+				"episode":   "3",
+				"name":      "코코렛",
+				"website":   "https://felia.tistory.com/885",
+				"upd_dt":    updDt,
+				"preferred": true,
+			})
+	}
+	subtitleInfo := anissia.SubtitleInfo{Episode: "3", Name: "코코렛", Website: "https://felia.tistory.com/885"}
+
+	tests := []struct {
+		name      string
+		record    *models.Record
+		subject   string
+		website   string
+		updDt     time.Time
+		preferred bool
+		want      []change
+	}{
+		{
+			name:      "new",
+			record:    newTestRecord("anime_subtitle", []string{"episode"}, nil),
+			subject:   "테스트 애니메이션",
+			website:   subtitleInfo.Website,
+			updDt:     updDt,
+			preferred: true,
+			want:      []change{{Type: event.NewEpisodeCaption}},
+		},
+		{
+			name:      "unchanged",
+			record:    stored(),
+			subject:   "테스트 애니메이션",
+			website:   subtitleInfo.Website,
+			updDt:     updDt,
+			preferred: true,
+		},
+		{
+			name:      "website changed",
+			record:    stored(),
+			subject:   "테스트 애니메이션",
+			website:   "https://felia.tistory.com/886",
+			updDt:     updDt,
+			preferred: true,
+			want:      []change{{Type: event.CaptionUpdated, Previous: subtitleInfo.Website}},
+		},
+		{
+			name:      "reuploaded",
+			record:    stored(),
+			subject:   "테스트 애니메이션",
+			website:   subtitleInfo.Website,
+			updDt:     updDt.Add(time.Hour),
+			preferred: true,
+			want:      []change{{Type: event.CaptionUpdated, Previous: subtitleInfo.Website}},
+		},
+		{
+			name:      "no longer preferred",
+			record:    stored(),
+			subject:   "테스트 애니메이션",
+			website:   subtitleInfo.Website,
+			updDt:     updDt,
+			preferred: false,
+			want:      []change{{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := subtitleInfo
+			info.Website = tt.website
+			got := diffSubtitleInfo(tt.record, tt.subject, info, tt.updDt, tt.preferred)
+			if !equalChanges(got, tt.want) {
+				t.Errorf("diffSubtitleInfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoredSubtitles(t *testing.T) {
+	fields := []string{"episode", "name", "preferred"}
+	stored := newStoredSubtitles([]*models.Record{
+		newTestRecord("anime_subtitle", fields, map[string]any{"episode": "1", "name": "a", "preferred": true}),
+		newTestRecord("anime_subtitle", fields, map[string]any{"episode": "1", "name": "b", "preferred": false}),
+		newTestRecord("anime_subtitle", fields, map[string]any{"episode": "2", "name": "b", "preferred": true}),
+	})
+
+	if got := stored.preferredName("1"); got != "a" {
+		t.Errorf("preferredName(1) = %q, want %q", got, "a")
+	}
+	if got := stored.preferredName("3"); got != "" {
+		t.Errorf("preferredName(3) = %q, want empty", got)
+	}
+	if record := stored.get("1", "b"); record == nil || record.GetString("name") != "b" {
+		t.Errorf("get(1, b) = %v", record)
+	}
+	if record := stored.get("2", "a"); record != nil {
+		t.Errorf("get(2, a) = %v, want nil", record)
+	}
+}
+
+func equalChanges(got, want []change) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
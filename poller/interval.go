@@ -0,0 +1,95 @@
+package poller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/tools/cron"
+)
+
+// DefaultInterval은 POLLING_INTERVAL이 설정되지 않았을 때 사용하는 폴링 주기입니다.
+const DefaultInterval = "*/1 * * * *"
+
+// Interval은 폴링 주기를 나타냅니다.
+// Every와 Cron 중 하나만 설정됩니다.
+type Interval struct {
+	Every time.Duration // Go duration 형식의 주기 (예: 10m)
+	Cron  string        // cron 표현식 형식의 주기 (예: */10 * * * *)
+}
+
+// ParseInterval은 Go duration 또는 cron 표현식을 Interval로 변환합니다.
+// 빈 문자열은 DefaultInterval로 처리합니다.
+func ParseInterval(s string) (Interval, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		s = DefaultInterval
+	}
+
+	// cron 표현식은 공백으로 구분된 5개의 필드로 이루어집니다.
+	if len(strings.Fields(s)) == 5 {
+		if _, err := cron.NewSchedule(s); err != nil {
+			return Interval{}, fmt.Errorf("invalid cron expression %q: %w", s, err)
+		}
+		return Interval{Cron: s}, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return Interval{}, fmt.Errorf("invalid polling interval %q: must be a duration or a cron expression", s)
+	}
+	if d < time.Second {
+		return Interval{}, fmt.Errorf("invalid polling interval %q: must be at least 1s", s)
+	}
+	return Interval{Every: d}, nil
+}
+
+// String은 Interval을 문자열로 반환합니다.
+func (i Interval) String() string {
+	if i.Cron != "" {
+		return i.Cron
+	}
+	return i.Every.String()
+}
+
+// Start는 폴링 주기에 맞춰 Poller를 실행하도록 등록합니다.
+// cron 표현식은 scheduler에 등록하고, duration은 ctx가 끝날 때까지 별도의 고루틴에서 실행합니다.
+// ctx가 끝나면 실행 중인 Run의 API 요청도 취소됩니다.
+func (p *Poller) Start(ctx context.Context, scheduler *cron.Cron) error {
+	if p.interval.Cron != "" {
+		return scheduler.Add("poller", p.interval.Cron, func() {
+			p.tick(ctx)
+		})
+	}
+
+	go func() {
+		ticker := time.NewTicker(p.interval.Every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.tick(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// tick은 주기마다 호출되어 Poller를 실행합니다.
+// 이전 실행이 끝나지 않았거나 ctx가 이미 끝났으면 이번 주기는 건너뜁니다.
+func (p *Poller) tick(ctx context.Context) {
+	if ctx.Err() != nil {
+		return
+	}
+	log.Println("[Poller] - Request Anime Schedule")
+	report, err := p.Run(ctx)
+	if err != nil {
+		log.Printf("[Poller] - Skipped: %v", err)
+		return
+	}
+	report.Log()
+}
@@ -0,0 +1,54 @@
+package poller
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Interval
+		wantErr bool
+	}{
+		{in: "", want: Interval{Cron: DefaultInterval}},
+		{in: "   ", want: Interval{Cron: DefaultInterval}},
+		{in: "10m", want: Interval{Every: 10 * time.Minute}},
+		{in: " 1h30m ", want: Interval{Every: 90 * time.Minute}},
+		{in: "1s", want: Interval{Every: time.Second}},
+		{in: "*/10 * * * *", want: Interval{Cron: "*/10 * * * *"}},
+		{in: "0 9 * * 1-5", want: Interval{Cron: "0 9 * * 1-5"}},
+		{in: "500ms", wantErr: true},
+		{in: "-1m", wantErr: true},
+		{in: "10", wantErr: true},
+		{in: "every minute", wantErr: true},
+		{in: "61 * * * *", wantErr: true},
+		{in: "* * * *", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseInterval(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseInterval(%q) = %+v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseInterval(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseInterval(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIntervalString(t *testing.T) {
+	if got := (Interval{Every: 10 * time.Minute}).String(); got != "10m0s" {
+		t.Errorf("String() = %q, want %q", got, "10m0s")
+	}
+	if got := (Interval{Cron: "*/5 * * * *"}).String(); got != "*/5 * * * *" {
+		t.Errorf("String() = %q, want %q", got, "*/5 * * * *")
+	}
+}
@@ -1,10 +1,15 @@
 package poller
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"net/http"
+	"sync"
+	"time"
+
+	"github.com/huketo/anisub-scraper/anissia"
+	"github.com/huketo/anisub-scraper/event"
 
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase"
@@ -27,166 +32,216 @@ const (
 	New                      // 신작, 8
 )
 
-// AnimeInfo 구조체는 각 애니메이션에 대한 정보를 정의합니다.
-type AnimeInfo struct {
-	Week         string `json:"week"`
-	AnimeNo      int    `json:"animeNo"`
-	Status       string `json:"status"`
-	Time         string `json:"time"`
-	Subject      string `json:"subject"`
-	Genres       string `json:"genres"`
-	CaptionCount int    `json:"captionCount"`
-	StartDate    string `json:"startDate"`
-	EndDate      string `json:"endDate"`
-	Website      string `json:"website"`
-}
-
-// AnimeScheduleResponse 구조체는 전체 응답을 정의합니다.
-type AnimeScheduleResponse struct {
-	Code string      `json:"code"`
-	Data []AnimeInfo `json:"data"`
-}
+// DefaultWorkers는 동시에 자막 정보를 수집하는 기본 고루틴 수입니다.
+const DefaultWorkers = 4
 
-// SubtitleInfo 구조체는 자막 정보를 정의합니다.
-type SubtitleInfo struct {
-	Episode string `json:"episode"` // 자막 회차
-	UpdDt   string `json:"updDt"`   // 자막 업로드 시간
-	Website string `json:"website"` // 자막 웹사이트
-	Name    string `json:"name"`    // 자막 제작자 이름
-}
-
-// SubtitleResponse 구조체는 전체 응답을 정의합니다.
-type SubtitleResponse struct {
-	Code string         `json:"code"`
-	Data []SubtitleInfo `json:"data"`
-}
+// StatusEnded는 종영된 애니메이션의 상태입니다. 편성표에서 사라진 애니메이션에도 저장합니다.
+const StatusEnded = "END"
 
 // Poller는 주기적으로 API를 통해서 신작 애니메이션 편성표 정보를 받아옵니다.
 // 신작 애니메이션 No로 자막 정보를 수집합니다.
-// 애니메이션 자막이 업로드되면 scraper에 자막 수집 요청을 보냅니다.
+// 저장된 정보와 비교하여 변경 사항을 이벤트 버스로 발행합니다.
 type Poller struct {
-	pollingInterval string // Polling interval
-	app             *pocketbase.PocketBase
+	interval Interval // Polling interval
+	workers  int      // 동시에 API를 요청하는 고루틴 수
+	app      *pocketbase.PocketBase
+	client   anissia.Client // Anissia API 클라이언트
+	bus      *event.Bus     // 변경 사항을 발행할 이벤트 버스
+	runMu    sync.Mutex     // Run이 동시에 실행되지 않도록 막는 잠금
 }
 
+// ErrAlreadyRunning은 이전 Run이 아직 끝나지 않았을 때 반환됩니다.
+var ErrAlreadyRunning = errors.New("poller is already running")
+
 // NewPoller는 Poller를 생성합니다.
-func NewPoller(pollingInterval string, app *pocketbase.PocketBase) *Poller {
+// workers가 0 이하이면 DefaultWorkers를 사용합니다.
+func NewPoller(interval Interval, workers int, app *pocketbase.PocketBase, client anissia.Client, bus *event.Bus) *Poller {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
 	return &Poller{
-		pollingInterval,
-		app,
+		interval: interval,
+		workers:  workers,
+		app:      app,
+		client:   client,
+		bus:      bus,
 	}
 }
 
-// Run은 Poller를 실행합니다.
-func (p *Poller) Run() {
-	// 1. 신작 애니메이션 편성표 정보를 받아옵니다.
-	animeInfos, err := p.GetNewAnimeSchedule()
+// Run은 Poller를 실행하고 실행 결과를 반환합니다.
+// ctx가 끝나면 진행 중인 API 요청을 취소합니다.
+// 이전 Run이 아직 실행 중이면 ErrAlreadyRunning을 반환합니다.
+func (p *Poller) Run(ctx context.Context) (*RunReport, error) {
+	if !p.runMu.TryLock() {
+		return nil, ErrAlreadyRunning
+	}
+	defer p.runMu.Unlock()
+
+	report := &RunReport{StartedAt: time.Now()}
+	defer func() {
+		report.FinishedAt = time.Now()
+	}()
+
+	// 1. 애니메이션 편성표 정보를 받아옵니다.
+	schedule, err := p.GetNewAnimeSchedule(ctx)
 	if err != nil {
-		log.Printf("failed to get new anime schedule: %v", err)
-		return
+		report.addError(0, StageSchedule, err)
+		// 모든 요일의 요청이 실패한 경우에만 실행을 중단합니다.
+		if len(schedule) == 0 {
+			return report, nil
+		}
 	}
-	log.Printf("AnimeCount: %d", len(animeInfos))
-	// 2. 신작 애니메이션 No로 자막 정보를 수집합니다.
-	for _, animeInfo := range animeInfos {
-		subtitleInfos, err := p.GetNewAnimeSubtitleInfo(animeInfo.AnimeNo)
+	// 일부 요일이 실패한 경우에는 편성표에서 사라진 애니메이션을 판별할 수 없습니다.
+	completeSchedule := err == nil
+
+	var animeInfos []anissia.AnimeInfo
+	seen := make(map[int]bool, len(schedule))
+	for _, animeInfo := range schedule {
+		seen[animeInfo.AnimeNo] = true
+		if isActive(animeInfo) {
+			animeInfos = append(animeInfos, animeInfo)
+			continue
+		}
+		// 방영 중이 아닌 애니메이션은 이미 저장된 경우에만 변경 사항을 반영합니다.
+		events, err := p.updateInactiveAnime(animeInfo)
 		if err != nil {
-			log.Printf("failed to get new anime subtitle info for Anime[%d]: %v", animeInfo.AnimeNo, err)
+			report.addError(animeInfo.AnimeNo, StageSave, err)
+		}
+		p.publish(report, events)
+	}
+	report.AnimeCount = len(animeInfos)
+
+	// 2. 신작 애니메이션 No로 자막 정보를 동시에 수집합니다.
+	subtitleInfos := make([][]anissia.SubtitleInfo, len(animeInfos))
+	captionErrs := make([]error, len(animeInfos))
+	forEach(len(animeInfos), p.workers, func(i int) {
+		subtitleInfos[i], captionErrs[i] = p.GetNewAnimeSubtitleInfo(ctx, animeInfos[i].AnimeNo)
+	})
+
+	// DB 저장은 편성표 순서대로 하나씩 처리합니다.
+	for i, animeInfo := range animeInfos {
+		if captionErrs[i] != nil {
+			report.addError(animeInfo.AnimeNo, StageCaption, captionErrs[i])
 			continue // 실패한 경우 다음 애니메이션으로 넘어갑니다.
 		}
-		log.Printf("Anime[%d]-SubtitleCount: %d", animeInfo.AnimeNo, len(subtitleInfos))
+		log.Printf("Anime[%d]-SubtitleCount: %d", animeInfo.AnimeNo, len(subtitleInfos[i]))
 
-		latestSubtitleInfo, ok := ExtractLatestSubtitleInfo(subtitleInfos)
-		if !ok {
-			log.Printf("failed to get latest subtitle info for Anime[%d]", animeInfo.AnimeNo)
+		if len(subtitleInfos[i]) == 0 {
+			report.addError(animeInfo.AnimeNo, StageCaption, errors.New("no subtitle info"))
 			continue // 실패한 경우 다음 애니메이션으로 넘어갑니다.
 		}
-		log.Printf("Anime[%d]-LatestSubtitleInfo: %v", animeInfo.AnimeNo, latestSubtitleInfo)
 
-		// 3. if ok true, 유효한 신작 애니메이션 편성표 정보를 DB에 저장합니다.
-		err = p.SaveNewAnimeSchedule(animeInfo)
+		// 3. 유효한 신작 애니메이션 편성표 정보를 DB에 저장합니다.
+		events, err := p.SaveNewAnimeSchedule(animeInfo)
 		if err != nil {
-			log.Printf("failed to save new anime schedule for Anime[%d]: %v", animeInfo.AnimeNo, err)
+			report.addError(animeInfo.AnimeNo, StageSave, err)
 			continue // 실패한 경우 다음 애니메이션으로 넘어갑니다.
 		}
-		// 4. if ok true, 유효한 신작 애니메이션 자막 정보를 DB에 저장합니다.
-		err = p.SaveNewAnimeSubtitleInfo(animeInfo, latestSubtitleInfo)
+		p.publish(report, events)
+
+		// 4. 모든 회차, 모든 자막 제작자의 자막 정보를 DB에 저장합니다.
+		events, saved, err := p.SaveNewAnimeSubtitleInfos(animeInfo, subtitleInfos[i])
+		report.CaptionCount += saved
+		p.publish(report, events)
 		if err != nil {
-			log.Printf("failed to save new anime subtitle info for Anime[%d]: %v", animeInfo.AnimeNo, err)
+			report.addError(animeInfo.AnimeNo, StageSave, err)
 			continue // 실패한 경우 다음 애니메이션으로 넘어갑니다.
 		}
+		report.SavedCount++
 	}
-}
 
-// 신작 애니메이션 편성표 정보를 받아옵니다.
-func (p *Poller) GetNewAnimeSchedule() ([]AnimeInfo, error) {
-	var animeInfos []AnimeInfo
-	// 요일별로 신작 애니메이션 편성표 정보를 받아옵니다.
-	for _, day := range []weekDay{Sunday, Monday, Tuesday, Wednesday, Thursday, Friday, Saturday, Others} {
-		reqUrl := fmt.Sprintf("https://api.anissia.net/anime/schedule/%d", day)
-		res, err := http.Get(reqUrl)
+	// 5. 편성표에서 사라진 애니메이션을 종영 처리합니다.
+	if completeSchedule {
+		events, err := p.endMissingAnime(seen)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get new anime schedule: %v", err)
+			report.addError(0, StageSave, err)
 		}
-		defer res.Body.Close()
+		p.publish(report, events)
+	}
 
-		// Check response status code
-		if res.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("failed to get new anime schedule: %v", err)
-		}
+	return report, nil
+}
 
-		// JSON을 파싱합니다.
-		var schedule AnimeScheduleResponse
-		err = json.NewDecoder(res.Body).Decode(&schedule)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decode response body: %v", err)
-		}
-		for _, animeInfo := range schedule.Data {
-			if (animeInfo.CaptionCount > 0) && (animeInfo.Status == "ON") {
-				animeInfos = append(animeInfos, animeInfo)
-			}
+// publish는 이벤트를 버스에 발행하고 리포트에 기록합니다.
+func (p *Poller) publish(report *RunReport, events []event.Event) {
+	for _, e := range events {
+		report.EventCount++
+		if p.bus != nil {
+			p.bus.Publish(e)
 		}
 	}
-	return animeInfos, nil
 }
 
-// 신작 애니메이션 No로 자막 정보를 수집합니다.
-func (p *Poller) GetNewAnimeSubtitleInfo(animeNo int) ([]SubtitleInfo, error) {
-	var subtitleInfos []SubtitleInfo
-	reqUrl := fmt.Sprintf("https://api.anissia.net/anime/caption/animeNo/%d", animeNo)
-	res, err := http.Get(reqUrl)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get new anime subtitle info: %v", err)
-	}
-	defer res.Body.Close()
+// isActive는 자막을 수집할 방영 중인 애니메이션인지 판별합니다.
+func isActive(animeInfo anissia.AnimeInfo) bool {
+	return animeInfo.CaptionCount > 0 && animeInfo.Status == "ON"
+}
+
+// 애니메이션 편성표 정보를 받아옵니다.
+// 요일별 요청은 동시에 보내고, 결과는 요일 순서대로 합칩니다.
+// 일부 요일의 요청이 실패하면 나머지 요일의 편성표와 함께 실패한 요일의 에러를 반환합니다.
+func (p *Poller) GetNewAnimeSchedule(ctx context.Context) ([]anissia.AnimeInfo, error) {
+	days := []weekDay{Sunday, Monday, Tuesday, Wednesday, Thursday, Friday, Saturday, Others}
+	schedules := make([][]anissia.AnimeInfo, len(days))
+	errs := make([]error, len(days))
+	// 요일별로 신작 애니메이션 편성표 정보를 받아옵니다.
+	forEach(len(days), p.workers, func(i int) {
+		schedule, err := p.client.GetSchedule(ctx, int(days[i]))
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to get anime schedule for week %d: %w", days[i], err)
+			return
+		}
+		schedules[i] = schedule
+	})
 
-	// Check response status code
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get new anime subtitle info: %v", err)
+	var animeInfos []anissia.AnimeInfo
+	for _, schedule := range schedules {
+		animeInfos = append(animeInfos, schedule...)
 	}
+	return animeInfos, errors.Join(errs...)
+}
 
-	// JSON을 파싱합니다.
-	var subtitleResponse SubtitleResponse
-	err = json.NewDecoder(res.Body).Decode(&subtitleResponse)
+// 신작 애니메이션 No로 자막 정보를 수집합니다.
+func (p *Poller) GetNewAnimeSubtitleInfo(ctx context.Context, animeNo int) ([]anissia.SubtitleInfo, error) {
+	subtitleInfos, err := p.client.GetCaptions(ctx, animeNo)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode response body: %v", err)
+		return nil, fmt.Errorf("failed to get new anime subtitle info: %w", err)
 	}
-	subtitleInfos = subtitleResponse.Data
-
 	return subtitleInfos, nil
 }
 
-func (p *Poller) SaveNewAnimeSchedule(animeInfo AnimeInfo) error {
+// SaveNewAnimeSchedule은 애니메이션 편성표 정보를 DB에 저장하고 변경 사항을 이벤트로 반환합니다.
+// 저장된 정보와 같으면 저장하지 않습니다.
+func (p *Poller) SaveNewAnimeSchedule(animeInfo anissia.AnimeInfo) ([]event.Event, error) {
 	animeInfoCollection, err := p.app.Dao().FindCollectionByNameOrId("anime_info")
 	if err != nil {
-		return fmt.Errorf("failed to find anime_info collection: %v", err)
+		return nil, fmt.Errorf("failed to find anime_info collection: %v", err)
 	}
 	// 이미 DB에 저장된 애니메이션인지 확인합니다.
 	record, err := p.app.Dao().FindFirstRecordByData("anime_info", "anime_no", animeInfo.AnimeNo)
 	if err != nil {
-		log.Printf("failed to find anime_info record: %v", err)
 		// DB에 저장된 애니메이션이 없는 경우, 새로운 애니메이션 정보를 저장합니다.
 		record = models.NewRecord(animeInfoCollection)
-		log.Println("New anime_info record created")
+		log.Printf("New anime_info record created for Anime[%d]", animeInfo.AnimeNo)
+	}
+
+	return p.saveAnimeInfo(record, animeInfo)
+}
+
+// updateInactiveAnime은 방영 중이 아닌 애니메이션이 이미 저장되어 있으면 변경 사항을 반영합니다.
+func (p *Poller) updateInactiveAnime(animeInfo anissia.AnimeInfo) ([]event.Event, error) {
+	record, err := p.app.Dao().FindFirstRecordByData("anime_info", "anime_no", animeInfo.AnimeNo)
+	if err != nil {
+		return nil, nil // 저장된 적 없는 애니메이션은 무시합니다.
+	}
+	return p.saveAnimeInfo(record, animeInfo)
+}
+
+// saveAnimeInfo는 record와 animeInfo를 비교하여 변경된 경우에만 저장합니다.
+func (p *Poller) saveAnimeInfo(record *models.Record, animeInfo anissia.AnimeInfo) ([]event.Event, error) {
+	changes := diffAnimeInfo(record, animeInfo)
+	if len(changes) == 0 {
+		return nil, nil
 	}
 
 	form := forms.NewRecordUpsert(p.app, record)
@@ -204,47 +259,169 @@ func (p *Poller) SaveNewAnimeSchedule(animeInfo AnimeInfo) error {
 	})
 
 	if err := form.Submit(); err != nil {
-		return fmt.Errorf("failed to submit form: %v", err)
+		return nil, fmt.Errorf("failed to submit form: %v", err)
 	}
 
-	return nil
+	var events []event.Event
+	for _, change := range changes {
+		if change.Type == "" {
+			continue // 이벤트가 없는 단순 변경입니다.
+		}
+		events = append(events, event.Event{
+			Type:     change.Type,
+			RecordID: record.Id,
+			AnimeNo:  animeInfo.AnimeNo,
+			Subject:  animeInfo.Subject,
+			Previous: change.Previous,
+		})
+	}
+	return events, nil
 }
 
-func (p *Poller) SaveNewAnimeSubtitleInfo(animeInfo AnimeInfo, subtitleInfo SubtitleInfo) error {
+// endMissingAnime은 종영되지 않은 것으로 저장되어 있지만 편성표에 없는 애니메이션을 종영 처리합니다.
+func (p *Poller) endMissingAnime(seen map[int]bool) ([]event.Event, error) {
+	records, err := p.app.Dao().FindRecordsByFilter(
+		"anime_info",
+		"status != {:status}",
+		"", 0, 0,
+		dbx.Params{"status": StatusEnded},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find anime_info records: %v", err)
+	}
+
+	var events []event.Event
+	for _, record := range records {
+		animeNo := record.GetInt("anime_no")
+		if seen[animeNo] {
+			continue
+		}
+		prevStatus := record.GetString("status")
+		record.Set("status", StatusEnded)
+		if err := p.app.Dao().SaveRecord(record); err != nil {
+			return events, fmt.Errorf("failed to end Anime[%d]: %v", animeNo, err)
+		}
+		events = append(events, event.Event{
+			Type:     event.AnimeEnded,
+			RecordID: record.Id,
+			AnimeNo:  animeNo,
+			Subject:  record.GetString("subject"),
+			Previous: prevStatus,
+		})
+	}
+	return events, nil
+}
+
+// SaveNewAnimeSubtitleInfos는 애니메이션의 모든 자막 정보를 DB에 저장하고 변경 사항을 이벤트로 반환합니다.
+// 자막 정보는 anime_no, episode, name(자막 제작자)의 조합으로 구분하며, 저장된 정보와 같으면 저장하지 않습니다.
+// 일부 자막의 저장에 실패해도 나머지 자막은 저장하고, 저장에 성공한 자막 수와 에러를 함께 반환합니다.
+func (p *Poller) SaveNewAnimeSubtitleInfos(animeInfo anissia.AnimeInfo, subtitleInfos []anissia.SubtitleInfo) ([]event.Event, int, error) {
 	animeSubtitleCollection, err := p.app.Dao().FindCollectionByNameOrId("anime_subtitle")
 	if err != nil {
-		return fmt.Errorf("failed to find anime_subtitle collection: %v", err)
+		return nil, 0, fmt.Errorf("failed to find anime_subtitle collection: %v", err)
 	}
-	// 이미 DB에 저장된 자막인지 확인합니다.
-	// retrieve a single "anime_subtitle" collection record by a string filter expression
-	// (use "{:placeholder}" to safely bind untrusted user input parameters)
-	record, err := p.app.Dao().FindFirstRecordByFilter(
+	// 이미 DB에 저장된 자막 정보를 모두 불러옵니다.
+	records, err := p.app.Dao().FindRecordsByFilter(
 		"anime_subtitle",
-		"anime_no = {:anime_no} && episode = {:episode}",
-		dbx.Params{
-			"anime_no": animeInfo.AnimeNo,
-			"episode":  subtitleInfo.Episode,
-		},
+		"anime_no = {:anime_no}",
+		"", 0, 0,
+		dbx.Params{"anime_no": animeInfo.AnimeNo},
 	)
 	if err != nil {
-		log.Printf("failed to find anime_subtitle record: %v", err)
-		// DB에 저장된 자막이 없는 경우, 새로운 자막 정보를 저장합니다.
-		record = models.NewRecord(animeSubtitleCollection)
-		log.Println("New anime_subtitle record created")
+		return nil, 0, fmt.Errorf("failed to find anime_subtitle records: %v", err)
 	}
+	stored := newStoredSubtitles(records)
 
-	form := forms.NewRecordUpsert(p.app, record)
-	form.LoadData(map[string]any{
-		"anime_no": animeInfo.AnimeNo,
-		"subject":  animeInfo.Subject,
-		"episode":  subtitleInfo.Episode,
-		"name":     subtitleInfo.Name,
-		"website":  subtitleInfo.Website,
-	})
+	var events []event.Event
+	var errs []error
+	saved := 0
+	current := make(map[string]*models.Record, len(subtitleInfos))
+	preferred := MarkPreferredSubtitleInfos(subtitleInfos)
+	for i, subtitleInfo := range subtitleInfos {
+		updDt, err := anissia.ParseUpdDt(subtitleInfo.UpdDt)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to parse updDt of episode %s: %v", subtitleInfo.Episode, err))
+			continue
+		}
 
-	if err := form.Submit(); err != nil {
-		return fmt.Errorf("failed to submit form: %v", err)
+		record := stored.get(subtitleInfo.Episode, subtitleInfo.Name)
+		isNew := record == nil
+		if isNew {
+			// DB에 저장된 자막이 없는 경우, 새로운 자막 정보를 저장합니다.
+			record = models.NewRecord(animeSubtitleCollection)
+		}
+		current[releaseKey(subtitleInfo.Episode, subtitleInfo.Name)] = record
+		changes := diffSubtitleInfo(record, animeInfo.Subject, subtitleInfo, updDt, preferred[i])
+		if len(changes) == 0 {
+			saved++
+			continue
+		}
+
+		form := forms.NewRecordUpsert(p.app, record)
+		form.LoadData(map[string]any{
+			"anime_no":  animeInfo.AnimeNo,
+			"subject":   animeInfo.Subject,
+			"episode":   subtitleInfo.Episode,
+			"name":      subtitleInfo.Name,
+			"website":   subtitleInfo.Website,
+			"upd_dt":    updDt,
+			"preferred": preferred[i],
+		})
+		if err := form.Submit(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to submit form for episode %s by %s: %v", subtitleInfo.Episode, subtitleInfo.Name, err))
+			continue
+		}
+		saved++
+		if isNew {
+			log.Printf("New anime_subtitle record created for Anime[%d] episode %s by %s", animeInfo.AnimeNo, subtitleInfo.Episode, subtitleInfo.Name)
+		}
+
+		for _, change := range changes {
+			if change.Type == "" {
+				continue // 이벤트가 없는 단순 변경입니다.
+			}
+			events = append(events, event.Event{
+				Type:     change.Type,
+				RecordID: record.Id,
+				AnimeNo:  animeInfo.AnimeNo,
+				Subject:  animeInfo.Subject,
+				Episode:  subtitleInfo.Episode,
+				Name:     subtitleInfo.Name,
+			})
+		}
+	}
+
+	// 회차별 선호 자막 제작자가 바뀐 경우를 찾습니다.
+	for i, subtitleInfo := range subtitleInfos {
+		if !preferred[i] {
+			continue
+		}
+		previous := stored.preferredName(subtitleInfo.Episode)
+		if previous == "" || previous == subtitleInfo.Name {
+			continue
+		}
+		record := stored.get(subtitleInfo.Episode, previous)
+		// 응답에 없는 이전 선호 자막은 선호 표시를 해제합니다.
+		if record != nil && !containsRelease(subtitleInfos, subtitleInfo.Episode, previous) {
+			record.Set("preferred", false)
+			if err := p.app.Dao().SaveRecord(record); err != nil {
+				errs = append(errs, fmt.Errorf("failed to unset preferred of episode %s by %s: %v", subtitleInfo.Episode, previous, err))
+			}
+		}
+		var recordID string
+		if record := current[releaseKey(subtitleInfo.Episode, subtitleInfo.Name)]; record != nil {
+			recordID = record.Id
+		}
+		events = append(events, event.Event{
+			Type:     event.ReleaserChanged,
+			RecordID: recordID,
+			AnimeNo:  animeInfo.AnimeNo,
+			Subject:  animeInfo.Subject,
+			Episode:  subtitleInfo.Episode,
+			Name:     subtitleInfo.Name,
+			Previous: previous,
+		})
 	}
 
-	return nil
+	return events, saved, errors.Join(errs...)
 }
@@ -0,0 +1,51 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/huketo/anisub-scraper/anissia"
+)
+
+// fakeClient는 요일별 편성표를 반환하는 anissia.Client입니다.
+// errs에 있는 요일은 에러를 반환하고, 앞 요일일수록 늦게 응답합니다.
+type fakeClient struct {
+	errs map[int]error
+}
+
+func (f *fakeClient) GetSchedule(ctx context.Context, week int) ([]anissia.AnimeInfo, error) {
+	time.Sleep(time.Duration(8-week) * time.Millisecond)
+	if err := f.errs[week]; err != nil {
+		return nil, err
+	}
+	return []anissia.AnimeInfo{{AnimeNo: week*10 + 1}, {AnimeNo: week*10 + 2}}, nil
+}
+
+func (f *fakeClient) GetCaptions(ctx context.Context, animeNo int) ([]anissia.SubtitleInfo, error) {
+	return nil, nil
+}
+
+func TestGetNewAnimeSchedule(t *testing.T) {
+	errMonday := errors.New("monday failed")
+	errFriday := errors.New("friday failed")
+	p := NewPoller(Interval{}, 3, nil, &fakeClient{errs: map[int]error{int(Monday): errMonday, int(Friday): errFriday}}, nil)
+
+	animeInfos, err := p.GetNewAnimeSchedule(context.Background())
+
+	// 실패한 요일의 에러를 모두 반환합니다.
+	if !errors.Is(err, errMonday) || !errors.Is(err, errFriday) {
+		t.Errorf("GetNewAnimeSchedule() error = %v, want monday and friday errors", err)
+	}
+	// 나머지 요일의 편성표는 응답 순서와 관계없이 요일 순서대로 반환합니다.
+	var got []int
+	for _, animeInfo := range animeInfos {
+		got = append(got, animeInfo.AnimeNo)
+	}
+	want := []int{1, 2, 21, 22, 31, 32, 41, 42, 61, 62, 71, 72}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetNewAnimeSchedule() = %v, want %v", got, want)
+	}
+}
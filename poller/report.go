@@ -0,0 +1,63 @@
+package poller
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// RunStage는 Poller.Run에서 에러가 발생한 단계를 나타냅니다.
+type RunStage string
+
+const (
+	// StageSchedule은 편성표 수집 단계를 나타냅니다.
+	StageSchedule RunStage = "schedule"
+	// StageCaption은 자막 정보 수집 단계를 나타냅니다.
+	StageCaption RunStage = "caption"
+	// StageSave는 DB 저장 단계를 나타냅니다.
+	StageSave RunStage = "save"
+)
+
+// RunError는 Poller.Run 도중 발생한 에러입니다.
+// 애니메이션과 관계없는 에러(예: 요일별 편성표 수집 실패)는 AnimeNo가 0입니다.
+type RunError struct {
+	AnimeNo int      // 애니메이션 No
+	Stage   RunStage // 에러가 발생한 단계
+	Err     error    // 에러
+}
+
+func (e RunError) Error() string {
+	if e.AnimeNo == 0 {
+		return fmt.Sprintf("[%s] %v", e.Stage, e.Err)
+	}
+	return fmt.Sprintf("[%s] Anime[%d]: %v", e.Stage, e.AnimeNo, e.Err)
+}
+
+func (e RunError) Unwrap() error {
+	return e.Err
+}
+
+// RunReport는 Poller.Run 한 번의 실행 결과입니다.
+type RunReport struct {
+	StartedAt    time.Time  // 실행 시작 시간
+	FinishedAt   time.Time  // 실행 종료 시간
+	AnimeCount   int        // 자막이 있는 방영 중 애니메이션 수
+	SavedCount   int        // 편성표와 모든 자막 정보의 저장에 성공한 애니메이션 수
+	CaptionCount int        // 저장(또는 변경 없음 확인)에 성공한 자막 정보 수
+	EventCount   int        // 발행한 이벤트 수
+	Errors       []RunError // 실행 도중 발생한 에러
+}
+
+// addError는 에러를 리포트에 추가합니다.
+func (r *RunReport) addError(animeNo int, stage RunStage, err error) {
+	r.Errors = append(r.Errors, RunError{AnimeNo: animeNo, Stage: stage, Err: err})
+}
+
+// Log는 리포트를 로그로 남깁니다.
+func (r *RunReport) Log() {
+	log.Printf("[Poller] - Run finished in %s: anime=%d saved=%d captions=%d events=%d errors=%d",
+		r.FinishedAt.Sub(r.StartedAt).Round(time.Millisecond), r.AnimeCount, r.SavedCount, r.CaptionCount, r.EventCount, len(r.Errors))
+	for _, err := range r.Errors {
+		log.Printf("[Poller] - %v", err)
+	}
+}
@@ -3,19 +3,22 @@ package poller
 import (
 	"log"
 	"strconv"
+	"sync"
 	"time"
+
+	"github.com/huketo/anisub-scraper/anissia"
 )
 
 // ExtractLatestSubtitleInfo 함수는 제공된 자막 정보 슬라이스에서 최신 자막 정보를 추출합니다.
 // 만약 website 필드가 비어 있는 경우, false를 반환합니다.
-func ExtractLatestSubtitleInfo(subtitleInfos []SubtitleInfo) (SubtitleInfo, bool) {
-	var latestSubtitleInfo SubtitleInfo
+func ExtractLatestSubtitleInfo(subtitleInfos []anissia.SubtitleInfo) (anissia.SubtitleInfo, bool) {
+	var latestSubtitleInfo anissia.SubtitleInfo
 	if len(subtitleInfos) > 0 {
 		maxEpisode, _ := strconv.ParseFloat(subtitleInfos[0].Episode, 64)
-		maxUpdDtParsed, err := time.Parse("2006-01-02T15:04:05", subtitleInfos[0].UpdDt)
+		maxUpdDtParsed, err := anissia.ParseUpdDt(subtitleInfos[0].UpdDt)
 		if err != nil {
 			log.Printf("failed to parse date: %v", err)
-			return SubtitleInfo{}, false
+			return anissia.SubtitleInfo{}, false
 		}
 		latestSubtitleInfo = subtitleInfos[0]
 
@@ -26,7 +29,7 @@ func ExtractLatestSubtitleInfo(subtitleInfos []SubtitleInfo) (SubtitleInfo, bool
 				continue
 			}
 
-			updDtParsed, err := time.Parse("2006-01-02T15:04:05", subtitleInfo.UpdDt)
+			updDtParsed, err := anissia.ParseUpdDt(subtitleInfo.UpdDt)
 			if err != nil {
 				log.Printf("failed to parse date: %v", err)
 				continue
@@ -43,5 +46,66 @@ func ExtractLatestSubtitleInfo(subtitleInfos []SubtitleInfo) (SubtitleInfo, bool
 			return latestSubtitleInfo, true
 		}
 	}
-	return SubtitleInfo{}, false
+	return anissia.SubtitleInfo{}, false
+}
+
+// MarkPreferredSubtitleInfos 함수는 회차별로 선호 자막을 표시합니다.
+// 같은 회차에서는 웹사이트가 있고 가장 먼저 업로드된 자막이 선호 자막이 됩니다.
+// 반환값의 i번째 값은 subtitleInfos[i]가 선호 자막인지를 나타냅니다.
+func MarkPreferredSubtitleInfos(subtitleInfos []anissia.SubtitleInfo) []bool {
+	preferred := make([]bool, len(subtitleInfos))
+	// 회차별로 선호 자막의 인덱스와 업로드 시간을 저장합니다.
+	type candidate struct {
+		index int
+		updDt time.Time
+	}
+	candidates := make(map[string]candidate)
+
+	for i, subtitleInfo := range subtitleInfos {
+		if subtitleInfo.Website == "" {
+			continue
+		}
+		updDt, err := anissia.ParseUpdDt(subtitleInfo.UpdDt)
+		if err != nil {
+			log.Printf("failed to parse date: %v", err)
+			continue
+		}
+		current, ok := candidates[subtitleInfo.Episode]
+		if !ok || updDt.Before(current.updDt) {
+			candidates[subtitleInfo.Episode] = candidate{index: i, updDt: updDt}
+		}
+	}
+
+	for _, c := range candidates {
+		preferred[c.index] = true
+	}
+	return preferred
+}
+
+// forEach는 0부터 n-1까지의 인덱스에 대해 fn을 최대 workers개의 고루틴으로 나누어 실행합니다.
+// 결과는 호출자가 인덱스로 저장하므로 실행 순서와 관계없이 결과 순서가 유지됩니다.
+func forEach(n, workers int, fn func(i int)) {
+	if workers <= 0 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
 }
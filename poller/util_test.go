@@ -0,0 +1,137 @@
+package poller
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/huketo/anisub-scraper/anissia"
+)
+
+func TestMarkPreferredSubtitleInfos(t *testing.T) {
+	tests := []struct {
+		name          string
+		subtitleInfos []anissia.SubtitleInfo
+		want          []bool
+	}{
+		{
+			name: "earliest upload",
+			subtitleInfos: []anissia.SubtitleInfo{
+				{Episode: "1", UpdDt: "2023-10-07T02:00:00", Website: "https://b.example.com", Name: "b"},
+				{Episode: "1", UpdDt: "2023-10-07T01:00:00", Website: "https://a.example.com", Name: "a"},
+				{Episode: "1", UpdDt: "2023-10-07T03:00:00", Website: "https://c.example.com", Name: "c"},
+			},
+			want: []bool{false, true, false},
+		},
+		{
+			// 업로드 시간이 같으면 먼저 나온 자막을 선호합니다.
+			name: "tie keeps the first",
+			subtitleInfos: []anissia.SubtitleInfo{
+				{Episode: "1", UpdDt: "2023-10-07T01:00:00", Website: "https://a.example.com", Name: "a"},
+				{Episode: "1", UpdDt: "2023-10-07T01:00:00", Website: "https://b.example.com", Name: "b"},
+			},
+			want: []bool{true, false},
+		},
+		{
+			// 웹사이트가 없는 자막은 가장 먼저 올라왔어도 선호 자막이 아닙니다.
+			name: "missing website",
+			subtitleInfos: []anissia.SubtitleInfo{
+				{Episode: "1", UpdDt: "2023-10-07T01:00:00", Name: "a"},
+				{Episode: "1", UpdDt: "2023-10-07T02:00:00", Website: "https://b.example.com", Name: "b"},
+			},
+			want: []bool{false, true},
+		},
+		{
+			name: "only missing websites",
+			subtitleInfos: []anissia.SubtitleInfo{
+				{Episode: "1", UpdDt: "2023-10-07T01:00:00", Name: "a"},
+				{Episode: "1", UpdDt: "2023-10-07T02:00:00", Name: "b"},
+			},
+			want: []bool{false, false},
+		},
+		{
+			// 업로드 시간을 알 수 없는 자막은 건너뜁니다.
+			name: "invalid upload time",
+			subtitleInfos: []anissia.SubtitleInfo{
+				{Episode: "1", UpdDt: "yesterday", Website: "https://a.example.com", Name: "a"},
+				{Episode: "1", UpdDt: "2023-10-07T02:00:00", Website: "https://b.example.com", Name: "b"},
+			},
+			want: []bool{false, true},
+		},
+		{
+			// 회차마다 선호 자막을 하나씩 고릅니다.
+			name: "multiple episodes",
+			subtitleInfos: []anissia.SubtitleInfo{
+				{Episode: "2", UpdDt: "2023-10-14T03:00:00", Website: "https://a.example.com", Name: "a"},
+				{Episode: "1", UpdDt: "2023-10-07T02:00:00", Website: "https://a.example.com", Name: "a"},
+				{Episode: "2", UpdDt: "2023-10-14T01:00:00", Website: "https://b.example.com", Name: "b"},
+				{Episode: "1", UpdDt: "2023-10-07T01:00:00", Website: "https://b.example.com", Name: "b"},
+				{Episode: "3", UpdDt: "2023-10-21T01:00:00", Name: "b"},
+				{Episode: "2.5", UpdDt: "2023-10-17T01:00:00", Website: "https://a.example.com", Name: "a"},
+			},
+			want: []bool{false, false, true, true, false, true},
+		},
+		{name: "empty", want: []bool{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MarkPreferredSubtitleInfos(tt.subtitleInfos); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MarkPreferredSubtitleInfos() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForEach(t *testing.T) {
+	tests := []struct {
+		name        string
+		n           int
+		workers     int
+		wantWorkers int // 동시에 실행되어야 하는 fn의 수
+	}{
+		{name: "more items than workers", n: 10, workers: 3, wantWorkers: 3},
+		{name: "more workers than items", n: 2, workers: 5, wantWorkers: 2},
+		{name: "no workers runs one at a time", n: 5, workers: 0, wantWorkers: 1},
+		{name: "no items", n: 0, workers: 4, wantWorkers: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inFlight, maxInFlight int32
+			// 처음 wantWorkers개의 fn은 모두 동시에 실행될 때까지 기다립니다.
+			var ready sync.WaitGroup
+			ready.Add(tt.wantWorkers)
+			results := make([]int, tt.n)
+			calls := make([]int32, tt.n)
+
+			forEach(tt.n, tt.workers, func(i int) {
+				current := atomic.AddInt32(&inFlight, 1)
+				defer atomic.AddInt32(&inFlight, -1)
+				for {
+					seen := atomic.LoadInt32(&maxInFlight)
+					if current <= seen || atomic.CompareAndSwapInt32(&maxInFlight, seen, current) {
+						break
+					}
+				}
+				if i < tt.wantWorkers {
+					ready.Done()
+					ready.Wait()
+				}
+				atomic.AddInt32(&calls[i], 1)
+				results[i] = i * i
+			})
+
+			if got := atomic.LoadInt32(&maxInFlight); int(got) != tt.wantWorkers {
+				t.Errorf("max in-flight = %d, want %d", got, tt.wantWorkers)
+			}
+			// 결과는 실행 순서와 관계없이 인덱스 순서대로 저장됩니다.
+			for i := range results {
+				if calls[i] != 1 || results[i] != i*i {
+					t.Errorf("index %d: calls = %d, result = %d, want 1, %d", i, calls[i], results[i], i*i)
+				}
+			}
+		})
+	}
+}
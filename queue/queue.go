@@ -0,0 +1,510 @@
+package queue
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// Stage는 작업 단계를 나타냅니다.
+type Stage string
+
+const (
+	// StageScrape는 블로그 글에서 다운로드 링크를 찾는 단계입니다.
+	StageScrape Stage = "scrape"
+	// StageDownload는 자막 파일을 다운로드하는 단계입니다.
+	StageDownload Stage = "download"
+	// StageUnpack은 압축 파일을 푸는 단계입니다.
+	StageUnpack Stage = "unpack"
+)
+
+// State는 작업 상태를 나타냅니다.
+type State string
+
+const (
+	// StatePending은 실행을 기다리는 상태입니다.
+	StatePending State = "pending"
+	// StateRunning은 워커가 작업을 점유하고 실행 중인 상태입니다.
+	StateRunning State = "running"
+	// StateSucceeded는 작업이 성공한 상태입니다.
+	StateSucceeded State = "succeeded"
+	// StateFailed는 작업이 실패했고 next_run_at 이후에 재시도할 상태입니다.
+	StateFailed State = "failed"
+	// StateDead는 재시도 횟수를 모두 썼거나 재시도할 수 없는 에러로 실패한 상태입니다.
+	StateDead State = "dead"
+)
+
+// collectionName은 작업을 저장하는 collection 이름입니다.
+const collectionName = "jobs"
+
+// Config는 Queue의 설정입니다.
+type Config struct {
+	LeaseDuration time.Duration // 워커가 작업을 점유하는 시간
+	MaxAttempts   int           // 최대 시도 횟수
+	MinBackoff    time.Duration // 첫 재시도 대기 시간
+	MaxBackoff    time.Duration // 최대 재시도 대기 시간
+	PollInterval  time.Duration // 실행할 작업이 없을 때 다시 확인하기까지의 대기 시간
+	MaxRunTime    time.Duration // 작업 한 번의 최대 실행 시간, 넘으면 Handler의 ctx를 취소하고 실패 처리합니다.
+}
+
+// DefaultConfig는 기본 설정을 반환합니다.
+func DefaultConfig() Config {
+	return Config{
+		LeaseDuration: 5 * time.Minute,
+		MaxAttempts:   5,
+		MinBackoff:    30 * time.Second,
+		MaxBackoff:    time.Hour,
+		PollInterval:  5 * time.Second,
+		MaxRunTime:    time.Hour,
+	}
+}
+
+// Job은 "jobs" collection에 저장된 작업입니다.
+type Job struct {
+	record *models.Record
+	lease  string // Claim할 때 lease_owner에 저장한 점유 토큰, 점유하지 않은 작업은 비어 있습니다.
+}
+
+// ID는 작업 ID를 반환합니다.
+func (j *Job) ID() string {
+	return j.record.Id
+}
+
+// Stage는 작업 단계를 반환합니다.
+func (j *Job) Stage() Stage {
+	return Stage(j.record.GetString("stage"))
+}
+
+// State는 작업 상태를 반환합니다.
+func (j *Job) State() State {
+	return State(j.record.GetString("state"))
+}
+
+// SubtitleID는 작업 대상 anime_subtitle 레코드 ID를 반환합니다.
+func (j *Job) SubtitleID() string {
+	return j.record.GetString("subtitle_id")
+}
+
+// Attempts는 지금까지 시도한 횟수를 반환합니다.
+func (j *Job) Attempts() int {
+	return j.record.GetInt("attempts")
+}
+
+// LastError는 마지막으로 실패한 에러 메시지를 반환합니다.
+func (j *Job) LastError() string {
+	return j.record.GetString("last_error")
+}
+
+// DecodePayload는 작업 payload를 v에 디코딩합니다.
+func (j *Job) DecodePayload(v any) error {
+	return j.record.UnmarshalJSONField("payload", v)
+}
+
+// permanentError는 재시도하지 않을 에러입니다.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent는 err를 재시도하지 않을 에러로 감쌉니다.
+// Handler가 Permanent 에러를 반환하면 작업은 바로 dead 상태가 됩니다.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// ErrLeaseLost는 점유 시간이 지나 다른 워커가 작업을 가져갔거나 작업이 이미 끝났을 때 반환됩니다.
+// 이 에러를 받은 워커는 작업의 결과를 저장하지 않아야 합니다.
+var ErrLeaseLost = errors.New("job lease lost")
+
+// IsPermanent는 err가 재시도하지 않을 에러인지 확인합니다.
+func IsPermanent(err error) bool {
+	var permanentErr *permanentError
+	return errors.As(err, &permanentErr)
+}
+
+// Queue는 PocketBase에 저장되는 작업 큐입니다.
+// 워커는 lease_expires_at까지 작업을 점유하며, 프로세스가 죽어 점유 시간이 지나면
+// 다른 워커(또는 재시작한 프로세스)가 작업을 다시 가져갑니다.
+// 점유할 때마다 lease_owner에 새 토큰을 저장하므로, 같은 프로세스의 다른 워커가 다시 가져간 작업도
+// 처음 워커가 덮어쓰지 못합니다.
+type Queue struct {
+	app     *pocketbase.PocketBase
+	config  Config
+	owner   string        // 이 프로세스를 구분하는 ID, 점유 토큰의 접두사입니다.
+	settled []SettledHook // 작업이 끝났을 때 호출할 함수
+	workers sync.WaitGroup
+}
+
+// SettledHook은 작업이 성공하거나 실패하여 상태가 저장된 뒤 호출됩니다.
+type SettledHook func(job *Job)
+
+// NewQueue는 Queue를 생성합니다.
+// 설정되지 않은 값은 DefaultConfig의 값을 사용합니다.
+func NewQueue(app *pocketbase.PocketBase, config Config) *Queue {
+	defaults := DefaultConfig()
+	if config.LeaseDuration <= 0 {
+		config.LeaseDuration = defaults.LeaseDuration
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaults.MaxAttempts
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = defaults.MinBackoff
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = config.MinBackoff
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaults.PollInterval
+	}
+	if config.MaxRunTime <= 0 {
+		config.MaxRunTime = defaults.MaxRunTime
+	}
+
+	hostname, _ := os.Hostname()
+	owner := hostname + "-" + strconv.Itoa(os.Getpid()) + "-" + security.RandomString(6)
+
+	return &Queue{
+		app:    app,
+		config: config,
+		owner:  owner,
+	}
+}
+
+// OnSettled는 작업이 끝났을 때 호출할 함수를 등록합니다.
+// Start 전에 등록해야 합니다.
+func (q *Queue) OnSettled(hook SettledHook) {
+	q.settled = append(q.settled, hook)
+}
+
+// Enqueue는 새로운 작업을 추가합니다.
+func (q *Queue) Enqueue(stage Stage, subtitleID string, payload any) (*Job, error) {
+	return q.enqueue(stage, subtitleID, "", payload)
+}
+
+// EnqueueUnique는 dedupKey로 추가된 작업이 없을 때만 새로운 작업을 추가합니다.
+// 이미 있으면 상태와 관계없이 그 작업을 반환합니다.
+// 작업을 추가한 handler가 점유를 잃어 다시 실행되어도 같은 작업을 두 번 추가하지 않도록 하기 위해서입니다.
+func (q *Queue) EnqueueUnique(stage Stage, subtitleID string, dedupKey string, payload any) (*Job, error) {
+	if dedupKey == "" {
+		return nil, errors.New("empty job dedup key")
+	}
+	if job, err := q.findByDedupKey(dedupKey); job != nil || err != nil {
+		return job, err
+	}
+
+	job, err := q.enqueue(stage, subtitleID, dedupKey, payload)
+	if err != nil {
+		// 다른 워커가 먼저 추가했으면 유니크 인덱스 때문에 저장하지 못합니다.
+		if existing, findErr := q.findByDedupKey(dedupKey); existing != nil && findErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// enqueue는 작업을 저장합니다. dedupKey가 비어 있으면 중복을 확인하지 않습니다.
+func (q *Queue) enqueue(stage Stage, subtitleID string, dedupKey string, payload any) (*Job, error) {
+	collection, err := q.app.Dao().FindCollectionByNameOrId(collectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find jobs collection: %w", err)
+	}
+
+	record := models.NewRecord(collection)
+	record.Set("stage", string(stage))
+	record.Set("state", string(StatePending))
+	record.Set("subtitle_id", subtitleID)
+	record.Set("payload", payload)
+	record.Set("attempts", 0)
+	record.Set("max_attempts", q.config.MaxAttempts)
+	record.Set("next_run_at", types.NowDateTime())
+	record.Set("dedup_key", dedupKey)
+	if err := q.app.Dao().SaveRecord(record); err != nil {
+		return nil, fmt.Errorf("failed to save job: %w", err)
+	}
+
+	return &Job{record: record}, nil
+}
+
+// findByDedupKey는 dedupKey로 추가된 작업을 찾습니다. 없으면 nil을 반환합니다.
+func (q *Queue) findByDedupKey(dedupKey string) (*Job, error) {
+	record, err := q.app.Dao().FindFirstRecordByData(collectionName, "dedup_key", dedupKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find job: %w", err)
+	}
+	return &Job{record: record}, nil
+}
+
+// Claim은 stage의 실행 가능한 작업 하나를 점유합니다.
+// 실행 가능한 작업이 없으면 nil을 반환합니다.
+// 점유 시간이 지난 running 작업이 이미 최대 시도 횟수만큼 실행되었으면 다시 실행하지 않고 dead 상태로 저장합니다.
+// 작업을 실행할 때마다 프로세스가 죽는 경우에도 작업이 끝나도록 하기 위해서입니다.
+func (q *Queue) Claim(stage Stage) (*Job, error) {
+	var claimed *models.Record
+	var dead []*models.Record
+	lease := q.owner + "-" + security.RandomString(8)
+	err := q.app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		dead = nil
+		now := types.NowDateTime()
+		for {
+			records, err := txDao.FindRecordsByFilter(
+				collectionName,
+				"stage = {:stage} && (((state = {:pending} || state = {:failed}) && next_run_at <= {:now}) || (state = {:running} && lease_expires_at < {:now}))",
+				"next_run_at",
+				1, 0,
+				dbx.Params{
+					"stage":   string(stage),
+					"pending": string(StatePending),
+					"failed":  string(StateFailed),
+					"running": string(StateRunning),
+					"now":     now.String(),
+				},
+			)
+			if err != nil {
+				return err
+			}
+			if len(records) == 0 {
+				return nil
+			}
+
+			record := records[0]
+			attempts := record.GetInt("attempts")
+			if State(record.GetString("state")) == StateRunning && attempts >= q.maxAttempts(record) {
+				record.Set("state", string(StateDead))
+				record.Set("last_error", fmt.Sprintf("lease expired after %d attempts", attempts))
+				clearLease(record)
+				if err := txDao.SaveRecord(record); err != nil {
+					return err
+				}
+				dead = append(dead, record)
+				continue
+			}
+
+			leaseExpiresAt, _ := types.ParseDateTime(now.Time().Add(q.config.LeaseDuration))
+			record.Set("state", string(StateRunning))
+			record.Set("attempts", attempts+1)
+			record.Set("lease_owner", lease)
+			record.Set("lease_expires_at", leaseExpiresAt)
+			if err := txDao.SaveRecord(record); err != nil {
+				return err
+			}
+			claimed = record
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim %s job: %w", stage, err)
+	}
+	for _, record := range dead {
+		log.Printf("[Queue] - %s job %s is dead: %s", stage, record.Id, record.GetString("last_error"))
+		q.settle(&Job{record: record})
+	}
+	if claimed == nil {
+		return nil, nil
+	}
+
+	return &Job{record: claimed, lease: lease}, nil
+}
+
+// maxAttempts는 작업 레코드의 최대 시도 횟수를 반환합니다. 저장되지 않았으면 설정의 값을 사용합니다.
+func (q *Queue) maxAttempts(record *models.Record) int {
+	if maxAttempts := record.GetInt("max_attempts"); maxAttempts > 0 {
+		return maxAttempts
+	}
+	return q.config.MaxAttempts
+}
+
+// settle은 끝난 작업으로 등록된 SettledHook을 호출합니다.
+func (q *Queue) settle(job *Job) {
+	for _, hook := range q.settled {
+		hook(job)
+	}
+}
+
+// updateLeased는 트랜잭션 안에서 작업을 다시 읽고, job이 아직 작업을 점유하고 있을 때만 update를 적용해 저장합니다.
+// lease_owner가 job의 점유 토큰과 같고, running 상태이며, 시도 횟수가 Claim할 때와 같아야 합니다.
+// 그렇지 않으면 ErrLeaseLost를 반환합니다.
+// job은 바꾸지 않고 저장한 레코드를 반환합니다. job을 실행 중인 handler가 동시에 읽을 수 있기 때문입니다.
+func (q *Queue) updateLeased(job *Job, update func(record *models.Record)) (*models.Record, error) {
+	var saved *models.Record
+	err := q.app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		record, err := txDao.FindRecordById(collectionName, job.ID())
+		if err != nil {
+			return err
+		}
+		if job.lease == "" ||
+			record.GetString("lease_owner") != job.lease ||
+			State(record.GetString("state")) != StateRunning ||
+			record.GetInt("attempts") != job.Attempts() {
+			return ErrLeaseLost
+		}
+		update(record)
+		if err := txDao.SaveRecord(record); err != nil {
+			return err
+		}
+		saved = record
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// Renew는 작업의 점유 시간을 연장합니다.
+// 저장된 lease_expires_at만 바꾸며, handler가 읽는 job은 바꾸지 않습니다.
+// 다른 워커가 작업을 가져간 경우 ErrLeaseLost를 반환합니다.
+func (q *Queue) Renew(job *Job) error {
+	_, err := q.updateLeased(job, func(record *models.Record) {
+		leaseExpiresAt, _ := types.ParseDateTime(time.Now().Add(q.config.LeaseDuration))
+		record.Set("lease_expires_at", leaseExpiresAt)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to renew job %s: %w", job.ID(), err)
+	}
+	return nil
+}
+
+// Complete는 작업을 성공 상태로 저장합니다.
+// 다른 워커가 작업을 가져간 경우 저장하지 않고 ErrLeaseLost를 반환합니다.
+// handler와 heartbeat가 끝난 뒤에 호출해야 합니다.
+func (q *Queue) Complete(job *Job) error {
+	saved, err := q.updateLeased(job, func(record *models.Record) {
+		record.Set("state", string(StateSucceeded))
+		record.Set("last_error", "")
+		clearLease(record)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete job %s: %w", job.ID(), err)
+	}
+	job.record = saved
+	return nil
+}
+
+// Fail은 작업을 실패 상태로 저장합니다.
+// 재시도 횟수가 남아 있으면 백오프 후에 재시도하고, 없거나 Permanent 에러이면 dead 상태가 됩니다.
+// 다른 워커가 작업을 가져간 경우 저장하지 않고 ErrLeaseLost를 반환합니다.
+// handler와 heartbeat가 끝난 뒤에 호출해야 합니다.
+func (q *Queue) Fail(job *Job, cause error) error {
+	saved, err := q.updateLeased(job, func(record *models.Record) {
+		attempts := record.GetInt("attempts")
+		if IsPermanent(cause) || attempts >= q.maxAttempts(record) {
+			record.Set("state", string(StateDead))
+		} else {
+			nextRunAt, _ := types.ParseDateTime(time.Now().Add(q.backoff(attempts)))
+			record.Set("state", string(StateFailed))
+			record.Set("next_run_at", nextRunAt)
+		}
+		record.Set("last_error", cause.Error())
+		clearLease(record)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fail job %s: %w", job.ID(), err)
+	}
+	job.record = saved
+	return nil
+}
+
+// Release는 시도 횟수를 늘리지 않고 작업을 다시 pending 상태로 돌려놓습니다.
+// 프로세스가 종료되어 작업을 중단할 때 사용합니다.
+// 다른 워커가 작업을 가져간 경우 저장하지 않고 ErrLeaseLost를 반환합니다.
+// handler와 heartbeat가 끝난 뒤에 호출해야 합니다.
+func (q *Queue) Release(job *Job) error {
+	saved, err := q.updateLeased(job, func(record *models.Record) {
+		record.Set("state", string(StatePending))
+		record.Set("attempts", record.GetInt("attempts")-1)
+		clearLease(record)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release job %s: %w", job.ID(), err)
+	}
+	job.record = saved
+	return nil
+}
+
+// Jobs는 subtitleID에 대한 모든 작업을 생성 순서대로 반환합니다.
+func (q *Queue) Jobs(subtitleID string) ([]*Job, error) {
+	records, err := q.app.Dao().FindRecordsByFilter(
+		collectionName,
+		"subtitle_id = {:subtitle_id}",
+		"created",
+		0, 0,
+		dbx.Params{"subtitle_id": subtitleID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find jobs: %w", err)
+	}
+
+	jobs := make([]*Job, len(records))
+	for i, record := range records {
+		jobs[i] = &Job{record: record}
+	}
+	return jobs, nil
+}
+
+// HasActive는 subtitleID에 대해 아직 끝나지 않은 stage 작업이 있는지 확인합니다.
+func (q *Queue) HasActive(stage Stage, subtitleID string) (bool, error) {
+	jobs, err := q.Jobs(subtitleID)
+	if err != nil {
+		return false, err
+	}
+	for _, job := range jobs {
+		if job.Stage() == stage && job.Active() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Active는 작업이 아직 끝나지 않았는지(pending, running, failed) 확인합니다.
+func (j *Job) Active() bool {
+	switch j.State() {
+	case StatePending, StateRunning, StateFailed:
+		return true
+	}
+	return false
+}
+
+// clearLease는 작업 레코드의 점유 정보를 지웁니다.
+func clearLease(record *models.Record) {
+	record.Set("lease_owner", "")
+	record.Set("lease_expires_at", "")
+}
+
+// backoff는 attempts번 실패한 작업을 다시 실행하기 전에 기다릴 시간을 계산합니다.
+func (q *Queue) backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	wait := q.config.MinBackoff << (attempts - 1)
+	if wait <= 0 || wait > q.config.MaxBackoff {
+		wait = q.config.MaxBackoff
+	}
+	return wait
+}
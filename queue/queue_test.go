@@ -0,0 +1,459 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huketo/anisub-scraper/internal/testapp"
+
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// testConfig는 테스트가 빨리 끝나도록 짧은 시간을 사용하는 설정입니다.
+func testConfig() Config {
+	return Config{
+		LeaseDuration: 300 * time.Millisecond,
+		MaxAttempts:   3,
+		MinBackoff:    10 * time.Millisecond,
+		MaxBackoff:    40 * time.Millisecond,
+		PollInterval:  10 * time.Millisecond,
+		MaxRunTime:    10 * time.Second,
+	}
+}
+
+// testPayload는 테스트 작업의 payload입니다.
+type testPayload struct {
+	URL string `json:"url"`
+}
+
+// findJob은 저장된 작업을 다시 읽습니다.
+func findJob(t *testing.T, app *pocketbase.PocketBase, id string) *Job {
+	t.Helper()
+	record, err := app.Dao().FindRecordById(collectionName, id)
+	if err != nil {
+		t.Fatalf("failed to find job %s: %v", id, err)
+	}
+	return &Job{record: record}
+}
+
+// waitJob은 저장된 작업이 cond를 만족할 때까지 기다립니다.
+func waitJob(t *testing.T, app *pocketbase.PocketBase, id string, cond func(job *Job) bool) *Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job := findJob(t, app, id)
+		if cond(job) {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s: state = %s, attempts = %d, last_error = %q", id, job.State(), job.Attempts(), job.LastError())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// startWorker는 q의 워커 하나를 실행하고, 테스트가 끝나면 멈춘 뒤 끝날 때까지 기다립니다.
+// 반환하는 함수로 테스트 중에 워커를 멈출 수 있습니다.
+func startWorker(t *testing.T, q *Queue, stage Stage, handler Handler) (stop func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		q.work(ctx, stage, handler)
+	}()
+	stop = func() {
+		cancel()
+		wg.Wait()
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+// settledJobs는 q에서 끝난 작업을 받는 채널을 등록합니다.
+func settledJobs(q *Queue) <-chan *Job {
+	ch := make(chan *Job, 10)
+	q.OnSettled(func(job *Job) { ch <- job })
+	return ch
+}
+
+// waitSettled는 끝난 작업을 기다립니다.
+func waitSettled(t *testing.T, settled <-chan *Job) *Job {
+	t.Helper()
+	select {
+	case job := <-settled:
+		return job
+	case <-time.After(5 * time.Second):
+		t.Fatal("no job settled")
+		return nil
+	}
+}
+
+func TestEnqueueClaim(t *testing.T) {
+	app := testapp.New(t)
+	q := NewQueue(app, testConfig())
+
+	first, err := q.Enqueue(StageDownload, "SUBTITLE", testPayload{URL: "https://example.com/1"})
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := q.Enqueue(StageDownload, "SUBTITLE", testPayload{URL: "https://example.com/2"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	if job, err := q.Claim(StageScrape); err != nil || job != nil {
+		t.Fatalf("Claim(scrape) = %v, %v, want no job", job, err)
+	}
+
+	job, err := q.Claim(StageDownload)
+	if err != nil || job == nil {
+		t.Fatalf("Claim() = %v, %v", job, err)
+	}
+	if job.ID() != first.ID() {
+		t.Errorf("Claim() = job %s, want the oldest job %s", job.ID(), first.ID())
+	}
+	if job.State() != StateRunning || job.Attempts() != 1 || job.SubtitleID() != "SUBTITLE" {
+		t.Errorf("Claim() state = %s, attempts = %d, subtitle = %s", job.State(), job.Attempts(), job.SubtitleID())
+	}
+	var payload testPayload
+	if err := job.DecodePayload(&payload); err != nil || payload.URL != "https://example.com/1" {
+		t.Errorf("DecodePayload() = %+v, %v", payload, err)
+	}
+
+	if second, err := q.Claim(StageDownload); err != nil || second == nil || second.ID() == first.ID() {
+		t.Fatalf("second Claim() = %v, %v, want the other job", second, err)
+	}
+	if job, err := q.Claim(StageDownload); err != nil || job != nil {
+		t.Errorf("third Claim() = %v, %v, want no job", job, err)
+	}
+
+	if err := q.Complete(job); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if job.State() != StateSucceeded || findJob(t, app, job.ID()).State() != StateSucceeded {
+		t.Errorf("state after Complete() = %s", job.State())
+	}
+	if active, err := q.HasActive(StageDownload, "SUBTITLE"); err != nil || !active {
+		t.Errorf("HasActive() = %v, %v, want true", active, err)
+	}
+}
+
+func TestEnqueueUnique(t *testing.T) {
+	app := testapp.New(t)
+	q := NewQueue(app, testConfig())
+
+	first, err := q.EnqueueUnique(StageDownload, "SUBTITLE", "scrape1:https://example.com/1", testPayload{URL: "https://example.com/1"})
+	if err != nil {
+		t.Fatalf("EnqueueUnique() error = %v", err)
+	}
+	// 작업이 끝난 뒤에도 같은 키로는 다시 추가하지 않습니다.
+	claimed, err := q.Claim(StageDownload)
+	if err != nil || claimed == nil {
+		t.Fatalf("Claim() = %v, %v", claimed, err)
+	}
+	if err := q.Complete(claimed); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	again, err := q.EnqueueUnique(StageDownload, "SUBTITLE", "scrape1:https://example.com/1", testPayload{URL: "https://example.com/1"})
+	if err != nil || again.ID() != first.ID() || again.State() != StateSucceeded {
+		t.Errorf("EnqueueUnique() with the same key = %v, %v, want job %s", again, err, first.ID())
+	}
+
+	other, err := q.EnqueueUnique(StageDownload, "SUBTITLE", "scrape2:https://example.com/1", testPayload{URL: "https://example.com/1"})
+	if err != nil || other.ID() == first.ID() {
+		t.Errorf("EnqueueUnique() with another key = %v, %v, want a new job", other, err)
+	}
+	if _, err := q.EnqueueUnique(StageDownload, "SUBTITLE", "", nil); err == nil {
+		t.Error("EnqueueUnique() with an empty key error = nil")
+	}
+	// 키 없이 추가한 작업끼리는 중복을 확인하지 않습니다.
+	for i := 0; i < 2; i++ {
+		if _, err := q.Enqueue(StageDownload, "SUBTITLE", nil); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	jobs, err := q.Jobs("SUBTITLE")
+	if err != nil || len(jobs) != 4 {
+		t.Errorf("Jobs() = %d jobs, %v, want 4", len(jobs), err)
+	}
+}
+
+func TestClaimExpiredLease(t *testing.T) {
+	app := testapp.New(t)
+	config := testConfig()
+	config.LeaseDuration = 50 * time.Millisecond
+	// 같은 데이터베이스를 쓰는 두 프로세스입니다.
+	crashed := NewQueue(app, config)
+	other := NewQueue(app, config)
+
+	if _, err := crashed.Enqueue(StageScrape, "SUBTITLE", nil); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	job, err := crashed.Claim(StageScrape)
+	if err != nil || job == nil {
+		t.Fatalf("Claim() = %v, %v", job, err)
+	}
+
+	// 점유 시간이 지나기 전에는 다른 워커가 가져가지 않습니다.
+	if stolen, err := other.Claim(StageScrape); err != nil || stolen != nil {
+		t.Fatalf("Claim() before the lease expired = %v, %v", stolen, err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	reclaimed, err := other.Claim(StageScrape)
+	if err != nil || reclaimed == nil || reclaimed.ID() != job.ID() {
+		t.Fatalf("Claim() after the lease expired = %v, %v", reclaimed, err)
+	}
+	if reclaimed.Attempts() != 2 {
+		t.Errorf("Attempts() = %d, want 2", reclaimed.Attempts())
+	}
+
+	// 점유를 잃은 워커는 결과를 저장하지 못합니다.
+	for name, settle := range map[string]func(*Job) error{
+		"Renew":    crashed.Renew,
+		"Complete": crashed.Complete,
+		"Release":  crashed.Release,
+		"Fail":     func(job *Job) error { return crashed.Fail(job, errors.New("boom")) },
+	} {
+		if err := settle(job); !errors.Is(err, ErrLeaseLost) {
+			t.Errorf("%s() error = %v, want ErrLeaseLost", name, err)
+		}
+	}
+	if err := other.Complete(reclaimed); err != nil {
+		t.Errorf("Complete() by the new owner error = %v", err)
+	}
+}
+
+func TestClaimExpiredLeaseAtMaxAttempts(t *testing.T) {
+	app := testapp.New(t)
+	config := testConfig()
+	config.LeaseDuration = 50 * time.Millisecond
+	config.MaxAttempts = 2
+	q := NewQueue(app, config)
+	settled := settledJobs(q)
+
+	enqueued, err := q.Enqueue(StageUnpack, "SUBTITLE", nil)
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	// 작업을 실행할 때마다 프로세스가 죽어서 점유 시간이 지납니다.
+	for attempt := 1; attempt <= config.MaxAttempts; attempt++ {
+		job, err := q.Claim(StageUnpack)
+		if err != nil || job == nil || job.Attempts() != attempt {
+			t.Fatalf("Claim() attempt %d = %v, %v", attempt, job, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if job, err := q.Claim(StageUnpack); err != nil || job != nil {
+		t.Fatalf("Claim() after max attempts = %v, %v, want no job", job, err)
+	}
+	job := findJob(t, app, enqueued.ID())
+	if job.State() != StateDead || job.Attempts() != config.MaxAttempts || !strings.Contains(job.LastError(), "lease expired") {
+		t.Errorf("job state = %s, attempts = %d, last_error = %q, want dead", job.State(), job.Attempts(), job.LastError())
+	}
+	if got := waitSettled(t, settled); got.ID() != enqueued.ID() || got.State() != StateDead {
+		t.Errorf("settled job = %s (%s), want %s (dead)", got.ID(), got.State(), enqueued.ID())
+	}
+}
+
+func TestFailBackoff(t *testing.T) {
+	app := testapp.New(t)
+	config := testConfig()
+	q := NewQueue(app, config)
+
+	enqueued, err := q.Enqueue(StageDownload, "SUBTITLE", nil)
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	for attempt := 1; attempt <= config.MaxAttempts; attempt++ {
+		var job *Job
+		deadline := time.Now().Add(5 * time.Second)
+		for job == nil && time.Now().Before(deadline) {
+			if job, err = q.Claim(StageDownload); err != nil {
+				t.Fatalf("Claim() error = %v", err)
+			}
+		}
+		if job == nil || job.Attempts() != attempt {
+			t.Fatalf("Claim() attempt %d = %v", attempt, job)
+		}
+
+		failedAt := time.Now()
+		if err := q.Fail(job, errors.New("server error")); err != nil {
+			t.Fatalf("Fail() error = %v", err)
+		}
+		if attempt == config.MaxAttempts {
+			if job.State() != StateDead {
+				t.Errorf("state after the last attempt = %s, want dead", job.State())
+			}
+			break
+		}
+		if job.State() != StateFailed || job.LastError() != "server error" {
+			t.Errorf("state = %s, last_error = %q, want failed", job.State(), job.LastError())
+		}
+		// 실패할 때마다 기다리는 시간이 두 배로 늘어납니다.
+		wait := job.record.GetDateTime("next_run_at").Time().Sub(failedAt)
+		if want := q.backoff(attempt); wait < want-5*time.Millisecond || wait > want+50*time.Millisecond {
+			t.Errorf("attempt %d next_run_at in %s, want %s", attempt, wait, want)
+		}
+	}
+
+	if job, err := q.Claim(StageDownload); err != nil || job != nil {
+		t.Errorf("Claim() of a dead job = %v, %v", job, err)
+	}
+	if active, err := q.HasActive(StageDownload, enqueued.SubtitleID()); err != nil || active {
+		t.Errorf("HasActive() = %v, %v, want false", active, err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	q := &Queue{config: Config{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: time.Second},
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: 2 * time.Second},
+		{attempts: 3, want: 4 * time.Second},
+		{attempts: 4, want: 5 * time.Second},
+		{attempts: 100, want: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := q.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWorkComplete(t *testing.T) {
+	app := testapp.New(t)
+	q := NewQueue(app, testConfig())
+	settled := settledJobs(q)
+
+	enqueued, err := q.Enqueue(StageDownload, "SUBTITLE", testPayload{URL: "https://example.com/1"})
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	startWorker(t, q, StageDownload, func(ctx context.Context, job *Job) error {
+		// heartbeat가 점유 시간을 연장하는 동안 job을 계속 읽습니다.
+		deadline := time.Now().Add(3 * q.config.LeaseDuration)
+		for time.Now().Before(deadline) {
+			var payload testPayload
+			if err := job.DecodePayload(&payload); err != nil {
+				return err
+			}
+			if job.SubtitleID() != "SUBTITLE" || job.Attempts() != 1 {
+				return errors.New("unexpected job")
+			}
+			time.Sleep(time.Millisecond)
+		}
+		return nil
+	})
+
+	job := waitSettled(t, settled)
+	if job.ID() != enqueued.ID() || job.State() != StateSucceeded {
+		t.Errorf("settled job = %s (%s), last_error = %q, want succeeded", job.ID(), job.State(), job.LastError())
+	}
+}
+
+func TestWorkPanicIsPermanent(t *testing.T) {
+	app := testapp.New(t)
+	q := NewQueue(app, testConfig())
+	settled := settledJobs(q)
+
+	if _, err := q.Enqueue(StageUnpack, "SUBTITLE", nil); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	startWorker(t, q, StageUnpack, func(ctx context.Context, job *Job) error {
+		panic("corrupt archive")
+	})
+
+	// 재시도 횟수가 남아 있어도 다시 실행하지 않습니다.
+	job := waitSettled(t, settled)
+	if job.State() != StateDead || job.Attempts() != 1 || !strings.Contains(job.LastError(), "handler panicked: corrupt archive") {
+		t.Errorf("state = %s, attempts = %d, last_error = %q, want dead", job.State(), job.Attempts(), job.LastError())
+	}
+}
+
+func TestWorkLeaseLost(t *testing.T) {
+	app := testapp.New(t)
+	config := testConfig()
+	config.LeaseDuration = 90 * time.Millisecond
+	q := NewQueue(app, config)
+	settled := settledJobs(q)
+
+	enqueued, err := q.Enqueue(StageDownload, "SUBTITLE", nil)
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	started := make(chan struct{})
+	causes := make(chan error, 1)
+	startWorker(t, q, StageDownload, func(ctx context.Context, job *Job) error {
+		close(started)
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return ctx.Err()
+	})
+
+	// 다른 워커가 작업을 가져간 것처럼 점유 토큰을 바꿉니다.
+	<-started
+	record := findJob(t, app, enqueued.ID()).record
+	leaseExpiresAt, _ := types.ParseDateTime(time.Now().Add(time.Minute))
+	record.Set("lease_owner", "other-worker")
+	record.Set("lease_expires_at", leaseExpiresAt)
+	if err := app.Dao().SaveRecord(record); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case cause := <-causes:
+		if !errors.Is(cause, ErrLeaseLost) {
+			t.Errorf("handler ctx cause = %v, want ErrLeaseLost", cause)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler was not canceled")
+	}
+
+	// 점유를 잃은 작업의 결과는 저장하지 않습니다.
+	select {
+	case job := <-settled:
+		t.Errorf("abandoned job settled: %s (%s)", job.ID(), job.State())
+	case <-time.After(100 * time.Millisecond):
+	}
+	job := findJob(t, app, enqueued.ID())
+	if job.State() != StateRunning || job.record.GetString("lease_owner") != "other-worker" {
+		t.Errorf("state = %s, lease_owner = %q, want the other worker's lease", job.State(), job.record.GetString("lease_owner"))
+	}
+}
+
+func TestWorkReleaseOnShutdown(t *testing.T) {
+	app := testapp.New(t)
+	q := NewQueue(app, testConfig())
+
+	enqueued, err := q.Enqueue(StageDownload, "SUBTITLE", nil)
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	started := make(chan struct{})
+	stop := startWorker(t, q, StageDownload, func(ctx context.Context, job *Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	<-started
+	stop()
+
+	// 프로세스가 종료되어 중단한 작업은 시도 횟수를 늘리지 않고 pending으로 돌려놓습니다.
+	job := waitJob(t, app, enqueued.ID(), func(job *Job) bool { return job.State() != StateRunning })
+	if job.State() != StatePending || job.Attempts() != 0 || job.record.GetString("lease_owner") != "" {
+		t.Errorf("state = %s, attempts = %d, lease_owner = %q, want released", job.State(), job.Attempts(), job.record.GetString("lease_owner"))
+	}
+}
@@ -0,0 +1,142 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Handler는 작업을 실행합니다.
+// 에러를 반환하면 작업은 재시도되며, Permanent로 감싼 에러는 재시도하지 않습니다.
+type Handler func(ctx context.Context, job *Job) error
+
+// Work는 ctx가 끝날 때까지 stage 작업을 가져와 handler로 실행하는 고루틴을 workers개 실행합니다.
+func (q *Queue) Work(ctx context.Context, stage Stage, workers int, handler Handler) {
+	if workers <= 0 {
+		workers = 1
+	}
+	for w := 0; w < workers; w++ {
+		q.workers.Add(1)
+		go func() {
+			defer q.workers.Done()
+			q.work(ctx, stage, handler)
+		}()
+	}
+}
+
+// Wait는 Work로 실행한 워커가 모두 끝날 때까지 기다립니다.
+// ctx가 끝난 뒤 Wait를 호출하면 실행 중이던 작업이 pending으로 돌아간 뒤에 반환됩니다.
+func (q *Queue) Wait() {
+	q.workers.Wait()
+}
+
+// work는 워커 하나의 루프입니다.
+func (q *Queue) work(ctx context.Context, stage Stage, handler Handler) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		job, err := q.Claim(stage)
+		if err != nil {
+			log.Printf("[Queue] - %v", err)
+		}
+		if job == nil {
+			// 실행할 작업이 없으면 잠시 기다립니다.
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(q.config.PollInterval):
+			}
+			continue
+		}
+
+		q.process(ctx, job, handler)
+	}
+}
+
+// process는 점유한 작업을 실행하고 결과를 저장합니다.
+// 실행하는 동안 주기적으로 점유 시간을 연장하며, 점유를 잃거나 MaxRunTime이 지나면 handler의 ctx를 취소합니다.
+func (q *Queue) process(ctx context.Context, job *Job, handler Handler) {
+	runCtx, cancelTimeout := context.WithTimeout(ctx, q.config.MaxRunTime)
+	defer cancelTimeout()
+	runCtx, cancelRun := context.WithCancelCause(runCtx)
+	defer cancelRun(nil)
+
+	// heartbeat가 끝난 뒤에 결과를 저장하므로, 진행 중인 Renew와 Complete, Fail, Release가 겹치지 않습니다.
+	heartbeatCtx, stopHeartbeat := context.WithCancel(runCtx)
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		q.heartbeat(heartbeatCtx, job, cancelRun)
+	}()
+
+	err := runHandler(runCtx, job, handler)
+	stopHeartbeat()
+	<-heartbeatDone
+
+	switch {
+	case errors.Is(context.Cause(runCtx), ErrLeaseLost):
+		// 다른 워커가 작업을 가져갔으므로 결과를 저장하지 않습니다.
+		log.Printf("[Queue] - %s job %s abandoned: %v", job.Stage(), job.ID(), context.Cause(runCtx))
+		return
+	case err == nil:
+		err = q.Complete(job)
+	case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+		// 프로세스가 종료되는 중이면 시도 횟수를 늘리지 않고 작업을 돌려놓습니다.
+		err = q.Release(job)
+	default:
+		if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("job exceeded max run time %s: %w", q.config.MaxRunTime, err)
+		}
+		log.Printf("[Queue] - %s job %s failed (attempt %d): %v", job.Stage(), job.ID(), job.Attempts(), err)
+		err = q.Fail(job, err)
+	}
+	if err != nil {
+		log.Printf("[Queue] - %v", err)
+		return
+	}
+
+	q.settle(job)
+}
+
+// heartbeat는 ctx가 끝날 때까지 작업의 점유 시간을 연장합니다.
+// 다른 워커가 작업을 가져갔거나, 점유 시간이 지날 때까지 연장하지 못하면 cancel로 handler를 중단합니다.
+func (q *Queue) heartbeat(ctx context.Context, job *Job, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(q.config.LeaseDuration / 3)
+	defer ticker.Stop()
+	renewedAt := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := q.Renew(job)
+			if err == nil {
+				renewedAt = time.Now()
+				continue
+			}
+			log.Printf("[Queue] - %v", err)
+			if errors.Is(err, ErrLeaseLost) {
+				cancel(err)
+				return
+			}
+			if time.Since(renewedAt) >= q.config.LeaseDuration {
+				cancel(fmt.Errorf("%w: lease expired while renewing: %v", ErrLeaseLost, err))
+				return
+			}
+		}
+	}
+}
+
+// runHandler는 handler를 실행하고 panic을 에러로 바꿉니다.
+func runHandler(ctx context.Context, job *Job, handler Handler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = Permanent(fmt.Errorf("handler panicked: %v", r))
+		}
+	}()
+	return handler(ctx, job)
+}
@@ -0,0 +1,51 @@
+package scraper
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// bloggerPostSelector는 Blogger 템플릿에서 글 본문을 감싸는 요소입니다.
+// 사이드바 위젯과 댓글은 본문 밖에 있으므로 여기에 포함되지 않습니다.
+const bloggerPostSelector = ".post-body, .entry-content, .post-content"
+
+// BloggerExtractor는 Blogger(blogspot) 블로그 글에서 다운로드 링크를 찾습니다.
+// 사이드바나 댓글의 다른 회차 링크를 제외하기 위해 글 본문에서만 링크를 찾고,
+// 찾은 링크는 처리 중인 회차 번호와 링크 텍스트가 잘 맞는 순서로 정렬합니다.
+type BloggerExtractor struct{}
+
+// Match는 Blogger 블로그 글인지 판별합니다.
+func (b *BloggerExtractor) Match(url string) bool {
+	host := hostOf(url)
+	return strings.Contains(host, ".blogspot.") || host == "www.blogger.com"
+}
+
+// Extract는 글 본문에서 다운로드 링크를 찾습니다.
+// 본문 요소가 없는 템플릿이면 페이지 전체에서 찾습니다.
+func (b *BloggerExtractor) Extract(ctx context.Context, url string, episode string) ([]DownloadLink, error) {
+	var body, page linkCollector
+	foundBody := false
+	c := newCollector(ctx)
+
+	c.OnHTML(bloggerPostSelector, func(e *colly.HTMLElement) {
+		foundBody = true
+		e.ForEach("a[href]", func(_ int, a *colly.HTMLElement) {
+			body.add(a)
+		})
+	})
+	c.OnHTML("a[href]", page.add)
+
+	// Start scraping
+	if err := c.Visit(url); err != nil {
+		return nil, err
+	}
+
+	links := page.links
+	if foundBody {
+		links = body.links
+	}
+	RankByEpisode(links, episode)
+	return links, ctx.Err()
+}
@@ -0,0 +1,64 @@
+package scraper
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestBloggerExtractor(t *testing.T) {
+	tests := []struct {
+		name    string
+		page    string
+		episode string
+		want    []string
+	}{
+		{
+			// 사이드바와 댓글의 링크는 제외하고, 7화와 잘 맞는 링크부터 정렬합니다.
+			name:    "post body",
+			page:    "blogger_post.html",
+			episode: "7",
+			want: []string{
+				"https://drive.google.com/file/d/BLOGGER_07_FIX/view?usp=sharing",
+				"https://drive.google.com/file/d/BLOGGER_07/view?usp=sharing",
+				"https://drive.google.com/file/d/BLOGGER_06/view?usp=sharing",
+				"https://drive.google.com/file/d/BLOGGER_17/view?usp=sharing",
+			},
+		},
+		{
+			// 회차를 알 수 없으면 페이지에 나온 순서를 유지합니다.
+			name: "unknown episode",
+			page: "blogger_post.html",
+			want: []string{
+				"https://drive.google.com/file/d/BLOGGER_06/view?usp=sharing",
+				"https://drive.google.com/file/d/BLOGGER_17/view?usp=sharing",
+				"https://drive.google.com/file/d/BLOGGER_07/view?usp=sharing",
+				"https://drive.google.com/file/d/BLOGGER_07_FIX/view?usp=sharing",
+			},
+		},
+		{
+			// 본문 요소가 없는 템플릿이면 페이지 전체에서 찾습니다.
+			name:    "page fallback",
+			page:    "blogger_page.html",
+			episode: "7",
+			want: []string{
+				"https://drive.google.com/file/d/BLOGGER_07/view?usp=sharing",
+				"https://drive.google.com/file/d/BLOGGER_06/view?usp=sharing",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newBlogServer(t, map[string]string{"/2024/01/post.html": tt.page})
+
+			links, err := (&BloggerExtractor{}).Extract(context.Background(), server.URL+"/2024/01/post.html", tt.episode)
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if got := linkURLs(links); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package scraper
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/huketo/anisub-scraper/downloader"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+)
+
+// maxContextLength는 DownloadLink.Context에 저장하는 최대 글자 수입니다.
+const maxContextLength = 200
+
+// urlParser는 다운로드 URL의 타입을 판별합니다.
+var urlParser downloader.Parser = &downloader.ParserImpl{}
+
+// IsDownloadURL은 link가 downloader로 받을 수 있는 다운로드 URL인지 판별합니다.
+func IsDownloadURL(link string) bool {
+	return urlParser.GetDownloadURLType(link) != downloader.NotSupportedURL
+}
+
+// newCollector는 ctx가 끝나면 요청을 중단하는 colly.Collector를 생성합니다.
+func newCollector(ctx context.Context) *colly.Collector {
+	c := colly.NewCollector()
+
+	// Before making a request print "Visiting ..."
+	c.OnRequest(func(r *colly.Request) {
+		if ctx.Err() != nil {
+			r.Abort()
+			return
+		}
+		log.Println("Visiting", r.URL.String())
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		log.Println("Request URL:", r.Request.URL, "failed with response:", r, "\nError:", err)
+	})
+
+	return c
+}
+
+// linkCollector는 중복을 제거하며 다운로드 링크를 모읍니다.
+type linkCollector struct {
+	links []DownloadLink
+	seen  map[string]bool
+}
+
+// add는 a 태그가 다운로드 링크이면 추가합니다.
+func (lc *linkCollector) add(e *colly.HTMLElement) {
+	link := e.Request.AbsoluteURL(e.Attr("href"))
+	if link == "" || !IsDownloadURL(link) {
+		return
+	}
+	lc.addLink(newDownloadLink(link, e.Text, e.DOM))
+}
+
+// addLink는 다운로드 링크를 추가합니다.
+func (lc *linkCollector) addLink(link DownloadLink) {
+	if lc.seen == nil {
+		lc.seen = make(map[string]bool)
+	}
+	if lc.seen[link.URL] {
+		return
+	}
+	lc.seen[link.URL] = true
+	log.Println("Found link:", link.URL)
+	lc.links = append(lc.links, link)
+}
+
+// newDownloadLink는 링크와 링크가 있는 위치로 DownloadLink를 생성합니다.
+func newDownloadLink(link string, label string, s *goquery.Selection) DownloadLink {
+	var host string
+	if parsedURL, err := url.Parse(link); err == nil {
+		host = parsedURL.Host
+	}
+	return DownloadLink{
+		URL:     link,
+		Label:   normalizeText(label),
+		Host:    host,
+		Context: surroundingText(s),
+		Heading: nearestHeading(s),
+	}
+}
+
+// surroundingText는 링크를 감싸는 가장 가까운 블록 요소의 텍스트를 반환합니다.
+func surroundingText(s *goquery.Selection) string {
+	block := s.Closest("p, li, td, figure, blockquote, h1, h2, h3, h4, div")
+	if block.Length() == 0 {
+		block = s.Parent()
+	}
+	return truncate(normalizeText(block.Text()), maxContextLength)
+}
+
+// normalizeText는 연속된 공백을 하나로 줄입니다.
+func normalizeText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// truncate는 s를 최대 n 글자로 자릅니다.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// hostOf는 rawURL의 호스트를 소문자로 반환합니다.
+func hostOf(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsedURL.Hostname())
+}
@@ -0,0 +1,113 @@
+package scraper
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// episodePatterns는 텍스트에서 회차 번호를 찾는 정규식입니다. 앞에 있는 정규식을 먼저 확인합니다.
+var episodePatterns = []*regexp.Regexp{
+	// 7화, 07 화, 3.1화, 第7話
+	regexp.MustCompile(`(\d{1,4}(?:\.\d+)?)\s*(?:화|話)`),
+	// EP07, Ep.7, Episode 7, E07, #7, 제7
+	// 단어 중간의 ep, e(Deep 07, Sleep 2)는 회차 표시가 아닙니다.
+	regexp.MustCompile(`(?i)(?:\b(?:episode|ep|e)|제|#)\s*\.?\s*(\d{1,4}(?:\.\d+)?)(?:v\d+)?\b`),
+	// [Group] Title 2 - 07v2.ass
+	// 제목 뒤의 시즌 번호보다 " - " 뒤의 번호를 먼저 확인합니다.
+	regexp.MustCompile(`(?:^|\s)-\s*(\d{1,4}(?:\.\d)?)(?:v\d+)?(?:[\s\]\)_.]|$)`),
+	// Title_07.smi, [Group] Title [07]
+	regexp.MustCompile(`(?i)(?:^|[\s\[\(_-])(\d{1,3}(?:\.\d)?)(?:v\d+)?(?:[\s\]\)_.-]|$)`),
+}
+
+// ParseEpisode는 링크 텍스트나 파일 이름에서 회차 번호를 찾아 정규화된 문자열("07" -> "7")로 반환합니다.
+// 해상도(1080p)나 연도처럼 회차가 아닌 숫자는 무시합니다.
+func ParseEpisode(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", false
+	}
+	for _, pattern := range episodePatterns {
+		for _, match := range pattern.FindAllStringSubmatchIndex(text, -1) {
+			number := text[match[2]:match[3]]
+			// 1080p, 720p 같은 해상도는 회차가 아닙니다.
+			if rest := text[match[3]:]; strings.HasPrefix(strings.ToLower(rest), "p") {
+				continue
+			}
+			if episode, ok := NormalizeEpisode(number); ok {
+				return episode, true
+			}
+		}
+	}
+	return "", false
+}
+
+// NormalizeEpisode는 회차 번호를 비교할 수 있도록 정규화합니다. ("07" -> "7", "3.10" -> "3.1")
+func NormalizeEpisode(episode string) (string, bool) {
+	number, err := strconv.ParseFloat(strings.TrimSpace(episode), 64)
+	if err != nil || number < 0 {
+		return "", false
+	}
+	return strconv.FormatFloat(number, 'f', -1, 64), true
+}
+
+// AssignEpisodes는 각 링크의 회차를 링크 텍스트, 파일 이름, 가까운 제목, 주변 텍스트 순서로 찾아 저장합니다.
+func AssignEpisodes(links []DownloadLink) {
+	for i := range links {
+		for _, text := range []string{links[i].Label, links[i].FileName, links[i].Heading, links[i].Context} {
+			if episode, ok := ParseEpisode(text); ok {
+				links[i].Episode = episode
+				break
+			}
+		}
+	}
+}
+
+// SelectByEpisode는 links를 episode 회차의 링크와 그 외의 링크로 나눕니다.
+// 회차를 알 수 없는 링크(예: 폰트)는 episode 회차의 링크가 있거나
+// 모든 링크의 회차를 알 수 없는 경우(한 회차만 올린 글)에만 선택합니다.
+func SelectByEpisode(links []DownloadLink, episode string) (matched []DownloadLink, unmatched []DownloadLink) {
+	target, ok := NormalizeEpisode(episode)
+	if !ok {
+		return links, nil
+	}
+
+	var unknown []DownloadLink
+	hasTarget, hasOther := false, false
+	for _, link := range links {
+		switch link.Episode {
+		case "":
+			unknown = append(unknown, link)
+		case target:
+			hasTarget = true
+			matched = append(matched, link)
+		default:
+			hasOther = true
+			unmatched = append(unmatched, link)
+		}
+	}
+
+	if hasTarget || !hasOther {
+		matched = append(matched, unknown...)
+	} else {
+		unmatched = append(unmatched, unknown...)
+	}
+	return matched, unmatched
+}
+
+// nearestHeading은 링크보다 앞에 있는 가장 가까운 제목 요소의 텍스트를 반환합니다.
+func nearestHeading(s *goquery.Selection) string {
+	const headings = "h1, h2, h3, h4, h5, h6"
+	for node := s; node.Length() > 0 && !node.Is("body"); node = node.Parent() {
+		prev := node.PrevAllFiltered(headings).First()
+		if prev.Length() > 0 {
+			return truncate(normalizeText(prev.Text()), maxContextLength)
+		}
+		if node.Is(headings) {
+			return truncate(normalizeText(node.Text()), maxContextLength)
+		}
+	}
+	return ""
+}
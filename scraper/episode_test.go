@@ -0,0 +1,144 @@
+package scraper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEpisode(t *testing.T) {
+	tests := []struct {
+		text   string
+		want   string
+		wantOK bool
+	}{
+		{text: "7화 자막", want: "7", wantOK: true},
+		{text: "07 화", want: "7", wantOK: true},
+		{text: "3.1화", want: "3.1", wantOK: true},
+		{text: "第12話", want: "12", wantOK: true},
+		{text: "EP07", want: "7", wantOK: true},
+		{text: "Ep.7 자막", want: "7", wantOK: true},
+		{text: "Episode 12", want: "12", wantOK: true},
+		{text: "E03v2", want: "3", wantOK: true},
+		{text: "#5", want: "5", wantOK: true},
+		{text: "제8", want: "8", wantOK: true},
+		{text: "[Group] Title - 07v2.ass", want: "7", wantOK: true},
+		{text: "Title_11.smi", want: "11", wantOK: true},
+		{text: "[SubsPlease] Title - 03 (1080p).ass", want: "3", wantOK: true},
+		// 단어 안의 ep, e는 회차 표시가 아닙니다.
+		{text: "Deep 07", want: "7", wantOK: true},
+		{text: "Sleep 2 - 07.ass", want: "7", wantOK: true},
+		{text: "Sleep2", wantOK: false},
+		{text: "Deep Sleep", wantOK: false},
+		// 제목 뒤의 시즌 번호보다 " - " 뒤의 번호를 먼저 봅니다.
+		{text: "Title 2 - 07", want: "7", wantOK: true},
+		{text: "[Group] Title 2 - 07v2 (1080p).ass", want: "7", wantOK: true},
+		{text: "Title S2 - 11.ass", want: "11", wantOK: true},
+		{text: "Title 1080p", wantOK: false},
+		{text: "Title (720p)", wantOK: false},
+		{text: "fonts.zip", wantOK: false},
+		{text: "", wantOK: false},
+		{text: "   ", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseEpisode(tt.text)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseEpisode(%q) = %q, %v, want %q, %v", tt.text, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestNormalizeEpisode(t *testing.T) {
+	tests := []struct {
+		episode string
+		want    string
+		wantOK  bool
+	}{
+		{episode: "07", want: "7", wantOK: true},
+		{episode: " 12 ", want: "12", wantOK: true},
+		{episode: "3.10", want: "3.1", wantOK: true},
+		{episode: "0", want: "0", wantOK: true},
+		{episode: "-1", wantOK: false},
+		{episode: "SP", wantOK: false},
+		{episode: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := NormalizeEpisode(tt.episode)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("NormalizeEpisode(%q) = %q, %v, want %q, %v", tt.episode, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestAssignEpisodes(t *testing.T) {
+	links := []DownloadLink{
+		{Label: "7화", FileName: "Title - 08.zip"},
+		{Label: "다운로드", FileName: "Title - 08.zip"},
+		{Label: "다운로드", Heading: "9화 자막"},
+		{Label: "다운로드", Context: "10화 자막입니다"},
+		{Label: "폰트"},
+	}
+	AssignEpisodes(links)
+
+	var got []string
+	for _, link := range links {
+		got = append(got, link.Episode)
+	}
+	if want := []string{"7", "8", "9", "10", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AssignEpisodes() episodes = %q, want %q", got, want)
+	}
+}
+
+func TestSelectByEpisode(t *testing.T) {
+	ep7 := DownloadLink{URL: "https://example.com/7", Episode: "7"}
+	ep8 := DownloadLink{URL: "https://example.com/8", Episode: "8"}
+	font := DownloadLink{URL: "https://example.com/font"}
+	single := DownloadLink{URL: "https://example.com/single"}
+
+	tests := []struct {
+		name          string
+		links         []DownloadLink
+		episode       string
+		wantMatched   []DownloadLink
+		wantUnmatched []DownloadLink
+	}{
+		{
+			name:          "target with font",
+			links:         []DownloadLink{ep7, ep8, font},
+			episode:       "07",
+			wantMatched:   []DownloadLink{ep7, font},
+			wantUnmatched: []DownloadLink{ep8},
+		},
+		{
+			name:          "target missing",
+			links:         []DownloadLink{ep8, font},
+			episode:       "7",
+			wantUnmatched: []DownloadLink{ep8, font},
+		},
+		{
+			name:        "single episode post",
+			links:       []DownloadLink{single, font},
+			episode:     "7",
+			wantMatched: []DownloadLink{single, font},
+		},
+		{
+			name:        "unknown episode keeps every link",
+			links:       []DownloadLink{ep7, ep8},
+			episode:     "SP",
+			wantMatched: []DownloadLink{ep7, ep8},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, unmatched := SelectByEpisode(tt.links, tt.episode)
+			if !reflect.DeepEqual(matched, tt.wantMatched) {
+				t.Errorf("matched = %+v, want %+v", matched, tt.wantMatched)
+			}
+			if !reflect.DeepEqual(unmatched, tt.wantUnmatched) {
+				t.Errorf("unmatched = %+v, want %+v", unmatched, tt.wantUnmatched)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package scraper
+
+import (
+	"context"
+)
+
+// GenericExtractor는 페이지의 모든 a 태그에서 다운로드 링크를 찾습니다.
+// 다른 Extractor가 처리하지 않는 블로그에 사용합니다.
+type GenericExtractor struct{}
+
+// Match는 모든 URL을 처리합니다.
+func (g *GenericExtractor) Match(url string) bool {
+	return true
+}
+
+// Extract는 페이지의 모든 a 태그에서 다운로드 링크를 찾습니다.
+func (g *GenericExtractor) Extract(ctx context.Context, url string, episode string) ([]DownloadLink, error) {
+	var lc linkCollector
+	c := newCollector(ctx)
+
+	// On every a element which has href attribute call callback
+	c.OnHTML("a[href]", lc.add)
+
+	// Start scraping
+	if err := c.Visit(url); err != nil {
+		return nil, err
+	}
+
+	return lc.links, ctx.Err()
+}
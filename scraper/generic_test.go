@@ -0,0 +1,42 @@
+package scraper
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestGenericExtractor(t *testing.T) {
+	server := newBlogServer(t, map[string]string{"/post": "generic.html"})
+
+	links, err := (&GenericExtractor{}).Extract(context.Background(), server.URL+"/post", "3")
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+
+	// 다운로드 URL이 아닌 링크는 제외하고, 상대 경로는 절대 URL로 바꿉니다.
+	want := []string{
+		"https://drive.google.com/file/d/GENERIC_FILE_03/view?usp=sharing",
+		server.URL + "/files/%ED%85%8C%EC%8A%A4%ED%8A%B8_03.ass",
+		"https://drive.google.com/drive/folders/GENERIC_FOLDER?usp=sharing",
+	}
+	if got := linkURLs(links); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Extract() = %q, want %q", got, want)
+	}
+
+	first := links[0]
+	if first.Label != "구글 드라이브" || first.Host != "drive.google.com" {
+		t.Errorf("Label, Host = %q, %q, want %q, %q", first.Label, first.Host, "구글 드라이브", "drive.google.com")
+	}
+	if first.Context != "3화 자막: 구글 드라이브" || first.Heading != "테스트 애니 3화" {
+		t.Errorf("Context, Heading = %q, %q", first.Context, first.Heading)
+	}
+}
+
+func TestGenericExtractorNotFound(t *testing.T) {
+	server := newBlogServer(t, nil)
+
+	if _, err := (&GenericExtractor{}).Extract(context.Background(), server.URL+"/post", ""); err == nil {
+		t.Error("Extract() error = nil, want not found error")
+	}
+}
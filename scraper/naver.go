@@ -0,0 +1,69 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// NaverBlogExtractor는 네이버 블로그 글에서 다운로드 링크를 찾습니다.
+// 네이버 블로그 글의 본문은 iframe 안에 있으므로 iframe의 페이지에서 링크를 찾습니다.
+type NaverBlogExtractor struct {
+	// Transport는 블로그 글과 iframe 페이지를 요청할 때 사용합니다. nil이면 colly의 기본값을 사용합니다.
+	Transport http.RoundTripper
+}
+
+// Match는 네이버 블로그 글인지 판별합니다.
+func (n *NaverBlogExtractor) Match(url string) bool {
+	host := hostOf(url)
+	return host == "blog.naver.com" || host == "m.blog.naver.com"
+}
+
+// Extract는 iframe 안의 본문에서 첨부 파일과 다운로드 링크를 찾습니다.
+func (n *NaverBlogExtractor) Extract(ctx context.Context, url string, episode string) ([]DownloadLink, error) {
+	var lc linkCollector
+	var iframeErr error
+	c := n.newCollector(ctx)
+
+	// 모바일 페이지처럼 iframe 없이 본문이 있는 경우를 처리합니다.
+	c.OnHTML("a[href]", lc.add)
+
+	// iframe의 src 속성을 찾는다
+	c.OnHTML("iframe", func(e *colly.HTMLElement) {
+		iframeSrc := e.Attr("src")
+		iframeURL := e.Request.AbsoluteURL(iframeSrc)
+		// iframe의 src가 네이버 블로그 페이지이면 iframe 내부의 콘텐츠에 대한 크롤링 시작
+		if iframeSrc == "" || !n.Match(iframeURL) {
+			return
+		}
+
+		innerCollector := n.newCollector(ctx)
+		innerCollector.OnHTML("a[href]", lc.add)
+
+		// iframe의 src로 요청을 보낸다
+		if err := innerCollector.Visit(iframeURL); err != nil && iframeErr == nil {
+			iframeErr = fmt.Errorf("visit iframe %s: %w", iframeURL, err)
+		}
+	})
+
+	// Start scraping
+	if err := c.Visit(url); err != nil {
+		return nil, err
+	}
+	if iframeErr != nil {
+		return nil, iframeErr
+	}
+
+	return lc.links, ctx.Err()
+}
+
+// newCollector는 Transport를 사용하는 colly.Collector를 생성합니다.
+func (n *NaverBlogExtractor) newCollector(ctx context.Context) *colly.Collector {
+	c := newCollector(ctx)
+	if n.Transport != nil {
+		c.WithTransport(n.Transport)
+	}
+	return c
+}
@@ -0,0 +1,65 @@
+package scraper
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestNaverBlogExtractor(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		pages   map[string]string
+		want    []string
+		wantErr bool
+	}{
+		{
+			// 본문은 mainFrame iframe의 PostView 페이지에 있습니다.
+			name: "main frame",
+			url:  "https://blog.naver.com/tester/223000000007",
+			pages: map[string]string{
+				"/tester/223000000007": "naver_main.html",
+				"/PostView.naver":      "naver_postview.html",
+			},
+			want: []string{
+				"https://blogattach.pstatic.net/MjAyNDAxMDFfMTAw/%ED%85%8C%EC%8A%A4%ED%8A%B8_07.zip?type=attachment",
+				"https://drive.google.com/file/d/NAVER_FILE_07/view?usp=sharing",
+			},
+		},
+		{
+			// 모바일 페이지는 iframe 없이 본문이 있고, 같은 링크는 한 번만 찾습니다.
+			name:  "mobile",
+			url:   "https://m.blog.naver.com/tester/223000000008",
+			pages: map[string]string{"/tester/223000000008": "naver_mobile.html"},
+			want:  []string{"https://drive.google.com/file/d/NAVER_FILE_08/view?usp=sharing"},
+		},
+		{
+			name:    "main frame not found",
+			url:     "https://blog.naver.com/tester/223000000007",
+			pages:   map[string]string{"/tester/223000000007": "naver_main.html"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newBlogServer(t, tt.pages)
+			extractor := &NaverBlogExtractor{Transport: serverTransport{server: server}}
+
+			links, err := extractor.Extract(context.Background(), tt.url, "7")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Extract() = %q, want error", linkURLs(links))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if got := linkURLs(links); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package scraper
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RankByEpisode는 링크 텍스트, 파일 이름, 주변 텍스트가 episode와 잘 맞는 순서로 links를 정렬합니다.
+// 점수가 같은 링크는 페이지에 나온 순서를 유지합니다. episode를 알 수 없으면 정렬하지 않습니다.
+func RankByEpisode(links []DownloadLink, episode string) {
+	pattern := episodePattern(episode)
+	if pattern == nil {
+		return
+	}
+	for i := range links {
+		links[i].Score = 0
+		if pattern.MatchString(links[i].Label) || pattern.MatchString(links[i].FileName) {
+			links[i].Score += 2
+		}
+		if pattern.MatchString(links[i].Context) {
+			links[i].Score++
+		}
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		return links[i].Score > links[j].Score
+	})
+}
+
+// episodePattern은 텍스트에서 episode 번호를 찾는 정규식을 만듭니다.
+// "7"은 "7", "07", "07화"와 맞지만 "17", "7.5"와는 맞지 않습니다.
+func episodePattern(episode string) *regexp.Regexp {
+	number, err := strconv.ParseFloat(strings.TrimSpace(episode), 64)
+	if err != nil {
+		return nil
+	}
+	formatted := regexp.QuoteMeta(strconv.FormatFloat(number, 'f', -1, 64))
+	return regexp.MustCompile(`(?:^|[^0-9.])0*` + formatted + `(?:[^0-9.]|\.[^0-9]|\.?$)`)
+}
@@ -0,0 +1,92 @@
+package scraper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRankByEpisode(t *testing.T) {
+	tests := []struct {
+		name      string
+		episode   string
+		links     []DownloadLink
+		want      []string
+		wantScore []int
+	}{
+		{
+			// 링크 텍스트나 파일 이름이 맞으면 2점, 주변 텍스트가 맞으면 1점입니다.
+			name:    "label, file name and context",
+			episode: "7",
+			links: []DownloadLink{
+				{URL: "a", Label: "다운로드", Context: "6화 자막"},
+				{URL: "b", Label: "다운로드", Context: "7화 자막: 다운로드"},
+				{URL: "c", Label: "07화", Context: "7화 자막: 07화"},
+				{URL: "d", Label: "첨부 파일", FileName: "[Sub] Title - 07.zip"},
+			},
+			want:      []string{"c", "d", "b", "a"},
+			wantScore: []int{3, 2, 1, 0},
+		},
+		{
+			// 점수가 같으면 페이지에 나온 순서를 유지합니다.
+			name:    "stable ties",
+			episode: "7",
+			links: []DownloadLink{
+				{URL: "a", Label: "자막"},
+				{URL: "b", Label: "7화"},
+				{URL: "c", Label: "폰트"},
+				{URL: "d", Label: "EP07"},
+			},
+			want:      []string{"b", "d", "a", "c"},
+			wantScore: []int{2, 2, 0, 0},
+		},
+		{
+			// 7은 17, 70, 7.5와 맞지 않습니다.
+			name:    "other episodes",
+			episode: "7",
+			links: []DownloadLink{
+				{URL: "a", Label: "17화"},
+				{URL: "b", Label: "70화"},
+				{URL: "c", Label: "7.5화"},
+				{URL: "d", Label: "7화."},
+			},
+			want:      []string{"d", "a", "b", "c"},
+			wantScore: []int{2, 0, 0, 0},
+		},
+		{
+			name:    "decimal episode",
+			episode: "7.5",
+			links: []DownloadLink{
+				{URL: "a", Label: "7화"},
+				{URL: "b", Label: "7.5화"},
+			},
+			want:      []string{"b", "a"},
+			wantScore: []int{2, 0},
+		},
+		{
+			// 회차를 알 수 없으면 정렬하지 않습니다.
+			name:    "unknown episode",
+			episode: "",
+			links: []DownloadLink{
+				{URL: "a", Label: "6화"},
+				{URL: "b", Label: "7화"},
+			},
+			want:      []string{"a", "b"},
+			wantScore: []int{0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			RankByEpisode(tt.links, tt.episode)
+			var got []string
+			var gotScore []int
+			for _, link := range tt.links {
+				got = append(got, link.URL)
+				gotScore = append(gotScore, link.Score)
+			}
+			if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(gotScore, tt.wantScore) {
+				t.Errorf("RankByEpisode() = %q %v, want %q %v", got, gotScore, tt.want, tt.wantScore)
+			}
+		})
+	}
+}
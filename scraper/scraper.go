@@ -1 +1,99 @@
 package scraper
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Scraper는 자막 제작자의 블로그 글에서 자막 다운로드 링크를 찾습니다.
+type Scraper interface {
+	// FindDownloadLinks는 블로그 글에서 다운로드 링크를 모두 찾습니다.
+	// episode는 처리 중인 자막 회차이며, 알 수 없으면 빈 문자열입니다.
+	FindDownloadLinks(ctx context.Context, url string, episode string) ([]DownloadLink, error)
+}
+
+// DownloadLink는 블로그 글에서 찾은 다운로드 링크입니다.
+type DownloadLink struct {
+	URL      string `json:"url"`                // 다운로드 URL
+	Label    string `json:"label"`              // 링크 텍스트
+	Host     string `json:"host"`               // 다운로드 URL의 호스트
+	Context  string `json:"context"`            // 링크 주변의 텍스트
+	FileName string `json:"fileName,omitempty"` // 블로그 글에 표시된 원본 파일 이름
+	Heading  string `json:"heading,omitempty"`  // 링크 앞의 가장 가까운 제목
+	Episode  string `json:"episode,omitempty"`  // 링크의 회차, 알 수 없으면 빈 문자열
+	Score    int    `json:"score,omitempty"`    // 처리 중인 회차와 맞는 정도, 클수록 잘 맞습니다.
+}
+
+// Extractor는 특정 블로그 호스트의 글에서 다운로드 링크를 찾습니다.
+type Extractor interface {
+	// Match는 url이 이 Extractor가 처리할 수 있는 블로그 글인지 판별합니다.
+	Match(url string) bool
+	// Extract는 블로그 글에서 다운로드 링크를 찾습니다.
+	// episode는 처리 중인 자막 회차로, 여러 회차의 링크 중 맞는 링크를 고를 때 사용합니다. 알 수 없으면 빈 문자열입니다.
+	Extract(ctx context.Context, url string, episode string) ([]DownloadLink, error)
+}
+
+// Registry는 Extractor를 등록하고 URL에 맞는 Extractor를 선택합니다.
+// 등록된 순서대로 Match를 확인하며, 맞는 Extractor가 없으면 fallback을 사용합니다.
+// Registry는 scraper.Scraper interface 를 구현합니다.
+type Registry struct {
+	mu         sync.RWMutex
+	extractors []Extractor
+	fallback   Extractor
+}
+
+// NewRegistry는 Registry를 생성합니다.
+func NewRegistry(fallback Extractor, extractors ...Extractor) *Registry {
+	return &Registry{
+		extractors: extractors,
+		fallback:   fallback,
+	}
+}
+
+// DefaultRegistry는 기본 Extractor가 등록된 Registry를 생성합니다.
+func DefaultRegistry() *Registry {
+	return NewRegistry(
+		&GenericExtractor{},
+		&NaverBlogExtractor{},
+		&TistoryExtractor{},
+		&BloggerExtractor{},
+	)
+}
+
+// Register는 Extractor를 등록합니다.
+// 나중에 등록한 Extractor가 먼저 확인되므로 기본 Extractor를 덮어쓸 수 있습니다.
+func (r *Registry) Register(extractor Extractor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.extractors = append([]Extractor{extractor}, r.extractors...)
+}
+
+// Extractor는 url을 처리할 Extractor를 반환합니다.
+func (r *Registry) Extractor(url string) Extractor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, extractor := range r.extractors {
+		if extractor.Match(url) {
+			return extractor
+		}
+	}
+	return r.fallback
+}
+
+// FindDownloadLinks는 url에 맞는 Extractor로 블로그 글에서 다운로드 링크를 모두 찾고 각 링크의 회차를 찾습니다.
+func (r *Registry) FindDownloadLinks(ctx context.Context, url string, episode string) ([]DownloadLink, error) {
+	extractor := r.Extractor(url)
+	if extractor == nil {
+		return nil, fmt.Errorf("no extractor for %s", url)
+	}
+	log.Printf("[Scraper] - %T: %s", extractor, url)
+
+	links, err := extractor.Extract(ctx, url, episode)
+	if err != nil {
+		return nil, err
+	}
+	AssignEpisodes(links)
+	return links, nil
+}
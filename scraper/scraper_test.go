@@ -0,0 +1,136 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newBlogServer는 경로별로 testdata의 HTML 파일을 응답하는 테스트 서버를 시작합니다.
+// pages에 없는 경로는 404로 응답합니다.
+func newBlogServer(t *testing.T, pages map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		body, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Errorf("testdata %s: %v", name, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// serverTransport는 모든 요청을 호스트와 관계없이 테스트 서버로 보냅니다.
+type serverTransport struct {
+	server *httptest.Server
+}
+
+// RoundTrip은 요청 URL의 scheme과 호스트를 테스트 서버로 바꿔서 요청합니다.
+// colly는 응답의 요청 URL로 상대 경로를 풀기 때문에 응답에는 원래 요청을 넣습니다.
+func (st serverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	target, err := url.Parse(st.server.URL)
+	if err != nil {
+		return nil, err
+	}
+	serverReq := req.Clone(req.Context())
+	serverReq.URL.Scheme = target.Scheme
+	serverReq.URL.Host = target.Host
+	resp, err := st.server.Client().Transport.RoundTrip(serverReq)
+	if err != nil {
+		return nil, err
+	}
+	resp.Request = req
+	return resp, nil
+}
+
+// linkURLs는 links의 URL을 순서대로 반환합니다.
+func linkURLs(links []DownloadLink) []string {
+	urls := make([]string, 0, len(links))
+	for _, link := range links {
+		urls = append(urls, link.URL)
+	}
+	return urls
+}
+
+// stubExtractor는 이름만 가진 Extractor입니다.
+type stubExtractor struct {
+	name  string
+	match func(url string) bool
+}
+
+func (s *stubExtractor) Match(url string) bool {
+	return s.match(url)
+}
+
+func (s *stubExtractor) Extract(ctx context.Context, url string, episode string) ([]DownloadLink, error) {
+	return []DownloadLink{{URL: "https://drive.google.com/file/d/" + s.name + "/view", Label: episode + "화"}}, nil
+}
+
+func TestDefaultRegistryExtractor(t *testing.T) {
+	tests := []struct {
+		url  string
+		want Extractor
+	}{
+		{url: "https://blog.naver.com/tester/223000000007", want: &NaverBlogExtractor{}},
+		{url: "https://m.blog.naver.com/tester/223000000007", want: &NaverBlogExtractor{}},
+		{url: "https://felia.tistory.com/123", want: &TistoryExtractor{}},
+		{url: "https://kitauji-highschool.blogspot.com/2024/01/post.html", want: &BloggerExtractor{}},
+		{url: "https://www.blogger.com/blog/post/1", want: &BloggerExtractor{}},
+		{url: "https://example.com/naver.com/post", want: &GenericExtractor{}},
+		{url: "https://tistory.com.example.com/123", want: &GenericExtractor{}},
+	}
+
+	registry := DefaultRegistry()
+	for _, tt := range tests {
+		got := registry.Extractor(tt.url)
+		if got == nil || reflect.TypeOf(got) != reflect.TypeOf(tt.want) {
+			t.Errorf("Extractor(%q) = %T, want %T", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestRegistryRegister(t *testing.T) {
+	registry := DefaultRegistry()
+	registry.Register(&stubExtractor{
+		name:  "custom",
+		match: func(url string) bool { return hostOf(url) == "felia.tistory.com" },
+	})
+
+	// 나중에 등록한 Extractor가 기본 Extractor보다 먼저 확인됩니다.
+	if got, ok := registry.Extractor("https://felia.tistory.com/123").(*stubExtractor); !ok || got.name != "custom" {
+		t.Errorf("Extractor() = %T, want the registered extractor", registry.Extractor("https://felia.tistory.com/123"))
+	}
+	if _, ok := registry.Extractor("https://other.tistory.com/123").(*TistoryExtractor); !ok {
+		t.Errorf("Extractor() of another blog = %T, want *TistoryExtractor", registry.Extractor("https://other.tistory.com/123"))
+	}
+
+	// FindDownloadLinks는 episode를 Extractor에 넘기고 링크의 회차를 찾습니다.
+	links, err := registry.FindDownloadLinks(context.Background(), "https://felia.tistory.com/123", "7")
+	if err != nil {
+		t.Fatalf("FindDownloadLinks() error = %v", err)
+	}
+	if len(links) != 1 || links[0].Label != "7화" || links[0].Episode != "7" {
+		t.Errorf("FindDownloadLinks() = %+v, want one link of episode 7", links)
+	}
+}
+
+func TestRegistryNoExtractor(t *testing.T) {
+	registry := NewRegistry(nil)
+	if _, err := registry.FindDownloadLinks(context.Background(), "https://example.com/post", ""); err == nil {
+		t.Error("FindDownloadLinks() error = nil, want no extractor error")
+	}
+}
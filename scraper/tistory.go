@@ -0,0 +1,71 @@
+package scraper
+
+import (
+	"context"
+	"strings"
+
+	"github.com/huketo/anisub-scraper/downloader"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// tistoryContentSelector는 티스토리 스킨에서 글 본문을 감싸는 요소입니다.
+const tistoryContentSelector = ".tt_article_useless_p_margin, .entry-content, .article-view, .contents_style, #article-view"
+
+// TistoryExtractor는 티스토리 블로그 글에서 다운로드 링크를 찾습니다.
+// 티스토리에 직접 올린 첨부 파일은 figure.fileblock 요소 안의 blog.kakaocdn.net 또는
+// tistory.com/attachment 링크로 표시되며, 원본 파일 이름은 .filename .name 요소에 있습니다.
+type TistoryExtractor struct{}
+
+// Match는 티스토리 블로그 글인지 판별합니다.
+func (t *TistoryExtractor) Match(url string) bool {
+	return strings.HasSuffix(hostOf(url), ".tistory.com")
+}
+
+// Extract는 글 본문에서 다운로드 링크를 찾습니다.
+// 본문 요소를 찾지 못한 스킨이면 페이지 전체에서 찾습니다.
+func (t *TistoryExtractor) Extract(ctx context.Context, url string, episode string) ([]DownloadLink, error) {
+	var body, page linkCollector
+	foundBody := false
+	c := newCollector(ctx)
+
+	// 첨부 파일 블록을 먼저 처리해서 원본 파일 이름을 가진 링크가 저장되도록 합니다.
+	c.OnHTML("figure.fileblock", func(e *colly.HTMLElement) {
+		href := e.ChildAttr("a[href]", "href")
+		if href == "" {
+			return
+		}
+		link := e.Request.AbsoluteURL(href)
+		if !IsDownloadURL(link) {
+			return
+		}
+		fileName := normalizeText(e.ChildText(".filename .name"))
+		if fileName == "" {
+			fileName = downloader.TistoryFileName(link)
+		}
+		downloadLink := newDownloadLink(link, fileName, e.DOM)
+		downloadLink.FileName = fileName
+		if e.DOM.Closest(tistoryContentSelector).Length() > 0 {
+			body.addLink(downloadLink)
+		}
+		page.addLink(downloadLink)
+	})
+
+	c.OnHTML(tistoryContentSelector, func(e *colly.HTMLElement) {
+		foundBody = true
+		e.ForEach("a[href]", func(_ int, a *colly.HTMLElement) {
+			body.add(a)
+		})
+	})
+	c.OnHTML("a[href]", page.add)
+
+	// Start scraping
+	if err := c.Visit(url); err != nil {
+		return nil, err
+	}
+
+	if foundBody {
+		return body.links, ctx.Err()
+	}
+	return page.links, ctx.Err()
+}
@@ -0,0 +1,61 @@
+package scraper
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTistoryExtractor(t *testing.T) {
+	tests := []struct {
+		name         string
+		page         string
+		want         []string
+		wantFileName []string
+	}{
+		{
+			// 본문 밖의 사이드바 링크와 이미지는 제외하고, 첨부 파일은 .filename .name의 원본 이름을 사용합니다.
+			// 이름이 없는 첨부 파일은 kakaocdn URL의 knm 파라미터를 사용합니다.
+			name: "file block",
+			page: "tistory_fileblock.html",
+			want: []string{
+				"https://blog.kakaocdn.net/dn/bXyz07/btsABC/abc123/tfile.zip?attach=1&knm=tfile.zip",
+				"https://blog.kakaocdn.net/dn/cAbc07/btsDEF/def456/tfile.ass?attach=1&knm=%ED%85%8C%EC%8A%A4%ED%8A%B8_07.ass",
+				"https://felia.tistory.com/attachment/cfile7.uf@99ABCDEF.ass",
+			},
+			wantFileName: []string{"[Sub] 테스트 07화.zip", "테스트_07.ass", ""},
+		},
+		{
+			// 본문 요소가 없는 스킨이면 페이지 전체에서 찾습니다.
+			name: "page fallback",
+			page: "tistory_page.html",
+			want: []string{
+				"https://felia.tistory.com/attachment/cfile8.uf@99ABCDEF.zip",
+				"https://drive.google.com/file/d/TISTORY_FILE_08/view?usp=sharing",
+			},
+			wantFileName: []string{"테스트 08화.zip", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newBlogServer(t, map[string]string{"/123": tt.page})
+
+			links, err := (&TistoryExtractor{}).Extract(context.Background(), server.URL+"/123", "7")
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if got := linkURLs(links); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Extract() = %q, want %q", got, tt.want)
+			}
+			for i, link := range links {
+				if link.FileName != tt.wantFileName[i] {
+					t.Errorf("links[%d].FileName = %q, want %q", i, link.FileName, tt.wantFileName[i])
+				}
+				if link.FileName != "" && link.Label != link.FileName {
+					t.Errorf("links[%d].Label = %q, want %q", i, link.Label, link.FileName)
+				}
+			}
+		})
+	}
+}
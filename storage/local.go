@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local은 로컬 디렉토리에 파일을 저장하는 Storage입니다.
+type Local struct {
+	root string
+}
+
+// NewLocal은 root 디렉토리에 파일을 저장하는 Local을 생성합니다.
+func NewLocal(root string) (*Local, error) {
+	if err := os.MkdirAll(root, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	return &Local{root: root}, nil
+}
+
+// path는 key의 파일 경로를 반환합니다.
+// root 밖이나 root 자체를 가리키는 key는 거부합니다.
+func (l *Local) path(key string) (string, error) {
+	key = filepath.FromSlash(key)
+	if !filepath.IsLocal(key) || filepath.Clean(key) == "." {
+		return "", fmt.Errorf("invalid storage key: %q", key)
+	}
+	return filepath.Join(l.root, key), nil
+}
+
+// Put은 srcPath를 key의 경로로 옮깁니다.
+// 다른 파일 시스템에 있어 이름을 바꿀 수 없으면 복사한 뒤 srcPath를 지웁니다.
+func (l *Local) Put(key string, srcPath string) error {
+	dst, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.Rename(srcPath, dst); err == nil {
+		return nil
+	}
+
+	if err := copyFile(srcPath, dst); err != nil {
+		return err
+	}
+	return os.Remove(srcPath)
+}
+
+// Open은 key의 파일을 엽니다.
+func (l *Local) Open(key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// Exists는 key의 파일이 있는지 확인합니다.
+func (l *Local) Exists(key string) (bool, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Delete는 key의 파일을 지웁니다.
+func (l *Local) Delete(key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List는 prefix로 시작하는 모든 파일의 key를 반환합니다.
+func (l *Local) List(prefix string) ([]string, error) {
+	// prefix의 마지막 "/"까지를 디렉토리로 보고 그 아래만 확인합니다.
+	start := filepath.Join(l.root, filepath.FromSlash(prefix[:strings.LastIndex(prefix, "/")+1]))
+
+	var keys []string
+	err := filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && p == start {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(l.root, p)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// Close는 아무 일도 하지 않습니다.
+func (l *Local) Close() error {
+	return nil
+}
+
+// copyFile은 src 파일을 dst로 복사합니다.
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeTemp는 임시 디렉토리에 content를 쓴 파일을 만들고 경로를 반환합니다.
+func writeTemp(t *testing.T, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "src")
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// testStorage는 st에 파일을 저장하고 읽고 지우는 공통 동작을 확인합니다.
+func testStorage(t *testing.T, st Storage) {
+	t.Helper()
+	files := map[string]string{
+		"blobs/ab/abcdef":   "first",
+		"blobs/ab/abcdff":   "second",
+		"blobs/cd/cdef01":   "third",
+		"files/1/2/sub.ass": "fourth",
+	}
+	for key, content := range files {
+		src := writeTemp(t, content)
+		if err := st.Put(key, src); err != nil {
+			t.Fatalf("Put(%q) error = %v", key, err)
+		}
+		if _, err := os.Stat(src); !os.IsNotExist(err) {
+			t.Errorf("Put(%q) left the source file: %v", key, err)
+		}
+	}
+
+	for key, content := range files {
+		r, err := st.Open(key)
+		if err != nil {
+			t.Fatalf("Open(%q) error = %v", key, err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil || string(data) != content {
+			t.Errorf("Open(%q) = %q, %v, want %q", key, data, err, content)
+		}
+	}
+
+	listTests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "blobs/ab/", want: []string{"blobs/ab/abcdef", "blobs/ab/abcdff"}},
+		{prefix: "blobs/ab/abcde", want: []string{"blobs/ab/abcdef"}},
+		{prefix: "blobs/", want: []string{"blobs/ab/abcdef", "blobs/ab/abcdff", "blobs/cd/cdef01"}},
+		{prefix: "missing/", want: nil},
+	}
+	for _, tt := range listTests {
+		got, err := st.List(tt.prefix)
+		if err != nil {
+			t.Fatalf("List(%q) error = %v", tt.prefix, err)
+		}
+		sort.Strings(got)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("List(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+
+	if err := st.Delete("blobs/ab/abcdef"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	// 없는 파일을 지워도 에러가 아닙니다.
+	if err := st.Delete("blobs/ab/abcdef"); err != nil {
+		t.Errorf("Delete() of a missing key error = %v", err)
+	}
+
+	existsTests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "blobs/ab/abcdef", want: false},
+		{key: "blobs/ab/abcdff", want: true},
+		{key: "files/1/2/sub.ass", want: true},
+		{key: "files/1/2/missing.ass", want: false},
+	}
+	for _, tt := range existsTests {
+		got, err := st.Exists(tt.key)
+		if err != nil || got != tt.want {
+			t.Errorf("Exists(%q) = %v, %v, want %v", tt.key, got, err, tt.want)
+		}
+	}
+	if _, err := st.Open("blobs/ab/abcdef"); err == nil {
+		t.Error("Open() of a deleted key error = nil")
+	}
+}
+
+func TestLocal(t *testing.T) {
+	local, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal() error = %v", err)
+	}
+	testStorage(t, local)
+}
+
+func TestLocalInvalidKey(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "root")
+	local, err := NewLocal(root)
+	if err != nil {
+		t.Fatalf("NewLocal() error = %v", err)
+	}
+
+	// 저장소 디렉토리 밖을 가리키는 key는 거부합니다.
+	keys := []string{"../escape", "a/../../escape", "/etc/passwd", "", "."}
+	for _, key := range keys {
+		src := writeTemp(t, "content")
+		if err := local.Put(key, src); err == nil {
+			t.Errorf("Put(%q) error = nil, want invalid key", key)
+		}
+		if _, err := os.Stat(src); err != nil {
+			t.Errorf("Put(%q) removed the source file: %v", key, err)
+		}
+		if _, err := local.Open(key); err == nil {
+			t.Errorf("Open(%q) error = nil, want invalid key", key)
+		}
+		if _, err := local.Exists(key); err == nil {
+			t.Errorf("Exists(%q) error = nil, want invalid key", key)
+		}
+		if err := local.Delete(key); err == nil {
+			t.Errorf("Delete(%q) error = nil, want invalid key", key)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(root), "escape")); !os.IsNotExist(err) {
+		t.Errorf("file was written outside the root: %v", err)
+	}
+}
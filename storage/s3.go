@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+	"gocloud.dev/gcerrors"
+)
+
+// S3는 PocketBase의 filesystem 패키지로 S3 호환 오브젝트 스토리지에 파일을 저장하는 Storage입니다.
+type S3 struct {
+	fs *filesystem.System
+}
+
+// NewS3는 config의 S3 설정으로 S3를 생성합니다.
+func NewS3(config Config) (*S3, error) {
+	if config.S3Bucket == "" {
+		return nil, fmt.Errorf("s3 bucket is not set")
+	}
+	fs, err := filesystem.NewS3(
+		config.S3Bucket,
+		config.S3Region,
+		config.S3Endpoint,
+		config.S3AccessKey,
+		config.S3SecretKey,
+		config.S3ForcePathStyle,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 filesystem: %w", err)
+	}
+	return &S3{fs: fs}, nil
+}
+
+// Put은 srcPath를 key로 업로드한 뒤 srcPath를 지웁니다.
+func (s *S3) Put(key string, srcPath string) error {
+	file, err := filesystem.NewFileFromPath(srcPath)
+	if err != nil {
+		return err
+	}
+	if err := s.fs.UploadFile(file, key); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	return os.Remove(srcPath)
+}
+
+// Open은 key의 파일을 읽습니다.
+func (s *S3) Open(key string) (io.ReadCloser, error) {
+	return s.fs.GetFile(key)
+}
+
+// Exists는 key의 파일이 있는지 확인합니다.
+func (s *S3) Exists(key string) (bool, error) {
+	return s.fs.Exists(key)
+}
+
+// Delete는 key의 파일을 지웁니다.
+func (s *S3) Delete(key string) error {
+	if err := s.fs.Delete(key); err != nil && gcerrors.Code(err) != gcerrors.NotFound {
+		return err
+	}
+	return nil
+}
+
+// List는 prefix로 시작하는 모든 파일의 key를 반환합니다.
+func (s *S3) List(prefix string) ([]string, error) {
+	objects, err := s.fs.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(objects))
+	for _, object := range objects {
+		if !object.IsDir {
+			keys = append(keys, object.Key)
+		}
+	}
+	return keys, nil
+}
+
+// Close는 S3 연결을 닫습니다.
+func (s *S3) Close() error {
+	return s.fs.Close()
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3는 path-style 요청의 PutObject, GetObject, HeadObject, DeleteObject, ListObjectsV2만
+// 처리하는 S3 호환 테스트 서버입니다.
+type fakeS3 struct {
+	t       *testing.T
+	bucket  string
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+// newFakeS3Server는 bucket 하나를 가진 fakeS3 서버를 생성합니다.
+func newFakeS3Server(t *testing.T, bucket string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(&fakeS3{t: t, bucket: bucket, objects: make(map[string][]byte)})
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != s.bucket {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch {
+	case key == "" && r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		s.list(w, r.URL.Query().Get("prefix"))
+	case key == "":
+		s.t.Errorf("unexpected bucket request %s %s", r.Method, r.URL)
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+	case r.Method == http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeS3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		s.objects[key] = data
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, len(data)))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := s.objects[key]
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, len(data)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.t.Errorf("unexpected object request %s %s", r.Method, r.URL)
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+// list는 prefix로 시작하는 모든 오브젝트를 한 페이지의 ListObjectsV2 응답으로 보냅니다.
+func (s *fakeS3) list(w http.ResponseWriter, prefix string) {
+	type object struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int
+	}
+	result := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Name        string
+		Prefix      string
+		KeyCount    int
+		MaxKeys     int
+		IsTruncated bool
+		Contents    []object
+	}{Name: s.bucket, Prefix: prefix, MaxKeys: 1000}
+
+	for key, data := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			result.Contents = append(result.Contents, object{
+				Key:          key,
+				LastModified: time.Now().UTC().Format(time.RFC3339),
+				ETag:         fmt.Sprintf(`"%x"`, len(data)),
+				Size:         len(data),
+			})
+		}
+	}
+	sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+	result.KeyCount = len(result.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+// writeS3Error는 S3 형식의 에러 응답을 보냅니다.
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func TestS3(t *testing.T) {
+	server := newFakeS3Server(t, "subtitles")
+	s3, err := NewS3(Config{
+		S3Bucket:         "subtitles",
+		S3Region:         "us-east-1",
+		S3Endpoint:       server.URL,
+		S3AccessKey:      "access",
+		S3SecretKey:      "secret",
+		S3ForcePathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3() error = %v", err)
+	}
+	defer s3.Close()
+
+	testStorage(t, s3)
+}
+
+func TestNewS3WithoutBucket(t *testing.T) {
+	if _, err := NewS3(Config{S3Region: "us-east-1"}); err == nil {
+		t.Error("NewS3() error = nil, want missing bucket error")
+	}
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+)
+
+// Type은 Storage 구현을 나타냅니다.
+type Type string
+
+const (
+	// TypeLocal은 로컬 디렉토리에 저장하는 Storage입니다.
+	TypeLocal Type = "local"
+	// TypeS3는 S3 호환 오브젝트 스토리지에 저장하는 Storage입니다.
+	TypeS3 Type = "s3"
+)
+
+// Storage는 다운로드하거나 압축을 푼 파일을 저장하는 곳입니다.
+// key는 "/"로 구분된 상대 경로입니다. (예: blobs/ab/abcdef...)
+type Storage interface {
+	// Put은 로컬 파일 srcPath를 key로 저장하고 srcPath를 지웁니다.
+	Put(key string, srcPath string) error
+	// Open은 key의 파일을 읽습니다. 다 읽은 뒤 Close를 호출해야 합니다.
+	Open(key string) (io.ReadCloser, error)
+	// Exists는 key의 파일이 있는지 확인합니다.
+	Exists(key string) (bool, error)
+	// Delete는 key의 파일을 지웁니다. 파일이 없으면 아무 일도 하지 않습니다.
+	Delete(key string) error
+	// List는 prefix로 시작하는 모든 파일의 key를 반환합니다.
+	List(prefix string) ([]string, error)
+	// Close는 Storage가 사용하는 자원을 해제합니다.
+	Close() error
+}
+
+// Config는 Storage 설정입니다.
+type Config struct {
+	Type             Type   // Storage 구현, 비어 있으면 TypeLocal을 사용합니다.
+	LocalDir         string // TypeLocal의 저장 디렉토리
+	S3Bucket         string // TypeS3의 버킷 이름
+	S3Region         string // TypeS3의 리전
+	S3Endpoint       string // TypeS3의 엔드포인트 (예: http://localhost:9000)
+	S3AccessKey      string // TypeS3의 access key
+	S3SecretKey      string // TypeS3의 secret key
+	S3ForcePathStyle bool   // 버킷 이름을 호스트 대신 경로에 넣습니다. MinIO 등에서 사용합니다.
+}
+
+// DefaultConfig는 기본 Storage 설정을 반환합니다.
+func DefaultConfig() Config {
+	return Config{
+		Type:     TypeLocal,
+		LocalDir: "./downloads",
+	}
+}
+
+// New는 config.Type에 맞는 Storage를 생성합니다.
+func New(config Config) (Storage, error) {
+	switch config.Type {
+	case "", TypeLocal:
+		if config.LocalDir == "" {
+			config.LocalDir = DefaultConfig().LocalDir
+		}
+		return NewLocal(config.LocalDir)
+	case TypeS3:
+		return NewS3(config)
+	}
+	return nil, fmt.Errorf("not supported storage type: %s", config.Type)
+}
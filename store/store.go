@@ -0,0 +1,321 @@
+package store
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/huketo/anisub-scraper/storage"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/daos"
+	"github.com/pocketbase/pocketbase/models"
+)
+
+const (
+	// filesCollection은 논리 경로와 blob을 연결하는 collection 이름입니다.
+	filesCollection = "files"
+	// blobsCollection은 blob의 참조 횟수를 저장하는 collection 이름입니다.
+	blobsCollection = "blobs"
+)
+
+// DefaultGCSchedule은 참조되지 않는 blob을 지우는 기본 cron 표현식입니다.
+const DefaultGCSchedule = "0 4 * * *"
+
+// ErrNotFound는 논리 경로에 저장된 파일이 없을 때의 에러입니다.
+var ErrNotFound = errors.New("file not found")
+
+// Entry는 논리 경로에 저장된 파일입니다.
+type Entry struct {
+	Path       string // 논리 경로 (anime_no/episode/name/파일 이름)
+	SHA256     string // 내용의 SHA-256 해시 (hex)
+	Size       int64  // 파일 크기 (byte)
+	MIMEType   string // MIME 타입
+	SubtitleID string // 파일을 저장한 anime_subtitle 레코드 ID
+}
+
+// Store는 파일을 SHA-256 해시로 저장하는 content-addressed 저장소입니다.
+// 내용은 storage.Storage의 blobs/ab/abcdef... 에 한 번만 저장되고, "files" collection이 논리 경로를 blob에 연결합니다.
+// "blobs" collection은 blob을 참조하는 논리 경로의 수를 세며, GC는 참조되지 않는 blob을 지웁니다.
+type Store struct {
+	app     *pocketbase.PocketBase
+	storage storage.Storage
+	mu      sync.Mutex // blob 파일과 참조 횟수를 함께 수정하도록 막는 잠금
+}
+
+// NewStore는 st에 blob을 저장하는 Store를 생성합니다.
+func NewStore(app *pocketbase.PocketBase, st storage.Storage) *Store {
+	return &Store{
+		app:     app,
+		storage: st,
+	}
+}
+
+// blobPrefix는 blob을 저장하는 key의 접두사입니다.
+const blobPrefix = "blobs/"
+
+// blobKey는 sha256 해시의 blob key를 반환합니다.
+func blobKey(sha256 string) string {
+	return blobPrefix + sha256[:2] + "/" + sha256
+}
+
+// Fetch는 sha256 해시의 blob을 로컬 파일 dstPath로 복사합니다.
+func (s *Store) Fetch(sha256 string, dstPath string) error {
+	r, err := s.storage.Open(blobKey(sha256))
+	if err != nil {
+		return fmt.Errorf("failed to open blob %s: %w", sha256, err)
+	}
+	defer r.Close()
+
+	f, err := os.Create(dstPath)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch blob %s: %w", sha256, err)
+	}
+	return nil
+}
+
+// Put은 srcPath 파일을 저장소로 옮기고 논리 경로 logicalPath를 파일에 연결합니다.
+// 같은 내용의 blob이 이미 있으면 srcPath를 지우고 기존 blob을 참조합니다.
+// logicalPath에 다른 내용이 연결되어 있었으면 이전 blob의 참조를 줄입니다.
+func (s *Store) Put(subtitleID string, logicalPath string, srcPath string) (*Entry, error) {
+	sum, size, err := hashFile(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	entry := &Entry{
+		Path:       logicalPath,
+		SHA256:     sum,
+		Size:       size,
+		MIMEType:   mime.TypeByExtension(path.Ext(logicalPath)),
+		SubtitleID: subtitleID,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exists, err := s.storage.Exists(blobKey(sum))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check blob: %w", err)
+	}
+	if exists {
+		// 같은 내용이 이미 저장되어 있습니다.
+		if err := os.Remove(srcPath); err != nil {
+			return nil, fmt.Errorf("failed to remove duplicate file: %w", err)
+		}
+	} else if err := s.storage.Put(blobKey(sum), srcPath); err != nil {
+		return nil, fmt.Errorf("failed to store blob: %w", err)
+	}
+
+	err = s.app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		record, err := findFile(txDao, logicalPath)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			collection, err := txDao.FindCollectionByNameOrId(filesCollection)
+			if err != nil {
+				return fmt.Errorf("failed to find files collection: %w", err)
+			}
+			record = models.NewRecord(collection)
+		} else if old := record.GetString("sha256"); old != sum {
+			if err := addRef(txDao, old, 0, -1); err != nil {
+				return err
+			}
+		} else {
+			// 같은 내용을 다시 저장한 경우 참조 횟수는 그대로입니다.
+			record.Set("subtitle_id", subtitleID)
+			return txDao.SaveRecord(record)
+		}
+
+		record.Set("path", logicalPath)
+		record.Set("sha256", sum)
+		record.Set("size", size)
+		record.Set("mime_type", entry.MIMEType)
+		record.Set("subtitle_id", subtitleID)
+		if err := txDao.SaveRecord(record); err != nil {
+			return fmt.Errorf("failed to save file record: %w", err)
+		}
+		return addRef(txDao, sum, size, 1)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// Lookup은 논리 경로에 저장된 파일을 찾습니다. 없으면 ErrNotFound를 반환합니다.
+func (s *Store) Lookup(logicalPath string) (*Entry, error) {
+	record, err := findFile(s.app.Dao(), logicalPath)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrNotFound
+	}
+	return &Entry{
+		Path:       record.GetString("path"),
+		SHA256:     record.GetString("sha256"),
+		Size:       int64(record.GetInt("size")),
+		MIMEType:   record.GetString("mime_type"),
+		SubtitleID: record.GetString("subtitle_id"),
+	}, nil
+}
+
+// ReleaseSubtitle은 anime_subtitle 레코드가 저장한 논리 경로를 모두 지우고 blob의 참조를 줄입니다.
+// blob 파일은 GC가 지웁니다.
+func (s *Store) ReleaseSubtitle(subtitleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.app.Dao().RunInTransaction(func(txDao *daos.Dao) error {
+		records, err := txDao.FindRecordsByFilter(
+			filesCollection,
+			"subtitle_id = {:subtitleID}",
+			"", 0, 0,
+			dbx.Params{"subtitleID": subtitleID},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to find file records: %w", err)
+		}
+		for _, record := range records {
+			if err := txDao.DeleteRecord(record); err != nil {
+				return fmt.Errorf("failed to delete file record: %w", err)
+			}
+			if err := addRef(txDao, record.GetString("sha256"), 0, -1); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GC는 참조되지 않는 blob과 "blobs" collection에 없는 blob 파일을 지우고 지운 blob 수를 반환합니다.
+func (s *Store) GC() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	records, err := s.app.Dao().FindRecordsByFilter(blobsCollection, "ref_count <= 0", "", 0, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find unreferenced blobs: %w", err)
+	}
+	for _, record := range records {
+		sum := record.GetString("sha256")
+		if err := s.storage.Delete(blobKey(sum)); err != nil {
+			return removed, fmt.Errorf("failed to remove blob %s: %w", sum, err)
+		}
+		if err := s.app.Dao().DeleteRecord(record); err != nil {
+			return removed, fmt.Errorf("failed to delete blob record %s: %w", sum, err)
+		}
+		removed++
+	}
+
+	// 파일을 옮긴 뒤 레코드를 저장하기 전에 종료되어 남은 blob 파일을 지웁니다.
+	keys, err := s.storage.List(blobPrefix)
+	if err != nil {
+		return removed, fmt.Errorf("failed to list blobs: %w", err)
+	}
+	for _, key := range keys {
+		record, err := findBlob(s.app.Dao(), path.Base(key))
+		if err != nil {
+			return removed, err
+		}
+		if record != nil {
+			continue
+		}
+		log.Printf("[Store] - remove orphan blob %s", key)
+		if err := s.storage.Delete(key); err != nil {
+			return removed, fmt.Errorf("failed to remove orphan blob %s: %w", key, err)
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// addRef는 blob의 참조 횟수를 delta만큼 바꿉니다. blob 레코드가 없으면 생성합니다.
+func addRef(txDao *daos.Dao, sum string, size int64, delta int) error {
+	record, err := findBlob(txDao, sum)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		if delta <= 0 {
+			return nil
+		}
+		collection, err := txDao.FindCollectionByNameOrId(blobsCollection)
+		if err != nil {
+			return fmt.Errorf("failed to find blobs collection: %w", err)
+		}
+		record = models.NewRecord(collection)
+		record.Set("sha256", sum)
+		record.Set("size", size)
+	}
+
+	refCount := record.GetInt("ref_count") + delta
+	if refCount < 0 {
+		refCount = 0
+	}
+	record.Set("ref_count", refCount)
+	if err := txDao.SaveRecord(record); err != nil {
+		return fmt.Errorf("failed to save blob record: %w", err)
+	}
+	return nil
+}
+
+// findFile은 논리 경로의 "files" 레코드를 찾습니다. 없으면 nil을 반환합니다.
+func findFile(dao *daos.Dao, logicalPath string) (*models.Record, error) {
+	record, err := dao.FindFirstRecordByData(filesCollection, "path", logicalPath)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find file record: %w", err)
+	}
+	return record, nil
+}
+
+// findBlob은 sha256 해시의 "blobs" 레코드를 찾습니다. 없으면 nil을 반환합니다.
+func findBlob(dao *daos.Dao, sum string) (*models.Record, error) {
+	record, err := dao.FindFirstRecordByData(blobsCollection, "sha256", sum)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find blob record: %w", err)
+	}
+	return record, nil
+}
+
+// hashFile은 파일의 SHA-256 해시(hex)와 크기를 계산합니다.
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read file: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
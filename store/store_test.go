@@ -0,0 +1,229 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/huketo/anisub-scraper/internal/testapp"
+	"github.com/huketo/anisub-scraper/storage"
+)
+
+// newTestStore는 테스트 앱과 임시 디렉토리의 로컬 저장소를 사용하는 Store를 생성합니다.
+func newTestStore(t *testing.T) (*Store, *storage.Local) {
+	t.Helper()
+	local, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal() error = %v", err)
+	}
+	return NewStore(testapp.New(t), local), local
+}
+
+// sum은 content의 SHA-256 해시(hex)를 반환합니다.
+func sum(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
+// put은 content를 쓴 임시 파일을 logicalPath로 저장하고, 임시 파일이 옮겨지거나 지워졌는지 확인합니다.
+func put(t *testing.T, s *Store, subtitleID string, logicalPath string, content string) *Entry {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), "src")
+	if err := os.WriteFile(src, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := s.Put(subtitleID, logicalPath, src)
+	if err != nil {
+		t.Fatalf("Put(%q) error = %v", logicalPath, err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("Put(%q) left the source file: %v", logicalPath, err)
+	}
+	return entry
+}
+
+// refCount는 blob의 참조 횟수를 반환합니다. blob 레코드가 없으면 -1을 반환합니다.
+func refCount(t *testing.T, s *Store, content string) int {
+	t.Helper()
+	record, err := findBlob(s.app.Dao(), sum(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record == nil {
+		return -1
+	}
+	return record.GetInt("ref_count")
+}
+
+// blobExists는 저장소에 content의 blob 파일이 있는지 확인합니다.
+func blobExists(t *testing.T, st storage.Storage, content string) bool {
+	t.Helper()
+	exists, err := st.Exists(blobKey(sum(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return exists
+}
+
+// wantRefs는 내용별 blob의 참조 횟수와 blob 파일이 있는지 확인합니다. 참조 횟수 -1은 blob 레코드가 없다는 뜻입니다.
+func wantRefs(t *testing.T, s *Store, want map[string]int, wantBlob map[string]bool) {
+	t.Helper()
+	for content, n := range want {
+		if got := refCount(t, s, content); got != n {
+			t.Errorf("ref_count(%q) = %d, want %d", content, got, n)
+		}
+	}
+	for content, exists := range wantBlob {
+		if got := blobExists(t, s.storage, content); got != exists {
+			t.Errorf("blob %q exists = %t, want %t", content, got, exists)
+		}
+	}
+}
+
+func TestPutDedup(t *testing.T) {
+	s, local := newTestStore(t)
+
+	first := put(t, s, "sub1", "1/1/a/01화.ass", "subtitle")
+	second := put(t, s, "sub2", "1/1/b/01화.ass", "subtitle")
+
+	if first.SHA256 != sum("subtitle") || second.SHA256 != first.SHA256 {
+		t.Errorf("SHA256 = %s, %s, want %s", first.SHA256, second.SHA256, sum("subtitle"))
+	}
+	if first.Size != int64(len("subtitle")) {
+		t.Errorf("Size = %d, want %d", first.Size, len("subtitle"))
+	}
+	wantRefs(t, s, map[string]int{"subtitle": 2}, map[string]bool{"subtitle": true})
+	keys, err := local.List(blobPrefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 {
+		t.Errorf("blobs = %q, want one blob", keys)
+	}
+
+	entry, err := s.Lookup("1/1/b/01화.ass")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if entry.SHA256 != first.SHA256 || entry.SubtitleID != "sub2" {
+		t.Errorf("Lookup() = %+v, want sha256 %s by sub2", entry, first.SHA256)
+	}
+
+	dst := filepath.Join(t.TempDir(), "dst")
+	if err := s.Fetch(entry.SHA256, dst); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if data, err := os.ReadFile(dst); err != nil || string(data) != "subtitle" {
+		t.Errorf("Fetch() = %q, %v, want %q", data, err, "subtitle")
+	}
+}
+
+func TestPutOverwrite(t *testing.T) {
+	s, _ := newTestStore(t)
+	const logicalPath = "1/1/a/01화.ass"
+
+	put(t, s, "sub1", logicalPath, "old")
+	put(t, s, "sub1", logicalPath, "new")
+
+	// 논리 경로가 새 내용을 가리키고, 이전 blob은 GC가 지울 때까지 남아 있습니다.
+	wantRefs(t, s, map[string]int{"old": 0, "new": 1}, map[string]bool{"old": true, "new": true})
+	entry, err := s.Lookup(logicalPath)
+	if err != nil || entry.SHA256 != sum("new") {
+		t.Fatalf("Lookup() = %+v, %v, want sha256 %s", entry, err, sum("new"))
+	}
+
+	// 같은 내용을 다시 저장하면 참조 횟수는 그대로이고 저장한 레코드만 바뀝니다.
+	put(t, s, "sub2", logicalPath, "new")
+	wantRefs(t, s, map[string]int{"new": 1}, nil)
+	if entry, err := s.Lookup(logicalPath); err != nil || entry.SubtitleID != "sub2" {
+		t.Errorf("Lookup() = %+v, %v, want saved by sub2", entry, err)
+	}
+}
+
+func TestReleaseSubtitle(t *testing.T) {
+	s, _ := newTestStore(t)
+	put(t, s, "sub1", "1/1/a/01화.ass", "shared")
+	put(t, s, "sub1", "1/1/a/fonts/a.ttf", "font")
+	put(t, s, "sub2", "1/1/b/01화.ass", "shared")
+
+	if err := s.ReleaseSubtitle("sub1"); err != nil {
+		t.Fatalf("ReleaseSubtitle() error = %v", err)
+	}
+
+	for _, p := range []string{"1/1/a/01화.ass", "1/1/a/fonts/a.ttf"} {
+		if _, err := s.Lookup(p); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Lookup(%q) error = %v, want ErrNotFound", p, err)
+		}
+	}
+	if _, err := s.Lookup("1/1/b/01화.ass"); err != nil {
+		t.Errorf("Lookup() of the other subtitle error = %v", err)
+	}
+	wantRefs(t, s, map[string]int{"shared": 1, "font": 0}, map[string]bool{"shared": true, "font": true})
+
+	// 이미 해제한 자막을 다시 해제해도 참조 횟수는 바뀌지 않습니다.
+	if err := s.ReleaseSubtitle("sub1"); err != nil {
+		t.Fatalf("ReleaseSubtitle() error = %v", err)
+	}
+	wantRefs(t, s, map[string]int{"shared": 1, "font": 0}, nil)
+}
+
+func TestGC(t *testing.T) {
+	s, local := newTestStore(t)
+	put(t, s, "sub1", "1/1/a/01화.ass", "shared")
+	put(t, s, "sub1", "1/1/a/fonts/a.ttf", "font")
+	put(t, s, "sub2", "1/1/b/01화.ass", "shared")
+	if err := s.ReleaseSubtitle("sub1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 파일을 옮긴 뒤 레코드를 저장하기 전에 종료되어 남은 blob 파일입니다.
+	orphan := filepath.Join(t.TempDir(), "orphan")
+	if err := os.WriteFile(orphan, []byte("orphan"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.Put(blobKey(sum("orphan")), orphan); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := s.GC()
+	if err != nil {
+		t.Fatalf("GC() error = %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("GC() = %d, want 2", removed)
+	}
+	wantRefs(t, s,
+		map[string]int{"shared": 1, "font": -1, "orphan": -1},
+		map[string]bool{"shared": true, "font": false, "orphan": false},
+	)
+
+	// 지울 blob이 없으면 아무것도 지우지 않습니다.
+	if removed, err := s.GC(); err != nil || removed != 0 {
+		t.Errorf("second GC() = %d, %v, want 0", removed, err)
+	}
+}
+
+func TestPutPendingGC(t *testing.T) {
+	s, _ := newTestStore(t)
+	const logicalPath = "1/1/a/01화.ass"
+	put(t, s, "sub1", logicalPath, "subtitle")
+	if err := s.ReleaseSubtitle("sub1"); err != nil {
+		t.Fatal(err)
+	}
+	wantRefs(t, s, map[string]int{"subtitle": 0}, map[string]bool{"subtitle": true})
+
+	// GC가 지우기 전에 같은 내용을 다시 저장하면 남아 있는 blob을 다시 참조합니다.
+	put(t, s, "sub1", logicalPath, "subtitle")
+	wantRefs(t, s, map[string]int{"subtitle": 1}, nil)
+
+	if removed, err := s.GC(); err != nil || removed != 0 {
+		t.Fatalf("GC() = %d, %v, want 0", removed, err)
+	}
+	wantRefs(t, s, map[string]int{"subtitle": 1}, map[string]bool{"subtitle": true})
+	if _, err := s.Lookup(logicalPath); err != nil {
+		t.Errorf("Lookup() error = %v", err)
+	}
+}